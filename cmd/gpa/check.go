@@ -0,0 +1,121 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/cvsouth/go-package-analyzer/internal/analyzer"
+)
+
+// rulesFile is the YAML layout of the file passed to "gpa check --rules".
+// Allow maps package paths to the only dependencies they may import; packages
+// without an entry are unconstrained.
+type rulesFile struct {
+	Allow map[string][]string `yaml:"allow"`
+}
+
+// checkOptions selects the gates applied by the check command.
+type checkOptions struct {
+	noCycles bool
+	maxFanIn int                 // 0 disables the fan-in gate
+	allow    map[string][]string // nil disables the rules gate
+}
+
+// runCheck analyzes an entry file and exits non-zero when any selected gate fails,
+// printing one line per failure.
+func runCheck(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	noCycles := flags.Bool("no-cycles", false, "fail when internal packages import each other in a cycle")
+	maxFanIn := flags.Int("max-fan-in", 0, "fail when a package is imported by more than n packages (0 disables)")
+	rulesPath := flags.String("rules", "", "YAML file of allowed dependencies per package")
+	exclude := flags.String("exclude", "", "comma-separated directories or glob patterns to exclude")
+
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return exitUsage
+	}
+	if len(positional) != 1 {
+		fmt.Fprintf(stderr, "gpa check: expected exactly one entry file\n\n%s", usage)
+		return exitUsage
+	}
+	if !*noCycles && *maxFanIn <= 0 && *rulesPath == "" {
+		fmt.Fprintf(stderr, "gpa check: nothing to check, pass --no-cycles, --max-fan-in or --rules\n")
+		return exitUsage
+	}
+
+	options := checkOptions{noCycles: *noCycles, maxFanIn: *maxFanIn}
+	if *rulesPath != "" {
+		rules, rulesErr := loadRules(*rulesPath)
+		if rulesErr != nil {
+			fmt.Fprintf(stderr, "gpa check: %v\n", rulesErr)
+			return exitError
+		}
+		options.allow = rules.Allow
+	}
+
+	a := analyzer.New()
+	graph, err := analyzeGraph(a, positional[0], true, parseExcludeList(*exclude))
+	if err != nil {
+		fmt.Fprintf(stderr, "gpa check: %v\n", err)
+		return exitError
+	}
+
+	failures := checkGraph(a, graph, options)
+	for _, failure := range failures {
+		fmt.Fprintln(stdout, failure)
+	}
+	if len(failures) > 0 {
+		return exitError
+	}
+	return exitOK
+}
+
+// loadRules reads and parses a rules file.
+func loadRules(path string) (*rulesFile, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading rules file: %w", err)
+	}
+
+	var rules rulesFile
+	if unmarshalErr := yaml.Unmarshal(content, &rules); unmarshalErr != nil {
+		return nil, fmt.Errorf("parsing rules file %s: %w", path, unmarshalErr)
+	}
+	return &rules, nil
+}
+
+// checkGraph applies the selected gates to a graph and describes every failure, in a stable order.
+func checkGraph(a *analyzer.Analyzer, graph *analyzer.DependencyGraph, options checkOptions) []string {
+	var failures []string
+
+	if options.noCycles {
+		for _, cycle := range graph.Cycles {
+			path := append(append([]string(nil), cycle.Packages...), cycle.Packages[0])
+			failures = append(failures, "cycle: "+strings.Join(path, " -> "))
+		}
+	}
+
+	if options.maxFanIn > 0 {
+		for _, pkg := range a.ComputeMetrics(graph).Packages {
+			if pkg.FanIn > options.maxFanIn {
+				failures = append(failures, fmt.Sprintf(
+					"fan-in: %s is imported by %d packages (max %d)", pkg.Path, pkg.FanIn, options.maxFanIn))
+			}
+		}
+	}
+
+	if options.allow != nil {
+		for _, violation := range a.CheckManifest(graph, options.allow) {
+			failures = append(failures, fmt.Sprintf(
+				"rule: %s is not allowed to import %s", violation.Package, violation.Dependency))
+		}
+	}
+
+	return failures
+}
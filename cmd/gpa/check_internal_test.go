@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createCyclicProject creates a module where a and b import each other and both import shared.
+func createCyclicProject(t *testing.T) string {
+	t.Helper()
+
+	return writeProject(t, map[string]string{
+		"main.go":          "package main\n\nimport _ \"example.com/app/a\"\n\nfunc main() {}\n",
+		"a/a.go":           "package a\n\nimport (\n\t_ \"example.com/app/b\"\n\t_ \"example.com/app/shared\"\n)\n",
+		"b/b.go":           "package b\n\nimport (\n\t_ \"example.com/app/a\"\n\t_ \"example.com/app/shared\"\n)\n",
+		"shared/shared.go": "package shared\n",
+	})
+}
+
+func TestRun_CheckNoCycles(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	code := run([]string{"check", createCyclicProject(t), "--no-cycles"}, &stdout, &stderr)
+
+	assert.Equal(t, exitError, code, stderr.String())
+	assert.Equal(t, "cycle: example.com/app/a -> example.com/app/b -> example.com/app/a\n", stdout.String())
+
+	stdout.Reset()
+	code = run([]string{"check", createProject(t), "--no-cycles"}, &stdout, &stderr)
+
+	assert.Equal(t, exitOK, code, stderr.String())
+	assert.Empty(t, stdout.String())
+}
+
+func TestRun_CheckMaxFanIn(t *testing.T) {
+	entry := createCyclicProject(t)
+	var stdout, stderr bytes.Buffer
+
+	code := run([]string{"check", entry, "--max-fan-in", "1"}, &stdout, &stderr)
+
+	assert.Equal(t, exitError, code, stderr.String())
+	assert.Equal(t, "fan-in: example.com/app/a is imported by 2 packages (max 1)\n"+
+		"fan-in: example.com/app/shared is imported by 2 packages (max 1)\n", stdout.String())
+
+	stdout.Reset()
+	assert.Equal(t, exitOK, run([]string{"check", entry, "--max-fan-in", "2"}, &stdout, &stderr))
+}
+
+func TestRun_CheckRules(t *testing.T) {
+	entry := createCyclicProject(t)
+	rules := filepath.Join(t.TempDir(), "rules.yaml")
+	require.NoError(t, os.WriteFile(rules, []byte(`allow:
+  example.com/app/a:
+    - example.com/app/shared
+  example.com/app/shared: []
+`), 0644))
+	var stdout, stderr bytes.Buffer
+
+	code := run([]string{"check", entry, "--rules", rules}, &stdout, &stderr)
+
+	assert.Equal(t, exitError, code, stderr.String())
+	assert.Equal(t, "rule: example.com/app/a is not allowed to import example.com/app/b\n", stdout.String())
+}
+
+func TestRun_CheckRequiresAGate(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	assert.Equal(t, exitUsage, run([]string{"check", createProject(t)}, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "nothing to check")
+}
@@ -0,0 +1,260 @@
+// Package main provides the gpa command-line interface for the Go package analyzer.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cvsouth/go-package-analyzer/internal/analyzer"
+	"github.com/cvsouth/go-package-analyzer/internal/visualizer"
+)
+
+// Exit codes.
+const (
+	exitOK    = 0 // Command succeeded
+	exitError = 1 // Command failed, or a check did not pass
+	exitUsage = 2 // Command line was invalid
+)
+
+// Output formats supported by the analyze command.
+const (
+	formatDOT  = "dot"
+	formatJSON = "json"
+	formatHTML = "html"
+)
+
+// outputFileMode is the permission mode of files written with --out.
+const outputFileMode = 0o644
+
+const usage = `Usage:
+  gpa analyze <entry> [--format dot|json|html] [--out file] [--exclude dirs] [--external] [--files]
+  gpa entrypoints <repo> [--json]
+  gpa check <entry> [--no-cycles] [--max-fan-in n] [--rules file] [--exclude dirs]
+
+Commands:
+  analyze      Analyze the packages reachable from an entry file and write the graph
+  entrypoints  List the files declaring a main function, relative to the repository root
+  check        Exit with status 1 when the internal packages break a cycle, coupling or rules gate
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes a gpa command and returns the process exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitUsage
+	}
+
+	switch args[0] {
+	case "analyze":
+		return runAnalyze(args[1:], stdout, stderr)
+	case "entrypoints":
+		return runEntryPoints(args[1:], stdout, stderr)
+	case "check":
+		return runCheck(args[1:], stdout, stderr)
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, usage)
+		return exitOK
+	default:
+		fmt.Fprintf(stderr, "gpa: unknown command %q\n\n%s", args[0], usage)
+		return exitUsage
+	}
+}
+
+// runAnalyze analyzes an entry file and writes the graph to a file or stdout.
+func runAnalyze(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("analyze", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	format := flags.String("format", formatDOT, "output format: dot, json or html")
+	out := flags.String("out", "", "write to this file instead of stdout")
+	exclude := flags.String("exclude", "", "comma-separated directories or glob patterns to exclude")
+	external := flags.Bool("external", false, "include external packages")
+	files := flags.Bool("files", false, "include source file paths in JSON output")
+
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return exitUsage
+	}
+	if len(positional) != 1 {
+		fmt.Fprintf(stderr, "gpa analyze: expected exactly one entry file\n\n%s", usage)
+		return exitUsage
+	}
+	if *format != formatDOT && *format != formatJSON && *format != formatHTML {
+		fmt.Fprintf(stderr, "gpa analyze: unknown format %q\n", *format)
+		return exitUsage
+	}
+
+	var jsonOpts []visualizer.JSONOption
+	if *files {
+		jsonOpts = append(jsonOpts, visualizer.JSONWithFiles())
+	}
+	content, err := analyze(positional[0], *format, !*external, parseExcludeList(*exclude), jsonOpts...)
+	if err != nil {
+		fmt.Fprintf(stderr, "gpa analyze: %v\n", err)
+		return exitError
+	}
+
+	if *out == "" {
+		_, err = stdout.Write(content)
+	} else {
+		err = os.WriteFile(*out, content, outputFileMode)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "gpa analyze: writing output: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+// analyze builds the dependency graph of an entry file and renders it in the given format,
+// passing jsonOpts to the JSON export.
+func analyze(
+	entryFile, format string,
+	excludeExternal bool,
+	excludeList []string,
+	jsonOpts ...visualizer.JSONOption,
+) ([]byte, error) {
+	graph, err := analyzeGraph(analyzer.New(), entryFile, excludeExternal, excludeList)
+	if err != nil {
+		return nil, err
+	}
+
+	viz := visualizer.New()
+	switch format {
+	case formatJSON:
+		return viz.GenerateJSON(graph, jsonOpts...)
+	case formatHTML:
+		return viz.GenerateHTML(context.Background(), graph)
+	default:
+		return []byte(viz.GenerateDOTContent(graph)), nil
+	}
+}
+
+// analyzeGraph builds the dependency graph of an entry file, failing when it has no packages.
+func analyzeGraph(
+	a *analyzer.Analyzer,
+	entryFile string,
+	excludeExternal bool,
+	excludeList []string,
+) (*analyzer.DependencyGraph, error) {
+	absEntryFile, err := filepath.Abs(entryFile)
+	if err != nil {
+		return nil, fmt.Errorf("resolving entry file path: %w", err)
+	}
+
+	graph, err := a.AnalyzeFromFile(absEntryFile, excludeExternal, excludeList)
+	if err != nil {
+		return nil, err
+	}
+	if len(graph.Packages) == 0 {
+		return nil, errors.New("no packages found to analyze")
+	}
+	return graph, nil
+}
+
+// entryPointJSON is an entry point as printed by "gpa entrypoints --json".
+type entryPointJSON struct {
+	Path         string `json:"path"`         // Absolute file path
+	RelativePath string `json:"relativePath"` // Slash-separated path from the repository root
+}
+
+// runEntryPoints prints the entry points of a repository, one relative path per line or as JSON.
+func runEntryPoints(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("entrypoints", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	asJSON := flags.Bool("json", false, "print entry points as a JSON array")
+
+	positional, err := parseArgs(flags, args)
+	if err != nil {
+		return exitUsage
+	}
+	if len(positional) != 1 {
+		fmt.Fprintf(stderr, "gpa entrypoints: expected exactly one repository directory\n\n%s", usage)
+		return exitUsage
+	}
+
+	entryPoints, err := findEntryPoints(positional[0])
+	if err != nil {
+		fmt.Fprintf(stderr, "gpa entrypoints: %v\n", err)
+		return exitError
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(entryPoints)
+	} else {
+		for _, entryPoint := range entryPoints {
+			if _, err = fmt.Fprintln(stdout, entryPoint.RelativePath); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "gpa entrypoints: writing output: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+// findEntryPoints discovers the entry points of a repository in path order.
+func findEntryPoints(repoRoot string) ([]entryPointJSON, error) {
+	absRepoRoot, err := filepath.Abs(repoRoot)
+	if err != nil {
+		return nil, fmt.Errorf("resolving repository root: %w", err)
+	}
+
+	paths, err := analyzer.New().FindEntryPoints(absRepoRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	entryPoints := make([]entryPointJSON, 0, len(paths))
+	for _, path := range paths {
+		relPath, relErr := filepath.Rel(absRepoRoot, path)
+		if relErr != nil {
+			return nil, fmt.Errorf("resolving relative path of %s: %w", path, relErr)
+		}
+		entryPoints = append(entryPoints, entryPointJSON{Path: path, RelativePath: filepath.ToSlash(relPath)})
+	}
+	return entryPoints, nil
+}
+
+// parseArgs parses flags that may appear before, between or after positional arguments,
+// and returns the positional arguments in order.
+func parseArgs(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		args = flags.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// parseExcludeList splits a comma-separated exclude list, dropping blank entries.
+func parseExcludeList(excludeDirsStr string) []string {
+	var excludeList []string
+	for _, dir := range strings.Split(excludeDirsStr, ",") {
+		if dir = strings.TrimSpace(dir); dir != "" {
+			excludeList = append(excludeList, dir)
+		}
+	}
+	return excludeList
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createProject creates a module whose entry imports api and store, and returns the entry file.
+func createProject(t *testing.T) string {
+	t.Helper()
+
+	return writeProject(t, map[string]string{
+		"main.go": "package main\n\nimport (\n\t_ \"example.com/app/api\"\n\t_ \"example.com/app/store\"\n)\n\n" +
+			"func main() {}\n",
+		"api/api.go":     "package api\n",
+		"store/store.go": "package store\n",
+	})
+}
+
+// writeProject writes files into a new example.com/app module and returns its main.go path.
+func writeProject(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/app\n\ngo 1.21\n"), 0644))
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	return filepath.Join(root, "main.go")
+}
+
+func TestRun_AnalyzeWritesDOTToStdout(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	code := run([]string{"analyze", createProject(t)}, &stdout, &stderr)
+
+	require.Equal(t, exitOK, code, stderr.String())
+	assert.Contains(t, stdout.String(), "digraph dependencies {")
+	assert.Contains(t, stdout.String(), "example_com_app_api")
+	assert.Contains(t, stdout.String(), "example_com_app_store")
+}
+
+func TestRun_AnalyzeWritesJSONToFile(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	out := filepath.Join(t.TempDir(), "graph.json")
+
+	code := run(
+		[]string{"analyze", createProject(t), "--format", "json", "--out", out, "--exclude", "store"},
+		&stdout,
+		&stderr,
+	)
+
+	require.Equal(t, exitOK, code, stderr.String())
+	assert.Empty(t, stdout.String())
+
+	content, err := os.ReadFile(out)
+	require.NoError(t, err)
+	var graph struct {
+		Packages []struct {
+			Path string `json:"path"`
+		} `json:"packages"`
+	}
+	require.NoError(t, json.Unmarshal(content, &graph))
+	require.Len(t, graph.Packages, 2)
+	assert.Equal(t, "example.com/app", graph.Packages[0].Path)
+	assert.Equal(t, "example.com/app/api", graph.Packages[1].Path)
+}
+
+func TestRun_AnalyzeWritesJSONFiles(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	code := run([]string{"analyze", createProject(t), "--format", "json", "--files"}, &stdout, &stderr)
+
+	require.Equal(t, exitOK, code, stderr.String())
+	assert.Contains(t, stdout.String(), `"files": [`)
+	assert.Contains(t, stdout.String(), filepath.Join("api", "api.go"))
+}
+
+func TestRun_AnalyzeWritesHTML(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	entryFile := createProject(t)
+	// A stand-in for Graphviz that wraps the DOT it reads in an SVG element
+	binDir := t.TempDir()
+	script := "#!/bin/sh\necho '<svg><!--'\n/bin/cat\necho '--></svg>'\n"
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "dot"), []byte(script), 0o755))
+	t.Setenv("PATH", binDir)
+
+	code := run([]string{"analyze", entryFile, "--format", "html"}, &stdout, &stderr)
+
+	require.Equal(t, exitOK, code, stderr.String())
+	assert.True(t, strings.HasPrefix(stdout.String(), "<!DOCTYPE html>"))
+	assert.Contains(t, stdout.String(), `data-node="example_com_app_api"`)
+	assert.Contains(t, stdout.String(), "<svg><!--\ndigraph dependencies {")
+}
+
+func TestRun_UsageErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		args []string
+	}{
+		{name: "no command", args: nil},
+		{name: "unknown command", args: []string{"render"}},
+		{name: "missing entry", args: []string{"analyze"}},
+		{name: "unknown format", args: []string{"analyze", "main.go", "--format", "svg"}},
+		{name: "unknown flag", args: []string{"analyze", "main.go", "--colour"}},
+		{name: "missing repo", args: []string{"entrypoints", "--json"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+
+			assert.Equal(t, exitUsage, run(tc.args, &stdout, &stderr))
+			assert.NotEmpty(t, stderr.String())
+		})
+	}
+}
+
+func TestRun_AnalyzeMissingEntry(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	code := run([]string{"analyze", filepath.Join(t.TempDir(), "missing.go")}, &stdout, &stderr)
+
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, stderr.String(), "entry file not found")
+}
+
+func TestRun_EntryPoints(t *testing.T) {
+	root := filepath.Dir(createProject(t))
+	toolDir := filepath.Join(root, "cmd", "tool")
+	require.NoError(t, os.MkdirAll(toolDir, 0755))
+	require.NoError(t,
+		os.WriteFile(filepath.Join(toolDir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644))
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"entrypoints", root}, &stdout, &stderr)
+
+	require.Equal(t, exitOK, code, stderr.String())
+	assert.Equal(t, "cmd/tool/main.go\nmain.go\n", stdout.String())
+
+	stdout.Reset()
+	code = run([]string{"entrypoints", "--json", root}, &stdout, &stderr)
+
+	require.Equal(t, exitOK, code, stderr.String())
+	var entryPoints []entryPointJSON
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &entryPoints))
+	require.Len(t, entryPoints, 2)
+	assert.Equal(t, "cmd/tool/main.go", entryPoints[0].RelativePath)
+	assert.Equal(t, filepath.Join(toolDir, "main.go"), entryPoints[0].Path)
+}
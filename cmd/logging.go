@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Environment variables controlling the default slog handler.
+const (
+	logLevelEnv  = "LOG_LEVEL"  // debug, info, warn or error; defaults to info
+	logFormatEnv = "LOG_FORMAT" // text or json; defaults to text
+)
+
+// Log formats supported by LOG_FORMAT.
+const (
+	logFormatText = "text"
+	logFormatJSON = "json"
+)
+
+// configureLogging installs the default slog handler selected by LOG_LEVEL and LOG_FORMAT.
+// Analyzer and scanner warnings go through the default logger, so they honor the same level.
+// Invalid values fall back to the defaults with a warning.
+func configureLogging() {
+	handler, err := newLogHandler(os.Stderr, os.Getenv(logLevelEnv), os.Getenv(logFormatEnv))
+	slog.SetDefault(slog.New(handler))
+	if err != nil {
+		slog.Warn("Warning: invalid logging configuration", "error", err)
+	}
+}
+
+// newLogHandler builds a handler writing to w at the given level and format. Empty values
+// select info and text. On an invalid value it still returns a usable handler, built from
+// the defaults for that setting, along with the error.
+func newLogHandler(w io.Writer, level, format string) (slog.Handler, error) {
+	var errs []string
+
+	var logLevel slog.Level
+	if level != "" {
+		if err := logLevel.UnmarshalText([]byte(strings.TrimSpace(level))); err != nil {
+			errs = append(errs, fmt.Sprintf("unknown %s %q", logLevelEnv, level))
+			logLevel = slog.LevelInfo
+		}
+	}
+
+	options := &slog.HandlerOptions{Level: logLevel}
+	var handler slog.Handler
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "", logFormatText:
+		handler = slog.NewTextHandler(w, options)
+	case logFormatJSON:
+		handler = slog.NewJSONHandler(w, options)
+	default:
+		errs = append(errs, fmt.Sprintf("unknown %s %q", logFormatEnv, format))
+		handler = slog.NewTextHandler(w, options)
+	}
+
+	if len(errs) > 0 {
+		return handler, errors.New(strings.Join(errs, "; "))
+	}
+	return handler, nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLogHandler_Level(t *testing.T) {
+	testCases := []struct {
+		name     string
+		level    string
+		expected slog.Level
+		invalid  bool
+	}{
+		{name: "default", level: "", expected: slog.LevelInfo},
+		{name: "debug", level: "debug", expected: slog.LevelDebug},
+		{name: "case insensitive", level: " WARN ", expected: slog.LevelWarn},
+		{name: "error", level: "error", expected: slog.LevelError},
+		{name: "unknown falls back to info", level: "loud", expected: slog.LevelInfo, invalid: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler, err := newLogHandler(&bytes.Buffer{}, tc.level, "")
+			if tc.invalid {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), logLevelEnv)
+			} else {
+				require.NoError(t, err)
+			}
+
+			ctx := context.Background()
+			assert.True(t, handler.Enabled(ctx, tc.expected))
+			assert.False(t, handler.Enabled(ctx, tc.expected-1))
+		})
+	}
+}
+
+func TestNewLogHandler_Format(t *testing.T) {
+	var jsonOut bytes.Buffer
+	handler, err := newLogHandler(&jsonOut, "", "JSON")
+	require.NoError(t, err)
+	slog.New(handler).Warn("Warning: failed to analyze dependency", "dependency", "example.com/lib")
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(jsonOut.Bytes(), &record))
+	assert.Equal(t, "WARN", record["level"])
+	assert.Equal(t, "example.com/lib", record["dependency"])
+
+	var textOut bytes.Buffer
+	handler, err = newLogHandler(&textOut, "warn", "xml")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), logFormatEnv)
+	logger := slog.New(handler)
+	logger.Info("hidden")
+	logger.Warn("shown")
+	assert.NotContains(t, textOut.String(), "hidden")
+	assert.Contains(t, textOut.String(), "level=WARN msg=shown")
+}
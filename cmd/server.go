@@ -6,12 +6,16 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"path"
 	"path/filepath"
 	"runtime/debug"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -31,6 +35,15 @@ const (
 	serverShutdownTimeout   = 30 * time.Second  // Server graceful shutdown timeout
 )
 
+// Directory depth limits for the scan.dot endpoint, keeping scans of large trees bounded.
+const (
+	defaultScanDOTDepth = 2
+	maxScanDOTDepth     = 5
+)
+
+// maxArchiveUploadBytes bounds the request body of the analyze-archive endpoint.
+const maxArchiveUploadBytes = 100 << 20
+
 // APIResponse represents the response structure for the API.
 type APIResponse struct {
 	Success bool   `json:"success"`
@@ -45,9 +58,37 @@ type MultiEntryAPIResponse struct {
 	Error       string                `json:"error,omitempty"`
 	RepoRoot    string                `json:"repoRoot,omitempty"`
 	ModuleName  string                `json:"moduleName,omitempty"`
+	Summaries   []string              `json:"summaries,omitempty"` // One line per entry point, with summaries=true
 }
 
+// MetricsAPIResponse represents the response structure for the metrics endpoint.
+type MetricsAPIResponse struct {
+	Success bool                   `json:"success"`
+	Metrics *analyzer.GraphMetrics `json:"metrics,omitempty"`
+	Error   string                 `json:"error,omitempty"`
+}
+
+// ValidateAPIResponse represents the response structure for the validate endpoint.
+type ValidateAPIResponse struct {
+	Success    bool                      `json:"success"`
+	Validation *analyzer.EntryValidation `json:"validation,omitempty"`
+	Code       string                    `json:"code,omitempty"` // Machine-readable failure reason, see validationFailure
+	Error      string                    `json:"error,omitempty"`
+}
+
+// Failure codes reported by the validate endpoint.
+const (
+	validateCodeMissingEntry = "missing_entry"
+	validateCodeNotFound     = "not_found"
+	validateCodePermission   = "permission"
+	validateCodeNotGoFile    = "not_go_file"
+	validateCodeNoModule     = "no_module"
+	validateCodeInvalid      = "invalid"
+)
+
 func main() {
+	configureLogging()
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "6333"
@@ -68,8 +109,16 @@ func main() {
 
 	mux.HandleFunc("/api/analyze", handleAnalyze)
 	mux.HandleFunc("/api/analyze-repo", handleAnalyzeRepo)
-	mux.HandleFunc("/api/scan-directories", handleScanDirectories)
-	mux.HandleFunc("/api/list-directory", handleListDirectory)
+	mux.HandleFunc("/api/analyze-archive", handleAnalyzeArchive)
+	mux.HandleFunc("/api/metrics", handleMetrics)
+	mux.HandleFunc("/api/validate", handleValidate)
+	// The browsing endpoints share a cache, so expanding the same directories again does not
+	// repeat Go project detection
+	projectCache := scanner.NewProjectCache()
+	mux.HandleFunc("/api/scan-directories", withProjectCache(handleScanDirectories, projectCache))
+	mux.HandleFunc("/api/list-directory", withProjectCache(handleListDirectory, projectCache))
+	mux.HandleFunc("/api/scan.dot", withProjectCache(handleScanDOT, projectCache))
+	mux.HandleFunc("/ws", handleWebSocket)
 
 	server.Handler = mux
 
@@ -147,33 +196,20 @@ func handleAnalyze(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if entry file exists
-	if _, statErr := os.Stat(absEntryFile); os.IsNotExist(statErr) {
-		sendJSONResponse(w, APIResponse{
-			Success: false,
-			Error:   fmt.Sprintf("Entry file does not exist: %s", absEntryFile),
-		})
-		return
-	}
-
 	// Parse parameters
 	showExternal := showExternalStr == "true"
-	var excludeList []string
-	if excludeDirsStr != "" {
-		excludeList = strings.Split(excludeDirsStr, ",")
-		for i, dir := range excludeList {
-			excludeList[i] = strings.TrimSpace(dir)
-		}
-	}
+	excludeList := requestExcludeList(excludeDirsStr)
 
 	// Analyze the codebase
-	analyze := analyzer.New()
+	analyze := analyzer.New(requestOptions(r.URL.Query())...)
 	graph, err := analyze.AnalyzeFromFile(absEntryFile, !showExternal, excludeList)
 	if err != nil {
 		slog.Error("handleAnalyze: Analysis failed", slog.Any("error", err))
+		status, message := analysisErrorResponse(absEntryFile, err)
+		w.WriteHeader(status)
 		sendJSONResponse(w, APIResponse{
 			Success: false,
-			Error:   fmt.Sprintf("Error analyzing codebase: %v", err),
+			Error:   message,
 		})
 		return
 	}
@@ -189,6 +225,16 @@ func handleAnalyze(w http.ResponseWriter, r *http.Request) {
 	// Generate DOT content
 	viz := visualizer.New()
 	dotContent := viz.GenerateDOTContent(graph)
+	// Only the structural check runs per request; parsing with Graphviz would add its latency
+	if validateErr := viz.ValidateStructure(dotContent); validateErr != nil {
+		slog.Error("handleAnalyze: Generated invalid DOT", slog.Any("error", validateErr))
+		w.WriteHeader(http.StatusInternalServerError)
+		sendJSONResponse(w, APIResponse{
+			Success: false,
+			Error:   fmt.Sprintf("Generated graph is invalid: %v", validateErr),
+		})
+		return
+	}
 
 	sendJSONResponse(w, APIResponse{
 		Success: true,
@@ -240,16 +286,10 @@ func handleAnalyzeRepo(w http.ResponseWriter, r *http.Request) {
 
 	// Parse parameters
 	showExternal := showExternalStr == "true"
-	var excludeList []string
-	if excludeDirsStr != "" {
-		excludeList = strings.Split(excludeDirsStr, ",")
-		for i, dir := range excludeList {
-			excludeList[i] = strings.TrimSpace(dir)
-		}
-	}
+	excludeList := requestExcludeList(excludeDirsStr)
 
 	// Analyze the repository
-	analyze := analyzer.New()
+	analyze := analyzer.New(requestOptions(r.URL.Query())...)
 	result, err := analyze.AnalyzeMultipleEntryPoints(absRepoRoot, !showExternal, excludeList)
 	if err != nil {
 		slog.Error("handleAnalyzeRepo: Repository analysis failed", slog.Any("error", err))
@@ -260,6 +300,76 @@ func handleAnalyzeRepo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	sendMultiEntryResult(w, result, r.URL.Query().Get("summaries") == "true")
+}
+
+// handleAnalyzeArchive analyzes a project uploaded as the "archive" file of a multipart POST.
+// The format field selects zip or tar.gz; without it the file name's extension decides.
+// Like analyze-repo, it accepts the external and exclude parameters.
+func handleAnalyzeArchive(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if r.Method != http.MethodPost {
+		slog.Info("handleAnalyzeArchive: Method not allowed", slog.String("method", r.Method))
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxArchiveUploadBytes)
+	file, header, err := r.FormFile("archive")
+	if err != nil {
+		sendMultiEntryJSONResponse(w, MultiEntryAPIResponse{
+			Success: false,
+			Error:   fmt.Sprintf("archive upload is required: %v", err),
+		})
+		return
+	}
+	defer file.Close()
+	defer func() {
+		if removeErr := r.MultipartForm.RemoveAll(); removeErr != nil {
+			slog.Warn("handleAnalyzeArchive: Error removing uploaded files", slog.Any("error", removeErr))
+		}
+	}()
+
+	format := r.FormValue("format")
+	if format == "" {
+		format = archiveFormat(header.Filename)
+	}
+	showExternal := r.FormValue("external") == "true"
+	excludeList := requestExcludeList(r.FormValue("exclude"))
+
+	analyze := analyzer.New(requestOptions(r.URL.Query())...)
+	result, err := analyze.AnalyzeArchive(file, header.Size, format, !showExternal, excludeList)
+	if err != nil {
+		slog.Error("handleAnalyzeArchive: Archive analysis failed", slog.Any("error", err))
+		sendMultiEntryJSONResponse(w, MultiEntryAPIResponse{
+			Success: false,
+			Error:   fmt.Sprintf("Error analyzing archive: %v", err),
+		})
+		return
+	}
+
+	sendMultiEntryResult(w, result, r.FormValue("summaries") == "true")
+}
+
+// archiveFormat infers an archive format from a file name, or returns "" when the extension
+// is not recognized.
+func archiveFormat(filename string) string {
+	lower := strings.ToLower(filename)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return analyzer.ArchiveZip
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return analyzer.ArchiveTarGz
+	default:
+		return ""
+	}
+}
+
+// sendMultiEntryResult sends a multi-entry analysis, generating the DOT content of each
+// entry point and, when requested, a one-line summary of each, or its failure.
+func sendMultiEntryResult(w http.ResponseWriter, result *analyzer.MultiEntryAnalysisResult, summaries bool) {
 	if !result.Success {
 		sendMultiEntryJSONResponse(w, MultiEntryAPIResponse{
 			Success: false,
@@ -275,15 +385,149 @@ func handleAnalyzeRepo(w http.ResponseWriter, r *http.Request) {
 			result.EntryPoints[i].DOTContent = viz.GenerateDOTContent(result.EntryPoints[i].Graph)
 		}
 	}
-	sendMultiEntryJSONResponse(w, MultiEntryAPIResponse{
+	response := MultiEntryAPIResponse{
 		Success:     true,
 		EntryPoints: result.EntryPoints,
 		RepoRoot:    result.RepoRoot,
 		ModuleName:  result.ModuleName,
+	}
+	if summaries {
+		for _, entryPoint := range result.EntryPoints {
+			response.Summaries = append(response.Summaries, entryPointSummary(entryPoint))
+		}
+	}
+	sendMultiEntryJSONResponse(w, response)
+}
+
+// entryPointSummary describes an entry point on one line, named by its directory relative to
+// the repository root, as in "cmd/api: 12 pkgs, 3 layers, 0 cycles".
+func entryPointSummary(entryPoint analyzer.EntryPoint) string {
+	dir := path.Dir(filepath.ToSlash(entryPoint.RelativePath))
+	if entryPoint.Graph == nil {
+		return dir + ": not analyzed"
+	}
+	return dir + ": " + entryPoint.Graph.Summary()
+}
+
+func handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if r.Method != http.MethodGet {
+		slog.Info("handleMetrics: Method not allowed", slog.String("method", r.Method))
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get query parameters
+	entryFile := r.URL.Query().Get("entry")
+	showExternal := r.URL.Query().Get("external") == "true"
+	excludeList := requestExcludeList(r.URL.Query().Get("exclude"))
+	countTodos := r.URL.Query().Get("todos") == "true"
+	complexity := r.URL.Query().Get("complexity") == "true"
+
+	if entryFile == "" {
+		sendMetricsJSONResponse(w, MetricsAPIResponse{
+			Success: false,
+			Error:   "entry parameter is required",
+		})
+		return
+	}
+
+	// Convert relative path to absolute
+	absEntryFile, err := filepath.Abs(entryFile)
+	if err != nil {
+		sendMetricsJSONResponse(w, MetricsAPIResponse{
+			Success: false,
+			Error:   fmt.Sprintf("Error resolving entry file path: %v", err),
+		})
+		return
+	}
+
+	// Analyze the codebase; DOT generation is skipped entirely
+	opts := append(requestOptions(r.URL.Query()),
+		analyzer.WithTodoCounts(countTodos),
+		analyzer.WithComplexity(complexity))
+	analyze := analyzer.New(opts...)
+	graph, err := analyze.AnalyzeFromFile(absEntryFile, !showExternal, excludeList)
+	if err != nil {
+		slog.Error("handleMetrics: Analysis failed", slog.Any("error", err))
+		status, message := analysisErrorResponse(absEntryFile, err)
+		w.WriteHeader(status)
+		sendMetricsJSONResponse(w, MetricsAPIResponse{
+			Success: false,
+			Error:   message,
+		})
+		return
+	}
+
+	sendMetricsJSONResponse(w, MetricsAPIResponse{
+		Success: true,
+		Metrics: analyze.ComputeMetrics(graph),
 	})
 }
 
-func handleScanDirectories(w http.ResponseWriter, r *http.Request) {
+func handleValidate(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if r.Method != http.MethodGet {
+		slog.Info("handleValidate: Method not allowed", slog.String("method", r.Method))
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	entryFile := r.URL.Query().Get("entry")
+	if entryFile == "" {
+		sendValidateJSONResponse(w, ValidateAPIResponse{
+			Success: false,
+			Code:    validateCodeMissingEntry,
+			Error:   "entry parameter is required",
+		})
+		return
+	}
+
+	absEntryFile, err := filepath.Abs(entryFile)
+	if err != nil {
+		sendValidateJSONResponse(w, ValidateAPIResponse{
+			Success: false,
+			Code:    validateCodeInvalid,
+			Error:   fmt.Sprintf("Error resolving entry file path: %v", err),
+		})
+		return
+	}
+
+	// Only locate the module; no packages are parsed
+	validation, err := analyzer.New().ValidateEntry(absEntryFile)
+	if err != nil {
+		code, message := validationFailure(absEntryFile, err)
+		sendValidateJSONResponse(w, ValidateAPIResponse{
+			Success: false,
+			Code:    code,
+			Error:   message,
+		})
+		return
+	}
+
+	sendValidateJSONResponse(w, ValidateAPIResponse{
+		Success:    true,
+		Validation: validation,
+	})
+}
+
+// browseHandler is a directory browsing handler, passed the project cache the browsing
+// endpoints share.
+type browseHandler func(w http.ResponseWriter, r *http.Request, projectCache *scanner.ProjectCache)
+
+// withProjectCache adapts a browsing handler to an http.HandlerFunc using projectCache, which
+// may be nil to detect Go projects without caching.
+func withProjectCache(handler browseHandler, projectCache *scanner.ProjectCache) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler(w, r, projectCache)
+	}
+}
+
+func handleScanDirectories(w http.ResponseWriter, r *http.Request, projectCache *scanner.ProjectCache) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 
@@ -294,11 +538,15 @@ func handleScanDirectories(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create scanner and get filesystem roots
-	scan := scanner.New()
+	scan := scanner.New(
+		scanner.WithScanRoot(os.Getenv(scanRootEnv)),
+		scanner.WithIncludeHidden(r.URL.Query().Get("hidden") == "true"),
+		scanner.WithProjectCache(projectCache),
+	)
 	result, err := scan.GetFilesystemRoots()
 	if err != nil {
 		slog.Error("handleScanDirectories: Scan failed", slog.Any("error", err))
-		if encodeErr := json.NewEncoder(w).Encode(scanner.ScanResult{
+		if encodeErr := newJSONEncoder(w).Encode(scanner.ScanResult{
 			Success: false,
 			Error:   fmt.Sprintf("Error getting filesystem roots: %v", err),
 		}); encodeErr != nil {
@@ -308,13 +556,13 @@ func handleScanDirectories(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Return the scan result
-	if encodeErr := json.NewEncoder(w).Encode(result); encodeErr != nil {
+	if encodeErr := newJSONEncoder(w).Encode(result); encodeErr != nil {
 		slog.Error("handleScanDirectories: Error encoding response", slog.Any("error", encodeErr))
 		return
 	}
 }
 
-func handleListDirectory(w http.ResponseWriter, r *http.Request) {
+func handleListDirectory(w http.ResponseWriter, r *http.Request, projectCache *scanner.ProjectCache) {
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 
@@ -327,7 +575,7 @@ func handleListDirectory(w http.ResponseWriter, r *http.Request) {
 	// Get the directory path from query parameter
 	dirPath := r.URL.Query().Get("path")
 	if dirPath == "" {
-		if encodeErr := json.NewEncoder(w).Encode(scanner.DirectoryListResult{
+		if encodeErr := newJSONEncoder(w).Encode(scanner.DirectoryListResult{
 			Success: false,
 			Error:   "path parameter is required",
 		}); encodeErr != nil {
@@ -336,12 +584,17 @@ func handleListDirectory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Create scanner and list directory
-	scan := scanner.New()
+	// Create scanner and list directory, with hidden directories when hidden=true and without
+	// git-ignored directories when gitignore=true
+	scan := scanner.New(
+		scanner.WithIncludeHidden(r.URL.Query().Get("hidden") == "true"),
+		scanner.WithGitignore(r.URL.Query().Get("gitignore") == "true"),
+		scanner.WithProjectCache(projectCache),
+	)
 	result, err := scan.ListDirectory(dirPath)
 	if err != nil {
 		slog.Error("handleListDirectory: List failed", slog.Any("error", err), slog.String("path", dirPath))
-		if encodeErr := json.NewEncoder(w).Encode(scanner.DirectoryListResult{
+		if encodeErr := newJSONEncoder(w).Encode(scanner.DirectoryListResult{
 			Success: false,
 			Error:   fmt.Sprintf("Error listing directory: %v", err),
 		}); encodeErr != nil {
@@ -351,21 +604,177 @@ func handleListDirectory(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Return the list result
-	if encodeErr := json.NewEncoder(w).Encode(result); encodeErr != nil {
+	if encodeErr := newJSONEncoder(w).Encode(result); encodeErr != nil {
 		slog.Error("handleListDirectory: Error encoding response", slog.Any("error", encodeErr))
 		return
 	}
 }
 
+// handleScanDOT renders the directory tree below the path parameter as a DOT graph, with
+// Go project directories highlighted. The optional depth parameter sets how many levels are
+// loaded, between 1 and maxScanDOTDepth.
+func handleScanDOT(w http.ResponseWriter, r *http.Request, projectCache *scanner.ProjectCache) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	if r.Method != http.MethodGet {
+		slog.Info("handleScanDOT: Method not allowed", slog.String("method", r.Method))
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	dirPath := r.URL.Query().Get("path")
+	if dirPath == "" {
+		http.Error(w, "path parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	depth := defaultScanDOTDepth
+	if depthStr := r.URL.Query().Get("depth"); depthStr != "" {
+		parsed, err := strconv.Atoi(depthStr)
+		if err != nil || parsed < 1 || parsed > maxScanDOTDepth {
+			http.Error(w, fmt.Sprintf("depth must be between 1 and %d", maxScanDOTDepth), http.StatusBadRequest)
+			return
+		}
+		depth = parsed
+	}
+
+	scan := scanner.New(scanner.WithProjectCache(projectCache))
+	result, err := scan.ListDirectoryDepth(dirPath, depth)
+	if err != nil {
+		slog.Error("handleScanDOT: List failed", slog.Any("error", err), slog.String("path", dirPath))
+		http.Error(w, fmt.Sprintf("Error listing directory: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !result.Success {
+		http.Error(w, result.Error, http.StatusBadRequest)
+		return
+	}
+
+	dotContent := visualizer.New().GenerateDirectoryDOT(filepath.Clean(dirPath), result.Directories)
+	w.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+	if _, writeErr := io.WriteString(w, dotContent); writeErr != nil {
+		slog.Error("handleScanDOT: Error writing response", slog.Any("error", writeErr))
+	}
+}
+
+// requestOptions returns the analyzer options selected by a request's view and
+// hideInternal parameters.
+func requestOptions(query url.Values) []analyzer.Option {
+	opts := viewOptions(query.Get("view"))
+	if query.Get("hideInternal") == "true" {
+		opts = append(opts, analyzer.WithHideInternal(true))
+	}
+	return opts
+}
+
+// viewProduction is the view parameter value restricting analysis to production architecture.
+const viewProduction = "production"
+
+// viewOptions returns the analyzer options selected by a request's view parameter.
+// Unknown views, including the empty default, select the full graph.
+func viewOptions(view string) []analyzer.Option {
+	if view == viewProduction {
+		return []analyzer.Option{analyzer.WithProductionOnly(true)}
+	}
+	return nil
+}
+
+// scanRootEnv names the environment variable holding the directory the browse UI starts from
+// instead of the filesystem roots.
+const scanRootEnv = "SCAN_ROOT"
+
+// defaultExcludeEnv names the environment variable holding exclusions applied to every analysis.
+const defaultExcludeEnv = "DEFAULT_EXCLUDE"
+
+// requestExcludeList combines the DEFAULT_EXCLUDE exclusions with a request's exclude parameter.
+// Request entries add to the defaults rather than replacing them; duplicates and blanks are dropped.
+func requestExcludeList(excludeDirsStr string) []string {
+	var excludeList []string
+	seen := make(map[string]bool)
+
+	for _, dir := range append(parseExcludeList(os.Getenv(defaultExcludeEnv)), parseExcludeList(excludeDirsStr)...) {
+		if dir == "" || seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		excludeList = append(excludeList, dir)
+	}
+
+	return excludeList
+}
+
+// parseExcludeList splits a comma-separated exclude parameter into trimmed entries.
+func parseExcludeList(excludeDirsStr string) []string {
+	if excludeDirsStr == "" {
+		return nil
+	}
+
+	excludeList := strings.Split(excludeDirsStr, ",")
+	for i, dir := range excludeList {
+		excludeList[i] = strings.TrimSpace(dir)
+	}
+	return excludeList
+}
+
+// validationFailure maps a ValidateEntry error to a failure code and user-facing message.
+func validationFailure(absEntryFile string, err error) (string, string) {
+	switch {
+	case errors.Is(err, analyzer.ErrEntryNotFound):
+		return validateCodeNotFound, fmt.Sprintf("Entry file does not exist: %s", absEntryFile)
+	case errors.Is(err, analyzer.ErrPermission):
+		return validateCodePermission, fmt.Sprintf("Permission denied - cannot read entry file: %s", absEntryFile)
+	case errors.Is(err, analyzer.ErrNotGoFile):
+		return validateCodeNotGoFile, fmt.Sprintf("Entry file is not a .go file: %s", absEntryFile)
+	case errors.Is(err, analyzer.ErrNoModule):
+		return validateCodeNoModule, fmt.Sprintf("No go.mod found above entry file: %s", absEntryFile)
+	default:
+		return validateCodeInvalid, fmt.Sprintf("Error validating entry file: %v", err)
+	}
+}
+
+// analysisErrorResponse maps an AnalyzeFromFile error to an HTTP status and user-facing message.
+func analysisErrorResponse(absEntryFile string, err error) (int, string) {
+	switch {
+	case errors.Is(err, analyzer.ErrEntryNotFound):
+		return http.StatusNotFound, fmt.Sprintf("Entry file does not exist: %s", absEntryFile)
+	case errors.Is(err, analyzer.ErrPermission):
+		return http.StatusForbidden, fmt.Sprintf("Permission denied - cannot read entry file: %s", absEntryFile)
+	default:
+		return http.StatusOK, fmt.Sprintf("Error analyzing codebase: %v", err)
+	}
+}
+
+// newJSONEncoder returns an encoder that writes package paths and DOT content verbatim.
+// HTML escaping is disabled so that characters such as "<", ">" and "&" round-trip unchanged.
+func newJSONEncoder(w io.Writer) *json.Encoder {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	return encoder
+}
+
 func sendJSONResponse(w http.ResponseWriter, response APIResponse) {
-	if err := json.NewEncoder(w).Encode(response); err != nil {
+	if err := newJSONEncoder(w).Encode(response); err != nil {
 		slog.Error("sendJSONResponse: Error encoding response", slog.Any("error", err))
 		return
 	}
 }
 
+func sendValidateJSONResponse(w http.ResponseWriter, response ValidateAPIResponse) {
+	if err := newJSONEncoder(w).Encode(response); err != nil {
+		slog.Error("sendValidateJSONResponse: Error encoding response", slog.Any("error", err))
+		return
+	}
+}
+
+func sendMetricsJSONResponse(w http.ResponseWriter, response MetricsAPIResponse) {
+	if err := newJSONEncoder(w).Encode(response); err != nil {
+		slog.Error("sendMetricsJSONResponse: Error encoding response", slog.Any("error", err))
+		return
+	}
+}
+
 func sendMultiEntryJSONResponse(w http.ResponseWriter, response MultiEntryAPIResponse) {
-	if err := json.NewEncoder(w).Encode(response); err != nil {
+	if err := newJSONEncoder(w).Encode(response); err != nil {
 		slog.Error("sendMultiEntryJSONResponse: Error encoding response", slog.Any("error", err))
 		return
 	}
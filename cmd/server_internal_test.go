@@ -0,0 +1,424 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cvsouth/go-package-analyzer/internal/scanner"
+)
+
+// unicodeNames are package and directory names that must survive JSON encoding unchanged.
+func unicodeNames() []string {
+	return []string{"тест", "测试"}
+}
+
+// createUnicodeProject creates a module whose entry imports one package per unicode name.
+func createUnicodeProject(t *testing.T) string {
+	t.Helper()
+
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/app\n\ngo 1.21\n"), 0644))
+
+	var imports bytes.Buffer
+	for _, name := range unicodeNames() {
+		pkgDir := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(pkgDir, 0755))
+		content := "package " + name + "\n\nfunc Hello() {}\n"
+		require.NoError(t, os.WriteFile(filepath.Join(pkgDir, name+".go"), []byte(content), 0644))
+		imports.WriteString("import _ \"example.com/app/" + name + "\"\n")
+	}
+
+	mainContent := "package main\n\n" + imports.String() + "\nfunc main() {}\n"
+	require.NoError(t, os.WriteFile(filepath.Join(root, "main.go"), []byte(mainContent), 0644))
+
+	return root
+}
+
+// serve runs a handler against a GET request and returns the raw response body.
+func serve(t *testing.T, handler http.HandlerFunc, target string) []byte {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	return rec.Body.Bytes()
+}
+
+func TestHandleAnalyze_UnicodePackagePaths(t *testing.T) {
+	root := createUnicodeProject(t)
+	query := url.Values{"entry": {filepath.Join(root, "main.go")}}
+
+	body := serve(t, handleAnalyze, "/api/analyze?"+query.Encode())
+
+	var response APIResponse
+	require.NoError(t, json.Unmarshal(body, &response))
+	require.True(t, response.Success, response.Error)
+
+	for _, name := range unicodeNames() {
+		assert.Contains(t, string(body), "example_com_app_"+name, "Package node should be written verbatim")
+		assert.Contains(t, response.DOT, `label="`+name)
+	}
+	assert.Contains(t, string(body), "->", "DOT edges should not be HTML-escaped")
+
+	var reencoded bytes.Buffer
+	require.NoError(t, newJSONEncoder(&reencoded).Encode(response))
+	assert.Equal(t, body, reencoded.Bytes(), "Response should round-trip byte for byte")
+}
+
+func TestHandleAnalyzeRepo_UnicodePackagePaths(t *testing.T) {
+	root := createUnicodeProject(t)
+	query := url.Values{"repo": {root}}
+
+	body := serve(t, handleAnalyzeRepo, "/api/analyze-repo?"+query.Encode())
+
+	var response MultiEntryAPIResponse
+	require.NoError(t, json.Unmarshal(body, &response))
+	require.True(t, response.Success, response.Error)
+	require.Len(t, response.EntryPoints, 1)
+
+	for _, name := range unicodeNames() {
+		assert.Contains(t, string(body), "example_com_app_"+name, "Package node should be written verbatim")
+		assert.Contains(t, response.EntryPoints[0].DOTContent, `label="`+name)
+	}
+
+	var reencoded bytes.Buffer
+	require.NoError(t, newJSONEncoder(&reencoded).Encode(response))
+	assert.Equal(t, body, reencoded.Bytes(), "Response should round-trip byte for byte")
+}
+
+func TestHandleAnalyzeRepo_Summaries(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"go.mod":             "module example.com/app\n\ngo 1.21\n",
+		"cmd/api/main.go":    "package main\n\nimport _ \"example.com/app/store\"\n\nfunc main() {}\n",
+		"cmd/worker/main.go": "package main\n\nfunc main() {}\n",
+		"store/store.go":     "package store\n",
+	}
+	for name, content := range files {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(root, name)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, name), []byte(content), 0644))
+	}
+
+	var response MultiEntryAPIResponse
+	body := serve(t, handleAnalyzeRepo, "/api/analyze-repo?"+url.Values{"repo": {root}}.Encode())
+	require.NoError(t, json.Unmarshal(body, &response))
+	require.True(t, response.Success, response.Error)
+	assert.Nil(t, response.Summaries, "Summaries should only be sent on request")
+
+	query := url.Values{"repo": {root}, "summaries": {"true"}}
+	body = serve(t, handleAnalyzeRepo, "/api/analyze-repo?"+query.Encode())
+	require.NoError(t, json.Unmarshal(body, &response))
+	require.True(t, response.Success, response.Error)
+	assert.Equal(t, []string{
+		"cmd/api: 2 pkgs, 2 layers, 0 cycles",
+		"cmd/worker: 1 pkgs, 1 layers, 0 cycles",
+	}, response.Summaries)
+	assert.Len(t, response.EntryPoints, 2, "Summaries should come alongside the full data")
+}
+
+func TestHandleAnalyzeRepo_Deterministic(t *testing.T) {
+	repo, err := filepath.Abs("../testing/data/complex_project")
+	require.NoError(t, err)
+	target := "/api/analyze-repo?" + url.Values{"repo": {repo}, "external": {"true"}}.Encode()
+
+	first := serve(t, handleAnalyzeRepo, target)
+	var response MultiEntryAPIResponse
+	require.NoError(t, json.Unmarshal(first, &response))
+	require.True(t, response.Success, response.Error)
+
+	for range 3 {
+		assert.Equal(t, string(first), string(serve(t, handleAnalyzeRepo, target)),
+			"Entry points and their DOT should be identical across requests")
+	}
+}
+
+func TestHandleAnalyzeArchive(t *testing.T) {
+	var archive bytes.Buffer
+	zipWriter := zip.NewWriter(&archive)
+	for name, content := range map[string]string{
+		"app/go.mod":  "module example.com/app\n\ngo 1.21\n",
+		"app/main.go": "package main\n\nfunc main() {}\n",
+	} {
+		fileWriter, err := zipWriter.Create(name)
+		require.NoError(t, err)
+		_, err = fileWriter.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zipWriter.Close())
+
+	upload := func(filename string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		form := multipart.NewWriter(&body)
+		part, err := form.CreateFormFile("archive", filename)
+		require.NoError(t, err)
+		_, err = part.Write(archive.Bytes())
+		require.NoError(t, err)
+		require.NoError(t, form.Close())
+
+		req := httptest.NewRequest(http.MethodPost, "/api/analyze-archive", &body)
+		req.Header.Set("Content-Type", form.FormDataContentType())
+		rec := httptest.NewRecorder()
+		handleAnalyzeArchive(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+		return rec
+	}
+
+	var response MultiEntryAPIResponse
+	require.NoError(t, json.Unmarshal(upload("app.zip").Body.Bytes(), &response))
+	require.True(t, response.Success, response.Error)
+	assert.Equal(t, "example.com/app", response.ModuleName)
+	require.Len(t, response.EntryPoints, 1)
+	assert.Contains(t, response.EntryPoints[0].DOTContent, "digraph")
+
+	require.NoError(t, json.Unmarshal(upload("app.rar").Body.Bytes(), &response))
+	assert.False(t, response.Success)
+	assert.Contains(t, response.Error, "unsupported archive format")
+
+	rec := httptest.NewRecorder()
+	handleAnalyzeArchive(rec, httptest.NewRequest(http.MethodGet, "/api/analyze-archive", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestHandleListDirectory_UnicodeDirectoryNames(t *testing.T) {
+	root := createUnicodeProject(t)
+	query := url.Values{"path": {root}}
+
+	body := serve(t, withProjectCache(handleListDirectory, nil), "/api/list-directory?"+query.Encode())
+
+	var response scanner.DirectoryListResult
+	require.NoError(t, json.Unmarshal(body, &response))
+	require.True(t, response.Success, response.Error)
+
+	var names []string
+	for _, dir := range response.Directories {
+		names = append(names, dir.Name)
+		assert.Equal(t, filepath.Join(root, dir.Name), dir.Path)
+	}
+	assert.ElementsMatch(t, unicodeNames(), names)
+
+	for _, name := range unicodeNames() {
+		assert.Contains(t, string(body), `"name":"`+name+`"`, "Directory name should be written verbatim")
+	}
+
+	var reencoded bytes.Buffer
+	require.NoError(t, newJSONEncoder(&reencoded).Encode(response))
+	assert.Equal(t, body, reencoded.Bytes(), "Response should round-trip byte for byte")
+}
+
+func TestHandleScanDOT(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "projects", "service"), 0755))
+	goMod := filepath.Join(root, "projects", "service", "go.mod")
+	require.NoError(t, os.WriteFile(goMod, []byte("module service\n"), 0644))
+	query := url.Values{"path": {root}}
+
+	body := string(serve(t, withProjectCache(handleScanDOT, nil), "/api/scan.dot?"+query.Encode()))
+	assert.True(t, strings.HasPrefix(body, "digraph directories {"))
+	assert.Contains(t, body, `label="projects"`)
+	assert.Contains(t, body, `label="service", fillcolor="rgba(111,220,140,0.05)"`,
+		"Go projects two levels down should be highlighted at the default depth")
+
+	query.Set("depth", "1")
+	body = string(serve(t, withProjectCache(handleScanDOT, nil), "/api/scan.dot?"+query.Encode()))
+	assert.Contains(t, body, `label="projects"`)
+	assert.NotContains(t, body, `label="service"`)
+
+	for _, target := range []string{
+		"/api/scan.dot",
+		"/api/scan.dot?" + url.Values{"path": {root}, "depth": {"0"}}.Encode(),
+		"/api/scan.dot?" + url.Values{"path": {filepath.Join(root, "missing")}}.Encode(),
+	} {
+		rec := httptest.NewRecorder()
+		handleScanDOT(rec, httptest.NewRequest(http.MethodGet, target, nil), nil)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, target)
+	}
+}
+
+func TestHandleAnalyze_MissingEntryReturnsNotFound(t *testing.T) {
+	query := url.Values{"entry": {filepath.Join(t.TempDir(), "missing.go")}}
+	req := httptest.NewRequest(http.MethodGet, "/api/analyze?"+query.Encode(), nil)
+	rec := httptest.NewRecorder()
+
+	handleAnalyze(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	var response APIResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	assert.False(t, response.Success)
+	assert.Contains(t, response.Error, "Entry file does not exist")
+}
+
+func TestHandleMetrics(t *testing.T) {
+	query := url.Values{"entry": {createLayeredProject(t)}}
+
+	body := serve(t, handleMetrics, "/api/metrics?"+query.Encode())
+
+	var response MetricsAPIResponse
+	require.NoError(t, json.Unmarshal(body, &response))
+	require.True(t, response.Success, response.Error)
+	require.NotNil(t, response.Metrics)
+	assert.NotContains(t, string(body), "digraph", "Metrics should not include DOT content")
+
+	metrics := response.Metrics
+	assert.Equal(t, 3, metrics.TotalPackages)
+	assert.Equal(t, 2, metrics.TotalEdges)
+	assert.Equal(t, 3, metrics.TotalFiles)
+	assert.Equal(t, 3, metrics.LayerCount)
+
+	require.Len(t, metrics.Packages, 3)
+	api := metrics.Packages[1]
+	assert.Equal(t, "example.com/app/api", api.Path)
+	assert.Equal(t, 1, api.FanIn)
+	assert.Equal(t, 1, api.FanOut)
+	assert.InDelta(t, 0.5, api.Instability, 0.0001)
+	assert.Equal(t, 3, api.LineCount)
+}
+
+func TestHandleMetrics_MissingEntry(t *testing.T) {
+	body := serve(t, handleMetrics, "/api/metrics")
+
+	var response MetricsAPIResponse
+	require.NoError(t, json.Unmarshal(body, &response))
+	assert.False(t, response.Success)
+	assert.Equal(t, "entry parameter is required", response.Error)
+}
+
+func TestRequestExcludeList(t *testing.T) {
+	testCases := []struct {
+		name      string
+		defaults  string
+		requested string
+		expected  []string
+	}{
+		{name: "neither set", expected: nil},
+		{name: "defaults only", defaults: "vendor, testdata", expected: []string{"vendor", "testdata"}},
+		{name: "request only", requested: "mocks", expected: []string{"mocks"}},
+		{
+			name:      "request adds to defaults",
+			defaults:  "vendor,testdata",
+			requested: "mocks, vendor,",
+			expected:  []string{"vendor", "testdata", "mocks"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(defaultExcludeEnv, tc.defaults)
+
+			assert.Equal(t, tc.expected, requestExcludeList(tc.requested))
+		})
+	}
+}
+
+func TestHandleAnalyze_DefaultExclude(t *testing.T) {
+	t.Setenv(defaultExcludeEnv, "store")
+	query := url.Values{"entry": {createLayeredProject(t)}}
+
+	body := serve(t, handleAnalyze, "/api/analyze?"+query.Encode())
+
+	var response APIResponse
+	require.NoError(t, json.Unmarshal(body, &response))
+	require.True(t, response.Success, response.Error)
+	assert.Contains(t, response.DOT, "example_com_app_api")
+	assert.NotContains(t, response.DOT, "example_com_app_store")
+}
+
+func TestHandleAnalyze_ProductionView(t *testing.T) {
+	entry := createLayeredProject(t)
+
+	for view, expectAPI := range map[string]bool{"": true, "production": false} {
+		t.Run("view="+view, func(t *testing.T) {
+			query := url.Values{"entry": {entry}, "view": {view}}
+
+			body := serve(t, handleAnalyze, "/api/analyze?"+query.Encode())
+
+			var response APIResponse
+			require.NoError(t, json.Unmarshal(body, &response))
+			require.True(t, response.Success, response.Error)
+			assert.Equal(t, expectAPI, strings.Contains(response.DOT, "example_com_app_api"),
+				"Side-effect-only imports should only be dropped in the production view")
+		})
+	}
+}
+
+func TestHandleAnalyze_HideInternal(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"go.mod":                  "module example.com/lib\n\ngo 1.21\n",
+		"main.go":                 "package main\n\nimport _ \"example.com/lib/api\"\n\nfunc main() {}\n",
+		"api/api.go":              "package api\n\nimport _ \"example.com/lib/internal/codec\"\n",
+		"internal/codec/codec.go": "package codec\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	for hideInternal, expectCodec := range map[string]bool{"": true, "true": false} {
+		t.Run("hideInternal="+hideInternal, func(t *testing.T) {
+			query := url.Values{"entry": {filepath.Join(root, "main.go")}, "hideInternal": {hideInternal}}
+
+			body := serve(t, handleAnalyze, "/api/analyze?"+query.Encode())
+
+			var response APIResponse
+			require.NoError(t, json.Unmarshal(body, &response))
+			require.True(t, response.Success, response.Error)
+			assert.Contains(t, response.DOT, "example_com_lib_api")
+			assert.Equal(t, expectCodec, strings.Contains(response.DOT, "example_com_lib_internal_codec"))
+		})
+	}
+}
+
+func TestHandleValidate(t *testing.T) {
+	entry := createLayeredProject(t)
+	testCases := []struct {
+		name         string
+		entry        string
+		expectedCode string
+	}{
+		{name: "valid entry", entry: entry},
+		{name: "missing parameter", expectedCode: validateCodeMissingEntry},
+		{
+			name:         "missing file",
+			entry:        filepath.Join(filepath.Dir(entry), "missing.go"),
+			expectedCode: validateCodeNotFound,
+		},
+		{
+			name:         "not a go file",
+			entry:        filepath.Join(filepath.Dir(entry), "go.mod"),
+			expectedCode: validateCodeNotGoFile,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			query := url.Values{"entry": {tc.entry}}
+
+			body := serve(t, handleValidate, "/api/validate?"+query.Encode())
+
+			var response ValidateAPIResponse
+			require.NoError(t, json.Unmarshal(body, &response))
+			assert.Equal(t, tc.expectedCode, response.Code)
+			assert.Equal(t, tc.expectedCode == "", response.Success, response.Error)
+			if response.Success {
+				assert.Equal(t, "example.com/app", response.Validation.ModuleName)
+				assert.NotContains(t, string(body), "digraph", "Validation should not analyze the graph")
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/cvsouth/go-package-analyzer/internal/analyzer"
+	"github.com/cvsouth/go-package-analyzer/internal/visualizer"
+)
+
+// WebSocket limits.
+const (
+	wsReadBufferSize  = 4096    // WebSocket read buffer size in bytes
+	wsWriteBufferSize = 4096    // WebSocket write buffer size in bytes
+	wsMaxMessageBytes = 1 << 16 // Largest accepted command message (64 KB)
+)
+
+// WebSocket command types.
+const (
+	wsCommandAnalyze    = "analyze"
+	wsCommandFocus      = "focus"
+	wsCommandDependents = "dependents"
+)
+
+// WSCommand is a request sent by the client over the WebSocket connection.
+type WSCommand struct {
+	ID       string `json:"id,omitempty"`       // Echoed back so clients can match responses
+	Type     string `json:"type"`               // One of "analyze", "focus" or "dependents"
+	Entry    string `json:"entry,omitempty"`    // Entry file for "analyze"
+	External bool   `json:"external,omitempty"` // Include external packages for "analyze"
+	Exclude  string `json:"exclude,omitempty"`  // Comma-separated exclusions for "analyze"
+	Package  string `json:"package,omitempty"`  // Package path for "focus" and "dependents"
+}
+
+// WSResponse is a result streamed back to the client for a single command.
+type WSResponse struct {
+	ID       string   `json:"id,omitempty"`
+	Type     string   `json:"type"`
+	Success  bool     `json:"success"`
+	DOT      string   `json:"dot,omitempty"`
+	Packages []string `json:"packages,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// wsSession holds the per-connection state, so drill-downs reuse the last analyzed graph.
+type wsSession struct {
+	analyzer *analyzer.Analyzer
+	graph    *analyzer.DependencyGraph
+}
+
+func handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  wsReadBufferSize,
+		WriteBufferSize: wsWriteBufferSize,
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		slog.Info("handleWebSocket: Upgrade failed", slog.Any("error", err))
+		return
+	}
+	defer conn.Close()
+	conn.SetReadLimit(wsMaxMessageBytes)
+
+	session := &wsSession{analyzer: analyzer.New()}
+
+	for {
+		var command WSCommand
+		if readErr := conn.ReadJSON(&command); readErr != nil {
+			if !websocket.IsCloseError(readErr, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				slog.Info("handleWebSocket: Connection closed", slog.Any("error", readErr))
+			}
+			return
+		}
+
+		response := session.handleCommand(command)
+		if writeErr := writeWSResponse(conn, response); writeErr != nil {
+			slog.Error("handleWebSocket: Error writing response", slog.Any("error", writeErr))
+			return
+		}
+	}
+}
+
+// writeWSResponse encodes a response as a single text message.
+func writeWSResponse(conn *websocket.Conn, response WSResponse) error {
+	writer, err := conn.NextWriter(websocket.TextMessage)
+	if err != nil {
+		return err
+	}
+	if encodeErr := newJSONEncoder(writer).Encode(response); encodeErr != nil {
+		writer.Close()
+		return encodeErr
+	}
+	return writer.Close()
+}
+
+// handleCommand runs a single command against the session and builds its response.
+func (s *wsSession) handleCommand(command WSCommand) WSResponse {
+	response := WSResponse{ID: command.ID, Type: command.Type}
+
+	var err error
+	switch command.Type {
+	case wsCommandAnalyze:
+		response.DOT, err = s.analyze(command)
+	case wsCommandFocus:
+		response.DOT, err = s.focus(command.Package)
+	case wsCommandDependents:
+		response.Packages, err = s.dependents(command.Package)
+	default:
+		err = fmt.Errorf("unknown command type: %q", command.Type)
+	}
+
+	if err != nil {
+		response.Error = err.Error()
+		return response
+	}
+	response.Success = true
+	return response
+}
+
+// analyze analyzes an entry file and caches the resulting graph for later commands.
+func (s *wsSession) analyze(command WSCommand) (string, error) {
+	if command.Entry == "" {
+		return "", errors.New("entry is required")
+	}
+
+	absEntryFile, err := filepath.Abs(command.Entry)
+	if err != nil {
+		return "", fmt.Errorf("resolving entry file path: %w", err)
+	}
+	if _, statErr := os.Stat(absEntryFile); os.IsNotExist(statErr) {
+		return "", fmt.Errorf("entry file does not exist: %s", absEntryFile)
+	}
+
+	graph, err := s.analyzer.AnalyzeFromFile(absEntryFile, !command.External, requestExcludeList(command.Exclude))
+	if err != nil {
+		return "", fmt.Errorf("analyzing codebase: %w", err)
+	}
+	s.graph = graph
+
+	return visualizer.New().GenerateDOTContent(graph), nil
+}
+
+// focus renders the subgraph rooted at a package of the cached graph.
+func (s *wsSession) focus(pkgPath string) (string, error) {
+	if s.graph == nil {
+		return "", errors.New("no graph analyzed yet, send an analyze command first")
+	}
+
+	subgraph, err := s.analyzer.Focus(s.graph, pkgPath)
+	if err != nil {
+		return "", err
+	}
+
+	return visualizer.New().GenerateDOTContent(subgraph), nil
+}
+
+// dependents lists the packages of the cached graph that transitively import a package.
+func (s *wsSession) dependents(pkgPath string) ([]string, error) {
+	if s.graph == nil {
+		return nil, errors.New("no graph analyzed yet, send an analyze command first")
+	}
+
+	return s.analyzer.Dependents(s.graph, pkgPath)
+}
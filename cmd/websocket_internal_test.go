@@ -0,0 +1,107 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createLayeredProject creates a module where main imports api, and api imports store.
+func createLayeredProject(t *testing.T) string {
+	t.Helper()
+
+	root := t.TempDir()
+	files := map[string]string{
+		"go.mod":         "module example.com/app\n\ngo 1.21\n",
+		"main.go":        "package main\n\nimport _ \"example.com/app/api\"\n\nfunc main() {}\n",
+		"api/api.go":     "package api\n\nimport _ \"example.com/app/store\"\n",
+		"store/store.go": "package store\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	return filepath.Join(root, "main.go")
+}
+
+// dialWebSocket starts a test server for the WebSocket handler and connects to it.
+func dialWebSocket(t *testing.T) *websocket.Conn {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(handleWebSocket))
+	t.Cleanup(server.Close)
+
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+	conn, resp, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+// sendCommand writes a command and reads the matching response.
+func sendCommand(t *testing.T, conn *websocket.Conn, command WSCommand) WSResponse {
+	t.Helper()
+
+	require.NoError(t, conn.WriteJSON(command))
+	var response WSResponse
+	require.NoError(t, conn.ReadJSON(&response))
+	assert.Equal(t, command.ID, response.ID)
+	assert.Equal(t, command.Type, response.Type)
+
+	return response
+}
+
+func TestHandleWebSocket_Commands(t *testing.T) {
+	entry := createLayeredProject(t)
+	conn := dialWebSocket(t)
+
+	analyzed := sendCommand(t, conn, WSCommand{ID: "1", Type: "analyze", Entry: entry})
+	require.True(t, analyzed.Success, analyzed.Error)
+	assert.Contains(t, analyzed.DOT, "example_com_app_store")
+
+	focused := sendCommand(t, conn, WSCommand{ID: "2", Type: "focus", Package: "example.com/app/api"})
+	require.True(t, focused.Success, focused.Error)
+	assert.Contains(t, focused.DOT, "example_com_app_store")
+	assert.NotContains(t, focused.DOT, "example_com_app [")
+
+	dependents := sendCommand(t, conn, WSCommand{ID: "3", Type: "dependents", Package: "example.com/app/store"})
+	require.True(t, dependents.Success, dependents.Error)
+	assert.Equal(t, []string{"example.com/app", "example.com/app/api"}, dependents.Packages)
+}
+
+func TestHandleWebSocket_Errors(t *testing.T) {
+	conn := dialWebSocket(t)
+
+	testCases := []struct {
+		name          string
+		command       WSCommand
+		expectedError string
+	}{
+		{
+			name:          "focus before analyze",
+			command:       WSCommand{Type: "focus", Package: "x"},
+			expectedError: "no graph analyzed",
+		},
+		{name: "analyze without entry", command: WSCommand{Type: "analyze"}, expectedError: "entry is required"},
+		{name: "unknown command", command: WSCommand{Type: "explode"}, expectedError: "unknown command type"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			response := sendCommand(t, conn, tc.command)
+			assert.False(t, response.Success)
+			assert.Contains(t, response.Error, tc.expectedError)
+		})
+	}
+}
@@ -2,10 +2,13 @@ module github.com/cvsouth/go-package-analyzer
 
 go 1.24
 
-require github.com/stretchr/testify v1.11.1
+require (
+	github.com/gorilla/websocket v1.5.3
+	github.com/stretchr/testify v1.11.1
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -2,16 +2,24 @@
 package analyzer
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"go/ast"
+	"go/build"
 	"go/parser"
 	"go/token"
+	"io"
+	"io/fs"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 )
 
 // Constants for layer calculation.
@@ -19,36 +27,145 @@ const (
 	maxIterationsPadding = 5 // Additional iterations to ensure layer convergence
 )
 
+// defaultMaxFileSize is the size above which Go files are skipped rather than parsed.
+const defaultMaxFileSize = 10 << 20 // 10 MB
+
+// embedDirective embeds files into a package variable.
+const embedDirective = "//go:embed"
+
+// ignoreDirective marks a package to be left out of the graph, along with every edge to it.
+const ignoreDirective = "//gpa:ignore"
+
+// Errors returned by AnalyzeFromFile, for use with errors.Is.
+var (
+	ErrEntryNotFound   = errors.New("entry file not found")
+	ErrPermission      = errors.New("permission denied")
+	ErrPackageConflict = errors.New("conflicting package clauses") // Only with WithStrictPackageClauses
+)
+
 // Analyzer analyzes Go package dependencies.
 type Analyzer struct {
 	fileSet     *token.FileSet
 	moduleRoot  string
 	moduleName  string
 	excludeDirs []string
+
+	cmdEntryNames    bool
+	externalDepth    int
+	minExternalFanIn int
+	maxExternalNodes int
+	minDeps          int
+	maxDeps          int // Negative for no limit
+	todoCounts       bool
+	exportedCounts   bool
+	embedCounts      bool
+	complexity       bool
+	unusedImports    bool
+	countTestFiles   bool
+	reachableOnly    bool
+	hideInternal     bool
+	skipBlankImports bool
+	constrainedMains bool
+	strictPackages   bool
+	skipGenerated    bool
+	localExternals   bool
+	useGoList        bool
+	maxFileSize      int64             // Files larger than this many bytes are skipped; 0 disables the limit
+	churnWindow      time.Duration     // Git history window for PackageInfo.Churn; 0 disables churn
+	buildTarget      *build.Context    // Build constraints files must satisfy, nil to read every file
+	replacements     map[string]string // Module path -> absolute directory, from local replace directives
+	modReplacements  map[string]string // Module path -> path@version of the replacement module
+	showReplacements bool
+	requirements     map[string]string // Module path -> version, from require directives
+	maxConcurrency   int               // Entry points analyzed at once; 0 for GOMAXPROCS
+	onProgress       ProgressFunc
+}
+
+// ProgressFunc reports that done of total entry points have been processed,
+// current being the absolute path of the entry point just processed.
+type ProgressFunc func(done, total int, current string)
+
+// Option configures an Analyzer.
+type Option func(*Analyzer)
+
+// WithCmdEntryNames controls whether entry points under cmd/<name>/ are labeled by <name>.
+// It is enabled by default; when disabled, entry points are labeled by their relative path.
+func WithCmdEntryNames(enabled bool) Option {
+	return func(a *Analyzer) {
+		a.cmdEntryNames = enabled
+	}
 }
 
 // PackageInfo represents information about a Go package.
 type PackageInfo struct {
-	Name         string
-	Path         string
-	Dependencies []string
-	Layer        int // Layer in the dependency graph (0 = bottom layer)
-	FileCount    int // Number of Go files in the package
+	Name            string
+	Path            string
+	Module          string // Path of the owning module ("" for external packages not replaced or resolved locally)
+	Dependencies    []string
+	Layer           int            // Layer in the dependency graph (0 = bottom layer)
+	FileCount       int            // Number of Go files in the package, including tests with WithTestFileCounts
+	TestFileCount   int            // Number of _test.go files in the package
+	Dir             string         // Absolute directory of the package (empty for unanalyzed external packages)
+	ImportCounts    map[string]int // Number of files in the package importing each dependency
+	LineCount       int            // Total lines across the package's non-test Go files
+	TodoCount       int            // TODO/FIXME comment lines (only counted with WithTodoCounts)
+	ExportedCount   int            // Exported top-level identifiers (only counted with WithExportedCounts)
+	EmbedCount      int            // //go:embed directives (only counted with WithEmbedCounts)
+	HasEmbeds       bool           // EmbedCount is non-zero, so the package carries embedded assets
+	Generated       bool           // Every file carries a "Code generated ... DO NOT EDIT." marker
+	Complete        bool           // Every file parsed, so Dependencies is not missing any imports
+	Ignored         bool           // A file carries a //gpa:ignore directive
+	EmptyForTarget  bool           // Every non-test file is excluded by build constraints (only with WithBuildTarget)
+	Churn           int            // Commits touching the package's Go files within the window (only with WithChurn)
+	Complexity      int            // Summed cyclomatic complexity of the package's functions (only with WithComplexity)
+	Files           []SourceFile   // Non-test Go files read for the package, sorted by path
+	ReplacedBy      string         // Replacement of the package's module (only with WithNamedReplacements)
+	IsImportedEntry bool           // The package is the entry package and another package in the graph imports it
+}
+
+// SourceFile is a Go file read for a package.
+type SourceFile struct {
+	Path string // Absolute path
 }
 
 // DependencyGraph represents the package dependency graph.
 type DependencyGraph struct {
-	EntryPackage string
-	Packages     map[string]*PackageInfo
-	Layers       [][]string // Packages organized by layer
-	ModuleName   string     // Name of the Go module
+	EntryPackage     string
+	EntryName        string // Short name of the entry package, the last element of EntryPackage bar a /vN suffix
+	Packages         map[string]*PackageInfo
+	Layers           [][]string // Packages organized by layer
+	ModuleName       string     // Name of the Go module
+	Cycles           []Cycle    // Import cycles, in deterministic order
+	IncludedExternal bool       // External packages were analyzed, excludeExternal being false
+	ExcludedDirs     []string   // Directories and glob patterns excluded from the analysis
+}
+
+// Depth returns the number of layers in the graph.
+func (g *DependencyGraph) Depth() int {
+	return len(g.Layers)
+}
+
+// Summary describes the graph's size on one line, as in "12 pkgs, 3 layers, 0 cycles".
+func (g *DependencyGraph) Summary() string {
+	return fmt.Sprintf("%d pkgs, %d layers, %d cycles", len(g.Packages), g.Depth(), len(g.Cycles))
+}
+
+// EntryLayer returns the layer of the entry package, or -1 if it is not in the graph.
+func (g *DependencyGraph) EntryLayer() int {
+	entry, exists := g.Packages[g.EntryPackage]
+	if !exists {
+		return -1
+	}
+	return entry.Layer
 }
 
 // EntryPoint represents a detected entry point in the codebase.
 type EntryPoint struct {
 	Path         string           `json:"path"`         // Absolute file path
 	RelativePath string           `json:"relativePath"` // Relative path from repository root
+	Name         string           `json:"name"`         // Display label, e.g. "api" for cmd/api/main.go
 	PackagePath  string           `json:"packagePath"`  // Go package path
+	EntryName    string           `json:"entryName"`    // Short name of the package, as in DependencyGraph.EntryName
 	DOTContent   string           `json:"dotContent"`   // Generated DOT visualization
 	Graph        *DependencyGraph `json:"-"`            // Internal graph data (not serialized)
 }
@@ -62,11 +179,290 @@ type MultiEntryAnalysisResult struct {
 	ModuleName  string       `json:"moduleName"`
 }
 
+// WithExternalDepth allows packages outside the entry module to be expanded up to n imports deep.
+// Only modules replaced with a local directory in go.mod can be expanded, plus vendored or cached
+// modules with WithLocalExternalSources. The default of 0 shows
+// every external package as a leaf; negative values are ignored.
+func WithExternalDepth(n int) Option {
+	return func(a *Analyzer) {
+		if n < 0 {
+			slog.Warn("Warning: ignoring negative external depth", "depth", n)
+			return
+		}
+		a.externalDepth = n
+	}
+}
+
+// WithMinExternalFanIn keeps only external packages imported by at least n internal packages.
+// This drops terminal externals used in one place while keeping those that shape the architecture.
+// The default of 0 keeps every external package.
+func WithMinExternalFanIn(n int) Option {
+	return func(a *Analyzer) {
+		a.minExternalFanIn = n
+	}
+}
+
+// WithMaxExternalNodes keeps at most n external packages, the n imported by the most packages,
+// and collapses the rest into a single CollapsedExternalPackage node that inherits their edges.
+// Ties are broken by path. The default of 0 keeps every external package.
+func WithMaxExternalNodes(n int) Option {
+	return func(a *Analyzer) {
+		a.maxExternalNodes = n
+	}
+}
+
+// WithMinDeps drops internal packages that import fewer than n other internal packages, to focus
+// on packages with structure of their own. Edges into dropped packages are removed with them.
+// The entry package is always kept. The default of 0 keeps every package.
+func WithMinDeps(n int) Option {
+	return func(a *Analyzer) {
+		a.minDeps = n
+	}
+}
+
+// WithMaxDeps drops internal packages that import more than n other internal packages, along
+// with the edges into them. The entry package is always kept. By default there is no limit;
+// a negative n removes it again.
+func WithMaxDeps(n int) Option {
+	return func(a *Analyzer) {
+		a.maxDeps = n
+	}
+}
+
+// WithTodoCounts counts TODO and FIXME comment lines per package into PackageInfo.TodoCount.
+// It is off by default because it requires parsing every file in full.
+func WithTodoCounts(enabled bool) Option {
+	return func(a *Analyzer) {
+		a.todoCounts = enabled
+	}
+}
+
+// WithChurn counts, for each analyzed package, the commits within the given window that touch
+// one of its Go files into PackageInfo.Churn, using git log under the module root. Frequently
+// changed packages that many others import are refactoring risks. Outside a git repository
+// churn stays zero. A non-positive window disables churn.
+func WithChurn(window time.Duration) Option {
+	return func(a *Analyzer) {
+		a.churnWindow = max(window, 0)
+	}
+}
+
+// WithExportedCounts counts the exported top-level identifiers of each package into
+// PackageInfo.ExportedCount. Like TODO counting, it requires parsing every file in full.
+func WithExportedCounts(enabled bool) Option {
+	return func(a *Analyzer) {
+		a.exportedCounts = enabled
+	}
+}
+
+// WithComplexity sums the cyclomatic complexity of every function in each package into
+// PackageInfo.Complexity, a health metric beyond file and line counts. Like TODO counting,
+// it requires parsing every file in full.
+func WithComplexity(enabled bool) Option {
+	return func(a *Analyzer) {
+		a.complexity = enabled
+	}
+}
+
+// WithEmbedCounts counts the //go:embed directives of each package into PackageInfo.EmbedCount,
+// so packages that only embed assets stand out from genuinely empty ones. Like TODO counting,
+// it requires parsing every file in full.
+func WithEmbedCounts(enabled bool) Option {
+	return func(a *Analyzer) {
+		a.embedCounts = enabled
+	}
+}
+
+// WithUnusedImportCheck verifies that every import of a file is referenced in its code,
+// dropping the edges of unused imports with a warning, since such files would not compile.
+// Like TODO counting, it requires parsing every file in full.
+func WithUnusedImportCheck(enabled bool) Option {
+	return func(a *Analyzer) {
+		a.unusedImports = enabled
+	}
+}
+
+// WithTestFileCounts includes _test.go files in PackageInfo.FileCount, so node labels
+// reflect all files rather than production files only. Test files are still not parsed,
+// so their imports never add edges. TestFileCount is populated either way.
+func WithTestFileCounts(enabled bool) Option {
+	return func(a *Analyzer) {
+		a.countTestFiles = enabled
+	}
+}
+
+// WithReachableOnly prunes packages that cannot be reached from the entry package by
+// following imports, once the graph is built and the other filters have run.
+// The number of pruned packages is logged as a warning.
+func WithReachableOnly(enabled bool) Option {
+	return func(a *Analyzer) {
+		a.reachableOnly = enabled
+	}
+}
+
+// WithHideInternal leaves out every package below an internal/ path segment, Go's own
+// visibility boundary, to show a library's public structure. Hidden packages are not
+// descended into, like excluded directories. The entry package is always kept.
+func WithHideInternal(hide bool) Option {
+	return func(a *Analyzer) {
+		a.hideInternal = hide
+	}
+}
+
+// WithSkipGenerated drops packages made entirely of generated code (protobuf, mocks, ...)
+// along with the edges into them. The entry package is always kept.
+func WithSkipGenerated(skip bool) Option {
+	return func(a *Analyzer) {
+		a.skipGenerated = skip
+	}
+}
+
+// WithProgress registers a callback invoked by AnalyzeMultipleEntryPoints after each entry point
+// is processed, whether or not its analysis succeeded. Entry points are analyzed concurrently,
+// so they are reported in the order they finish, but calls never overlap and done counts up.
+func WithProgress(onProgress ProgressFunc) Option {
+	return func(a *Analyzer) {
+		a.onProgress = onProgress
+	}
+}
+
+// WithSkipBlankImports ignores side-effect-only imports (import _ "pkg"), so packages
+// registered purely for their init functions add no edges.
+func WithSkipBlankImports(skip bool) Option {
+	return func(a *Analyzer) {
+		a.skipBlankImports = skip
+	}
+}
+
+// WithProductionOnly restricts the graph to production architecture: generated packages
+// and side-effect-only imports are dropped, and test files are kept out of FileCount.
+// Test file imports never add edges, with or without this option. Disabling it leaves the
+// individual settings as other options made them.
+func WithProductionOnly(enabled bool) Option {
+	return func(a *Analyzer) {
+		if enabled {
+			a.skipGenerated = true
+			a.skipBlankImports = true
+			a.countTestFiles = false
+		}
+	}
+}
+
+// WithLocalExternalSources lets WithExternalDepth expand third-party packages whose source is
+// on disk, either under the module's vendor directory or in the module cache at the version
+// required by go.mod. Packages that cannot be found locally remain leaves.
+func WithLocalExternalSources(enabled bool) Option {
+	return func(a *Analyzer) {
+		a.localExternals = enabled
+	}
+}
+
+// WithNamedReplacements makes locally overridden dependencies stand out: packages of a module
+// replaced by a directory outside the module root, or by another module such as a fork, become
+// external leaf nodes whose PackageInfo.ReplacedBy names the replacement directory or
+// path@version. Replacements within the module root are still expanded like other packages.
+func WithNamedReplacements(enabled bool) Option {
+	return func(a *Analyzer) {
+		a.showReplacements = enabled
+	}
+}
+
+// WithGoList makes AnalyzeFromFile resolve imports with `go list` rather than the AST scanner,
+// as AnalyzeWithGoList does, keeping the packages reachable from the entry package.
+// It falls back to the AST scanner when no go command is available or no go.mod was found.
+func WithGoList(enabled bool) Option {
+	return func(a *Analyzer) {
+		a.useGoList = enabled
+	}
+}
+
+// WithMaxFileSize skips, with a warning, Go files larger than maxBytes instead of reading and
+// parsing them, guarding against pathological inputs. Skipped files count as unparsed, so their
+// package is marked incomplete. The default is 10 MB; zero or a negative value removes the limit.
+func WithMaxFileSize(maxBytes int64) Option {
+	return func(a *Analyzer) {
+		a.maxFileSize = max(maxBytes, 0)
+	}
+}
+
+// WithBuildTarget skips the non-test files whose build constraints or GOOS/GOARCH file name
+// suffixes exclude them when building for goos and goarch; an empty value keeps the host's.
+// Packages left without files are marked EmptyForTarget. It also selects the target of
+// `go list` with WithGoList and AnalyzeWithGoList, and of the constraint check of FindEntryPoints.
+// By default every file is read, whatever its constraints.
+func WithBuildTarget(goos, goarch string) Option {
+	return func(a *Analyzer) {
+		target := a.buildContextBase()
+		if goos != "" {
+			target.GOOS = goos
+		}
+		if goarch != "" {
+			target.GOARCH = goarch
+		}
+		a.buildTarget = &target
+	}
+}
+
+// WithBuildTags evaluates build constraints with the given custom tags enabled, as with
+// `go build -tags`, so files guarded by //go:build integration are read when "integration" is
+// among them and files guarded by !integration are skipped. Like WithBuildTarget, which it
+// combines with, it makes the analysis skip files excluded by their constraints and mark the
+// packages left without files EmptyForTarget, and it passes the tags to `go list`.
+func WithBuildTags(tags []string) Option {
+	return func(a *Analyzer) {
+		target := a.buildContextBase()
+		target.BuildTags = slices.Clone(tags)
+		a.buildTarget = &target
+	}
+}
+
+// buildContextBase returns a copy of the build target to refine, or of the default context.
+func (a *Analyzer) buildContextBase() build.Context {
+	if a.buildTarget != nil {
+		return *a.buildTarget
+	}
+	return build.Default
+}
+
+// WithConstrainedEntryPoints makes FindEntryPoints report main files whose build constraints
+// exclude them from the default build, such as scripts behind //go:build ignore.
+func WithConstrainedEntryPoints(include bool) Option {
+	return func(a *Analyzer) {
+		a.constrainedMains = include
+	}
+}
+
+// WithStrictPackageClauses makes AnalyzeFromFile fail with ErrPackageConflict when the non-test
+// files of the entry file's directory declare different packages, instead of warning about it.
+// Files excluded from the build by their constraints, such as //go:build ignore scripts, are not
+// compared.
+func WithStrictPackageClauses(strict bool) Option {
+	return func(a *Analyzer) {
+		a.strictPackages = strict
+	}
+}
+
+// WithMaxConcurrency bounds the number of entry points AnalyzeMultipleEntryPoints analyzes at
+// once, which bounds the files held open on large repositories. A non-positive n restores the
+// default, GOMAXPROCS.
+func WithMaxConcurrency(n int) Option {
+	return func(a *Analyzer) {
+		a.maxConcurrency = max(n, 0)
+	}
+}
+
 // New creates a new analyzer.
-func New() *Analyzer {
-	return &Analyzer{
-		fileSet: token.NewFileSet(),
+func New(opts ...Option) *Analyzer {
+	a := &Analyzer{
+		fileSet:       token.NewFileSet(),
+		cmdEntryNames: true,
+		maxDeps:       -1,
+		maxFileSize:   defaultMaxFileSize,
+	}
+	for _, opt := range opts {
+		opt(a)
 	}
+	return a
 }
 
 // AnalyzeFromFile analyzes package dependencies starting from a Go file.
@@ -77,9 +473,19 @@ func (a *Analyzer) AnalyzeFromFile(
 ) (*DependencyGraph, error) {
 	a.excludeDirs = excludeDirs
 
+	if err := checkEntryFile(entryFile); err != nil {
+		return nil, err
+	}
+
+	// Work on the canonical path so the entry package is relative to the real module root
+	if resolved, resolveErr := filepath.EvalSymlinks(entryFile); resolveErr == nil {
+		entryFile = resolved
+	}
+
 	// Always find the correct module for this specific entry file
 	// This ensures each entry point in a monorepo uses its correct module context
-	if err := a.findModule(entryFile); err != nil {
+	moduleErr := a.findModule(entryFile)
+	if err := moduleErr; err != nil {
 		// If no go.mod found, use the directory containing the entry file as module root
 		entryDir := filepath.Dir(entryFile)
 		absEntryDir, absErr := filepath.Abs(entryDir)
@@ -87,7 +493,12 @@ func (a *Analyzer) AnalyzeFromFile(
 			return nil, fmt.Errorf("resolving entry directory: %w", absErr)
 		}
 		a.moduleRoot = absEntryDir
-		a.moduleName = filepath.Base(absEntryDir)
+		a.moduleName = a.inferModuleName(entryFile, absEntryDir)
+		slog.Warn("Warning: go.mod not found, internal packages may not be detected",
+			"entryFile", entryFile,
+			"moduleRoot", a.moduleRoot,
+			"moduleName", a.moduleName,
+			"error", err)
 	}
 
 	// Parse the entry file to get its package
@@ -98,25 +509,101 @@ func (a *Analyzer) AnalyzeFromFile(
 
 	// Build dependency graph
 	graph := &DependencyGraph{
-		EntryPackage: entryPkg,
-		Packages:     make(map[string]*PackageInfo),
-		ModuleName:   a.moduleName,
+		EntryPackage:     entryPkg,
+		Packages:         make(map[string]*PackageInfo),
+		ModuleName:       a.moduleName,
+		IncludedExternal: !excludeExternal,
+		ExcludedDirs:     slices.Clone(excludeDirs),
+	}
+
+	if a.useGoList && moduleErr == nil {
+		listed, listErr := a.goList(a.moduleRoot)
+		switch {
+		case listErr == nil:
+			a.addGoListPackages(graph, listed, excludeExternal)
+			removePackages(graph, unreachablePackages(graph))
+			return a.finishGraph(graph), nil
+		case errors.Is(listErr, errGoUnavailable):
+			slog.Warn("Warning: go command not found, falling back to the AST scanner", "entryFile", entryFile)
+		default:
+			return nil, fmt.Errorf("analyzing packages: %w", listErr)
+		}
 	}
 
 	// Recursively analyze all packages
-	visited := make(map[string]bool)
-	if analyzeErr := a.analyzePackage(entryPkg, graph, visited, excludeExternal); analyzeErr != nil {
+	visited := make(map[string]int)
+	if analyzeErr := a.analyzePackage(entryPkg, graph, visited, excludeExternal, 0); analyzeErr != nil {
+		if errors.Is(analyzeErr, fs.ErrPermission) {
+			return nil, fmt.Errorf("analyzing packages: %w: %w", ErrPermission, analyzeErr)
+		}
 		return nil, fmt.Errorf("analyzing packages: %w", analyzeErr)
 	}
 
+	return a.finishGraph(graph), nil
+}
+
+// finishGraph applies the filters to a freshly built graph, then calculates its layers and cycles.
+func (a *Analyzer) finishGraph(graph *DependencyGraph) *DependencyGraph {
+	graph.EntryName = a.getPackageName(graph.EntryPackage)
+
+	// Drop packages excluded by filters before they influence the layout
+	a.applyFilters(graph)
+	if a.churnWindow > 0 {
+		a.addChurn(graph)
+	}
+
+	markImportedEntry(graph)
+
 	// Calculate layers
 	a.calculateLayers(graph)
+	graph.Cycles = a.FindCycles(graph)
+
+	return graph
+}
+
+// markImportedEntry flags the entry package when another package imports it, which happens
+// when a package with a main function is also used as a library, for example through build
+// tags. Such an entry cannot simply be placed above everything else.
+func markImportedEntry(graph *DependencyGraph) {
+	entry, exists := graph.Packages[graph.EntryPackage]
+	if !exists {
+		return
+	}
+	for pkgPath, pkg := range graph.Packages {
+		if pkgPath != graph.EntryPackage && slices.Contains(pkg.Dependencies, graph.EntryPackage) {
+			entry.IsImportedEntry = true
+			return
+		}
+	}
+}
 
-	return graph, nil
+// checkEntryFile verifies the entry file can be opened, classifying missing and unreadable files.
+func checkEntryFile(entryFile string) error {
+	file, err := os.Open(entryFile)
+	switch {
+	case err == nil:
+		return file.Close()
+	case errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("%w: %w", ErrEntryNotFound, err)
+	case errors.Is(err, fs.ErrPermission):
+		return fmt.Errorf("%w: %w", ErrPermission, err)
+	default:
+		return fmt.Errorf("opening entry file: %w", err)
+	}
 }
 
 // findModule finds the module root by looking for go.mod file.
 func (a *Analyzer) findModule(startPath string) error {
+	a.replacements = nil
+	a.modReplacements = nil
+	a.requirements = nil
+
+	// Resolve symlinks so the upward walk follows the real directory structure
+	startPath, err := filepath.EvalSymlinks(startPath)
+	if err != nil {
+		return fmt.Errorf("resolving start path: %w", err)
+	}
+
 	// Check if startPath is a file or directory
 	stat, err := os.Stat(startPath)
 	if err != nil {
@@ -141,6 +628,10 @@ func (a *Analyzer) findModule(startPath string) error {
 				return fmt.Errorf("reading go.mod: %w", readErr)
 			}
 
+			a.replacements = parseReplaceDirectives(string(content), dir)
+			a.modReplacements = parseModuleReplaceDirectives(string(content))
+			a.requirements = parseRequireDirectives(string(content))
+
 			lines := strings.Split(string(content), "\n")
 			for _, line := range lines {
 				line = strings.TrimSpace(line)
@@ -160,8 +651,51 @@ func (a *Analyzer) findModule(startPath string) error {
 	}
 }
 
-// getPackageFromFile determines the package path from a Go file.
+// inferModuleName guesses the module name when no go.mod is available.
+// It looks for an import in the entry file whose trailing path segments match a
+// directory under the module root (e.g. "example.com/app/util" with a "util"
+// directory yields "example.com/app"), falling back to the directory name.
+// Only domain-style imports are considered, so a standard library import such as
+// "net/http" never names the module after a local "http" directory.
+func (a *Analyzer) inferModuleName(entryFile, moduleRoot string) string {
+	fallback := filepath.Base(moduleRoot)
+
+	parsed, err := a.parseFileImports(entryFile)
+	if err != nil {
+		return fallback
+	}
+
+	imports := slices.Clone(parsed.imports)
+	sort.Strings(imports)
+
+	for _, imp := range imports {
+		segments := strings.Split(imp, "/")
+		if !strings.Contains(segments[0], ".") {
+			continue
+		}
+		// Try the longest suffix first so nested packages resolve to the shortest module prefix
+		for i := 1; i < len(segments); i++ {
+			candidateDir := filepath.Join(moduleRoot, filepath.FromSlash(strings.Join(segments[i:], "/")))
+			if info, statErr := os.Stat(candidateDir); statErr == nil && info.IsDir() {
+				return strings.Join(segments[:i], "/")
+			}
+		}
+	}
+
+	return fallback
+}
+
+// getPackageFromFile determines the package path from a Go file. When the non-test files of its
+// directory declare different packages the results are unreliable, so this is reported as a
+// warning, or as an error with WithStrictPackageClauses.
 func (a *Analyzer) getPackageFromFile(filePath string) (string, error) {
+	if err := a.checkPackageClauses(filepath.Dir(filePath)); err != nil {
+		if a.strictPackages {
+			return "", err
+		}
+		slog.Warn("Warning: directory declares conflicting packages, results may be unreliable", "error", err)
+	}
+
 	// Get relative path from module root
 	relPath, err := filepath.Rel(a.moduleRoot, filepath.Dir(filePath))
 	if err != nil {
@@ -179,19 +713,27 @@ func (a *Analyzer) getPackageFromFile(filePath string) (string, error) {
 }
 
 // analyzePackage recursively analyzes a package and its dependencies.
+// The depth counts how many imports away from the entry module the package is;
+// packages outside the entry module are only expanded while depth <= externalDepth.
 func (a *Analyzer) analyzePackage(
 	pkgPath string,
 	graph *DependencyGraph,
-	visited map[string]bool,
+	visited map[string]int,
 	excludeExternal bool,
+	depth int,
 ) error {
-	if visited[pkgPath] {
+	// Revisit only when reached more shallowly, so a package first seen as a leaf can be expanded
+	previousDepth, seen := visited[pkgPath]
+	if seen && previousDepth <= depth {
 		return nil
 	}
-	visited[pkgPath] = true
+	visited[pkgPath] = depth
+	if !seen {
+		a.warnIfLooksInternal(pkgPath)
+	}
 
 	// Skip excluded directories
-	if a.isExcludedPackage(pkgPath) {
+	if a.isExcludedPackage(pkgPath) || a.isHiddenPackage(pkgPath, graph) {
 		return nil
 	}
 
@@ -202,15 +744,33 @@ func (a *Analyzer) analyzePackage(
 
 	// Handle external packages when excludeExternal is false
 	if !a.isInternalPackage(pkgPath) {
-		// Add external package to graph as a leaf node (no dependencies to analyze)
-		pkgInfo := &PackageInfo{
-			Name:         a.getPackageName(pkgPath),
-			Path:         pkgPath,
-			Dependencies: []string{}, // External packages have no analyzable dependencies
-			FileCount:    0,          // We can't count files for external packages
-		}
-		graph.Packages[pkgPath] = pkgInfo
-		return nil
+		if modulePath, replacement := a.namedReplacement(pkgPath); replacement != "" {
+			graph.Packages[pkgPath] = &PackageInfo{
+				Name:         a.getPackageName(pkgPath),
+				Path:         pkgPath,
+				Module:       modulePath,
+				Dependencies: []string{},
+				ReplacedBy:   replacement,
+			}
+			return nil
+		}
+		pkgDir, resolved := a.getReplacedPackageDir(pkgPath)
+		if !resolved && a.localExternals {
+			pkgDir, resolved = a.getLocalExternalDir(pkgPath)
+		}
+		if !resolved || depth > a.externalDepth {
+			// Add external package to graph as a leaf node (no dependencies to analyze)
+			pkgInfo := &PackageInfo{
+				Name:         a.getPackageName(pkgPath),
+				Path:         pkgPath,
+				Module:       a.packageModule(pkgPath),
+				Dependencies: []string{}, // External packages have no analyzable dependencies
+				FileCount:    0,          // We can't count files for external packages
+			}
+			graph.Packages[pkgPath] = pkgInfo
+			return nil
+		}
+		return a.analyzePackageDir(pkgPath, pkgDir, graph, visited, excludeExternal, depth)
 	}
 
 	// Get package directory for internal packages
@@ -219,11 +779,28 @@ func (a *Analyzer) analyzePackage(
 		return fmt.Errorf("getting package directory for %s: %w", pkgPath, err)
 	}
 
+	return a.analyzePackageDir(pkgPath, pkgDir, graph, visited, excludeExternal, depth)
+}
+
+// analyzePackageDir parses the package in pkgDir, adds it to the graph and analyzes its dependencies.
+func (a *Analyzer) analyzePackageDir(
+	pkgPath, pkgDir string,
+	graph *DependencyGraph,
+	visited map[string]int,
+	excludeExternal bool,
+	depth int,
+) error {
+	absPkgDir, err := filepath.Abs(pkgDir)
+	if err != nil {
+		return fmt.Errorf("resolving package directory for %s: %w", pkgPath, err)
+	}
+
 	// Parse all Go files in the package
-	dependencies, fileCount, err := a.parsePackageImports(pkgDir)
+	parsed, err := a.parsePackageImports(pkgDir)
 	if err != nil {
 		return fmt.Errorf("parsing imports for %s: %w", pkgPath, err)
 	}
+	dependencies := parsed.imports
 
 	// Filter dependencies if needed
 	if excludeExternal {
@@ -239,17 +816,45 @@ func (a *Analyzer) analyzePackage(
 
 	// Create package info
 	pkgInfo := &PackageInfo{
-		Name:         a.getPackageName(pkgPath),
-		Path:         pkgPath,
-		Dependencies: dependencies,
-		FileCount:    fileCount,
-		Layer:        0,
+		Name:           a.getPackageName(pkgPath),
+		Path:           pkgPath,
+		Module:         a.packageModule(pkgPath),
+		Dependencies:   dependencies,
+		FileCount:      parsed.fileCount,
+		TestFileCount:  parsed.testFileCount,
+		LineCount:      parsed.lineCount,
+		TodoCount:      parsed.todoCount,
+		ExportedCount:  parsed.exportedCount,
+		EmbedCount:     parsed.embedCount,
+		HasEmbeds:      parsed.embedCount > 0,
+		Complexity:     parsed.complexity,
+		Files:          parsed.files,
+		Layer:          0,
+		Dir:            absPkgDir,
+		ImportCounts:   parsed.importCounts,
+		Generated:      parsed.allGenerated(),
+		Complete:       parsed.parseFailures == 0,
+		Ignored:        parsed.ignored,
+		EmptyForTarget: parsed.emptyForTarget(),
+	}
+	if a.countTestFiles {
+		pkgInfo.FileCount += parsed.testFileCount
 	}
 	graph.Packages[pkgPath] = pkgInfo
 
+	// Generated and ignored packages are dropped by the filters, so there is no need to descend into them
+	skipped := pkgInfo.Ignored || (a.skipGenerated && pkgInfo.Generated)
+	if skipped && pkgPath != graph.EntryPackage {
+		return nil
+	}
+
 	// Recursively analyze dependencies
 	for _, dep := range dependencies {
-		if depErr := a.analyzePackage(dep, graph, visited, excludeExternal); depErr != nil {
+		depDepth := 0
+		if !a.isInternalPackage(dep) {
+			depDepth = depth + 1
+		}
+		if depErr := a.analyzePackage(dep, graph, visited, excludeExternal, depDepth); depErr != nil {
 			// Log error but continue with other dependencies
 			slog.Warn("Warning: failed to analyze dependency",
 				"dependency", dep,
@@ -260,11 +865,73 @@ func (a *Analyzer) analyzePackage(
 	return nil
 }
 
+// checkPackageClauses returns an ErrPackageConflict error naming each package declared by the
+// non-test Go files of dir, with a file declaring it, when they differ. Files excluded from the
+// build, or that cannot be read, are skipped.
+func (a *Analyzer) checkPackageClauses(dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	declaringFile := make(map[string]string)
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		if matched, matchErr := a.buildContext().MatchFile(dir, name); matchErr != nil || !matched {
+			continue
+		}
+
+		src, readErr := a.readSourceFile(filepath.Join(dir, name))
+		if readErr != nil {
+			continue
+		}
+		file, parseErr := parser.ParseFile(a.fileSet, name, src, parser.PackageClauseOnly)
+		if parseErr != nil {
+			continue
+		}
+		if _, seen := declaringFile[file.Name.Name]; !seen {
+			declaringFile[file.Name.Name] = name
+			names = append(names, file.Name.Name)
+		}
+	}
+
+	if len(names) < 2 {
+		return nil
+	}
+	conflicts := make([]string, 0, len(names))
+	for _, pkgName := range names {
+		conflicts = append(conflicts, fmt.Sprintf("%s (%s)", pkgName, declaringFile[pkgName]))
+	}
+	return fmt.Errorf("%w in %s: %s", ErrPackageConflict, dir, strings.Join(conflicts, ", "))
+}
+
+// buildContext returns the build target set by WithBuildTarget and WithBuildTags, or the default
+// build context.
+func (a *Analyzer) buildContext() *build.Context {
+	if a.buildTarget != nil {
+		return a.buildTarget
+	}
+	return &build.Default
+}
+
 // isInternalPackage checks if a package is internal to the module.
 func (a *Analyzer) isInternalPackage(pkgPath string) bool {
 	return strings.HasPrefix(pkgPath, a.moduleName)
 }
 
+// isHiddenPackage reports whether WithHideInternal hides a package: one other than the entry
+// with an "internal" path segment.
+func (a *Analyzer) isHiddenPackage(pkgPath string, graph *DependencyGraph) bool {
+	if !a.hideInternal || pkgPath == graph.EntryPackage {
+		return false
+	}
+	return slices.Contains(strings.Split(pkgPath, "/"), "internal")
+}
+
 // isExcludedPackage checks if a package should be excluded based on the exclude list.
 func (a *Analyzer) isExcludedPackage(pkgPath string) bool {
 	if !a.isInternalPackage(pkgPath) {
@@ -272,12 +939,11 @@ func (a *Analyzer) isExcludedPackage(pkgPath string) bool {
 	}
 
 	// Get the relative path from the module root
-	relPath := strings.TrimPrefix(pkgPath, a.moduleName)
-	relPath = strings.TrimPrefix(relPath, "/")
+	relPath := RelativePath(pkgPath, a.moduleName)
 
 	// Check if the relative path matches any excluded pattern
 	for _, excludePattern := range a.excludeDirs {
-		if a.matchesWildcardPattern(relPath, excludePattern) {
+		if MatchPattern(relPath, excludePattern) {
 			return true
 		}
 	}
@@ -285,10 +951,10 @@ func (a *Analyzer) isExcludedPackage(pkgPath string) bool {
 	return false
 }
 
-// matchesWildcardPattern checks if a path matches a wildcard pattern.
+// MatchPattern checks if a path matches a wildcard pattern, as exclude patterns are matched.
 // The pattern can contain * wildcards which match any sequence of characters.
 // If no wildcards are present, it performs exact matching.
-func (a *Analyzer) matchesWildcardPattern(path, pattern string) bool {
+func MatchPattern(path, pattern string) bool {
 	// Empty pattern matches nothing
 	if pattern == "" {
 		return false
@@ -300,11 +966,11 @@ func (a *Analyzer) matchesWildcardPattern(path, pattern string) bool {
 	}
 
 	// Handle wildcard patterns
-	return a.wildcardMatch(path, pattern)
+	return wildcardMatch(path, pattern)
 }
 
 // wildcardMatch implements wildcard pattern matching where * matches any sequence of characters.
-func (a *Analyzer) wildcardMatch(text, pattern string) bool {
+func wildcardMatch(text, pattern string) bool {
 	// Convert pattern to regexp-like matching logic
 	// Split pattern by * to get literal parts
 	parts := strings.Split(pattern, "*")
@@ -354,18 +1020,15 @@ func (a *Analyzer) wildcardMatch(text, pattern string) bool {
 	return true
 }
 
-// getPackageDir converts a package path to a directory path.
+// getPackageDir converts a package path to a directory path. A major version suffix belongs
+// to the module name, so the packages of example.com/foo/v2 resolve below the module root
+// rather than in a v2 directory.
 func (a *Analyzer) getPackageDir(pkgPath string) (string, error) {
 	if !a.isInternalPackage(pkgPath) {
 		return "", fmt.Errorf("external package: %s", pkgPath)
 	}
 
-	// Remove module name prefix and normalize path separators
-	relPath := strings.TrimPrefix(pkgPath, a.moduleName)
-	relPath = strings.TrimPrefix(relPath, "/")
-	relPath = strings.TrimPrefix(relPath, "\\")
-	relPath = filepath.ToSlash(relPath)
-
+	relPath := RelativePath(pkgPath, a.moduleName)
 	if relPath == "" {
 		return a.moduleRoot, nil
 	}
@@ -374,67 +1037,312 @@ func (a *Analyzer) getPackageDir(pkgPath string) (string, error) {
 	return filepath.FromSlash(filepath.Join(a.moduleRoot, relPath)), nil
 }
 
+// packageImports holds the imports collected from the Go files of a package.
+type packageImports struct {
+	imports        []string       // Unique imports, sorted
+	fileCount      int            // Number of non-test Go files
+	testFileCount  int            // Number of _test.go files
+	lineCount      int            // Total lines across those files
+	todoCount      int            // TODO/FIXME comment lines, when enabled
+	exportedCount  int            // Exported top-level identifiers, when enabled
+	embedCount     int            // //go:embed directives, when enabled
+	complexity     int            // Summed cyclomatic complexity of functions, when enabled
+	generatedCount int            // Files carrying a generated-code marker
+	parseFailures  int            // Files whose imports could not be parsed
+	constrained    int            // Non-test files excluded by the build target
+	importCounts   map[string]int // Number of files importing each path
+	files          []SourceFile   // Non-test files read, including those that failed to parse
+	ignored        bool           // A file carries the ignore directive
+}
+
+// emptyForTarget reports whether the build target excludes every non-test Go file of the package.
+func (p *packageImports) emptyForTarget() bool {
+	return p.fileCount == 0 && p.constrained > 0
+}
+
+// allGenerated reports whether every Go file of the package is generated code.
+func (p *packageImports) allGenerated() bool {
+	return p.fileCount > 0 && p.generatedCount == p.fileCount
+}
+
 // parsePackageImports parses all Go files in a directory to extract imports and count files.
-func (a *Analyzer) parsePackageImports(dir string) ([]string, int, error) {
+func (a *Analyzer) parsePackageImports(dir string) (*packageImports, error) {
 	files, err := os.ReadDir(dir)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
 
-	importSet := make(map[string]bool)
-	fileCount := 0
+	result := &packageImports{importCounts: make(map[string]int)}
 
 	for _, file := range files {
-		if !strings.HasSuffix(file.Name(), ".go") || strings.HasSuffix(file.Name(), "_test.go") {
+		if !strings.HasSuffix(file.Name(), ".go") {
 			continue
 		}
-
-		fileCount++
-		filePath := filepath.Join(dir, file.Name())
-		imports, parseErr := a.parseFileImports(filePath)
-		if parseErr != nil {
-			continue // Skip files that can't be parsed
+		if strings.HasSuffix(file.Name(), "_test.go") {
+			result.testFileCount++
+			continue
 		}
-
-		for _, imp := range imports {
-			importSet[imp] = true
+		if !a.matchesBuildTarget(dir, file.Name()) {
+			result.constrained++
+			continue
 		}
+
+		a.addPackageFile(result, filepath.Join(dir, file.Name()))
 	}
 
 	// Convert set to slice and sort for deterministic order
-	imports := make([]string, 0, len(importSet))
-	for imp := range importSet {
-		imports = append(imports, imp)
+	result.imports = make([]string, 0, len(result.importCounts))
+	for imp := range result.importCounts {
+		result.imports = append(result.imports, imp)
 	}
-	sort.Strings(imports)
+	sort.Strings(result.imports)
 
-	return imports, fileCount, nil
+	return result, nil
 }
 
-// parseFileImports parses imports from a single Go file.
-func (a *Analyzer) parseFileImports(filePath string) ([]string, error) {
-	src, err := os.ReadFile(filePath)
+// addPackageFile parses a non-test Go file and adds its counts and imports to result.
+// Files that fail to parse only contribute their line count.
+func (a *Analyzer) addPackageFile(result *packageImports, filePath string) {
+	result.fileCount++
+	parsed, parseErr := a.parseFileImports(filePath)
+	if parsed != nil {
+		result.lineCount += parsed.lineCount
+	}
+	result.files = append(result.files, SourceFile{Path: filePath})
+	if parseErr != nil {
+		result.parseFailures++
+		return
+	}
+	result.todoCount += parsed.todoCount
+	result.exportedCount += parsed.exportedCount
+	result.embedCount += parsed.embedCount
+	result.complexity += parsed.complexity
+	if parsed.generated {
+		result.generatedCount++
+	}
+	if parsed.ignored {
+		result.ignored = true
+	}
+
+	fileImports := make(map[string]bool)
+	for _, imp := range parsed.imports {
+		fileImports[imp] = true
+	}
+	for imp := range fileImports {
+		result.importCounts[imp]++
+	}
+}
+
+// matchesBuildTarget reports whether a file in dir is part of the build for the build target.
+// Without a target, or when the constraints cannot be read, every file matches.
+func (a *Analyzer) matchesBuildTarget(dir, name string) bool {
+	if a.buildTarget == nil {
+		return true
+	}
+	matched, err := a.buildTarget.MatchFile(dir, name)
+	return err != nil || matched
+}
+
+// errFileTooLarge reports a Go file skipped because it exceeds the maximum file size.
+var errFileTooLarge = errors.New("file exceeds maximum size")
+
+// readSourceFile reads a Go file, refusing files larger than the maximum file size.
+// At most one byte past the limit is read, so oversized files never sit in memory.
+func (a *Analyzer) readSourceFile(filePath string) ([]byte, error) {
+	if a.maxFileSize == 0 {
+		return os.ReadFile(filePath)
+	}
+
+	file, err := os.Open(filePath)
 	if err != nil {
 		return nil, err
 	}
+	defer file.Close()
 
-	file, err := parser.ParseFile(a.fileSet, filePath, src, parser.ImportsOnly)
+	src, err := io.ReadAll(io.LimitReader(file, a.maxFileSize+1))
 	if err != nil {
 		return nil, err
 	}
+	if int64(len(src)) > a.maxFileSize {
+		slog.Warn("Warning: skipping file larger than the maximum size",
+			"file", filePath,
+			"maxBytes", a.maxFileSize)
+		return nil, fmt.Errorf("%w of %d bytes: %s", errFileTooLarge, a.maxFileSize, filePath)
+	}
+	return src, nil
+}
+
+// parsedFile holds the information extracted from a single Go file.
+type parsedFile struct {
+	imports       []string
+	lineCount     int
+	todoCount     int  // Only counted when TODO counting is enabled
+	exportedCount int  // Only counted when exported counting is enabled
+	embedCount    int  // Only counted when embed counting is enabled
+	complexity    int  // Only computed when complexity is enabled
+	generated     bool // Whether the file carries a "Code generated ... DO NOT EDIT." marker
+	ignored       bool // Whether the file carries the ignore directive
+}
+
+// parseFileImports parses imports from a single Go file and counts its lines.
+// The line count is returned even when the file fails to parse.
+func (a *Analyzer) parseFileImports(filePath string) (*parsedFile, error) {
+	src, err := a.readSourceFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	result := &parsedFile{lineCount: countLines(src)}
+
+	// Leading comments are enough to detect generated files, but counting TODOs and
+	// embed directives needs every comment, and counting exports, complexity or checking
+	// for unused imports every declaration, which requires parsing the whole file
+	mode := parser.ImportsOnly | parser.ParseComments
+	if a.todoCounts || a.exportedCounts || a.embedCounts || a.complexity || a.unusedImports {
+		mode = parser.ParseComments
+	}
+
+	file, err := parser.ParseFile(a.fileSet, filePath, src, mode)
+	if err != nil {
+		return result, err
+	}
+
+	unused := make(map[*ast.ImportSpec]bool)
+	if a.unusedImports {
+		for _, imp := range unusedImports(file) {
+			unused[imp] = true
+		}
+	}
 
-	var imports []string
 	for _, imp := range file.Imports {
-		// Remove quotes from import path
-		path := strings.Trim(imp.Path.Value, `"`)
-		imports = append(imports, path)
+		if a.skipBlankImports && imp.Name != nil && imp.Name.Name == "_" {
+			continue
+		}
+		importPath := canonicalImportPath(imp.Path.Value)
+		if unused[imp] {
+			slog.Warn("Warning: dropping unused import", "file", filePath, "import", importPath)
+			continue
+		}
+		result.imports = append(result.imports, importPath)
+	}
+
+	if a.todoCounts {
+		result.todoCount = countTodoMarkers(file.Comments)
+	}
+	if a.exportedCounts {
+		result.exportedCount = countExported(file)
+	}
+	if a.embedCounts {
+		result.embedCount = countEmbedDirectives(file.Comments)
+	}
+	if a.complexity {
+		result.complexity = fileComplexity(file)
+	}
+	result.generated = ast.IsGenerated(file)
+	result.ignored = hasIgnoreDirective(file)
+
+	return result, nil
+}
+
+// hasIgnoreDirective reports whether a file carries the ignore directive on a line of its own,
+// optionally followed by a reason. Only comments before the end of the import block are seen,
+// so the directive belongs in the file header, like a build constraint.
+func hasIgnoreDirective(file *ast.File) bool {
+	// Files parsed in full also carry later comments, which must not count
+	limit := token.NoPos
+	for _, decl := range file.Decls {
+		if genDecl, ok := decl.(*ast.GenDecl); !ok || genDecl.Tok != token.IMPORT {
+			limit = decl.Pos()
+			break
+		}
+	}
+
+	for _, group := range file.Comments {
+		if limit.IsValid() && group.Pos() >= limit {
+			break
+		}
+		for _, comment := range group.List {
+			if comment.Text == ignoreDirective || strings.HasPrefix(comment.Text, ignoreDirective+" ") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// countExported counts the exported top-level functions, types, variables and constants
+// declared in a file. Methods are not counted, as they are reached through their types.
+func countExported(file *ast.File) int {
+	count := 0
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv == nil && decl.Name.IsExported() {
+				count++
+			}
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					if spec.Name.IsExported() {
+						count++
+					}
+				case *ast.ValueSpec:
+					for _, name := range spec.Names {
+						if name.IsExported() {
+							count++
+						}
+					}
+				}
+			}
+		}
 	}
+	return count
+}
 
-	return imports, nil
+// countTodoMarkers counts the comment lines containing a TODO or FIXME marker.
+func countTodoMarkers(commentGroups []*ast.CommentGroup) int {
+	count := 0
+	for _, group := range commentGroups {
+		for _, comment := range group.List {
+			for _, line := range strings.Split(comment.Text, "\n") {
+				if strings.Contains(line, "TODO") || strings.Contains(line, "FIXME") {
+					count++
+				}
+			}
+		}
+	}
+	return count
+}
+
+// countEmbedDirectives counts the //go:embed directives among a file's comments.
+func countEmbedDirectives(commentGroups []*ast.CommentGroup) int {
+	count := 0
+	for _, group := range commentGroups {
+		for _, comment := range group.List {
+			if comment.Text == embedDirective || strings.HasPrefix(comment.Text, embedDirective+" ") {
+				count++
+			}
+		}
+	}
+	return count
 }
 
-// getPackageName extracts a short name from a package path.
+// countLines counts the lines in a source file, including a final line without a newline.
+func countLines(src []byte) int {
+	lines := bytes.Count(src, []byte("\n"))
+	if len(src) > 0 && src[len(src)-1] != '\n' {
+		lines++
+	}
+	return lines
+}
+
+// getPackageName extracts a short name from a package path. The major version suffix of a
+// module path, as in "example.com/foo/v2", names no package, so the element before it is used.
+// Packages inside a module keep their last element, so "example.com/foo/api/v2" is "v2".
 func (a *Analyzer) getPackageName(pkgPath string) string {
+	if prefix, found := majorVersionPrefix(pkgPath); found && a.isModulePath(pkgPath) {
+		pkgPath = prefix
+	}
 	parts := strings.Split(pkgPath, "/")
 	return parts[len(parts)-1]
 }
@@ -565,11 +1473,13 @@ func organizePackagesByLayer(graph *DependencyGraph, layers map[string]int) {
 }
 
 func (a *Analyzer) calculateLayers(graph *DependencyGraph) {
+	view := layeringView(graph)
+
 	// First, detect circular dependencies to exclude them from layer calculation
-	circularEdges := a.detectCircularDependencies(graph)
+	circularEdges := a.detectCircularDependencies(view)
 
 	// Build reverse dependency map to understand what depends on each package
-	reverseDeps := a.buildReverseDependencyMap(graph, circularEdges)
+	reverseDeps := a.buildReverseDependencyMap(view, circularEdges)
 
 	// Initialize all packages to unassigned (-1)
 	layers := initializeLayerMap(graph)
@@ -586,6 +1496,27 @@ func (a *Analyzer) calculateLayers(graph *DependencyGraph) {
 	organizePackagesByLayer(graph, layers)
 }
 
+// layeringView returns a copy of the graph without self-imports or imports of the entry
+// package, which always forms the top layer. Cycles through the entry, such as a subpackage
+// importing the module root, are then broken at the import of the entry instead of dropping
+// every edge of the cycle from the layering.
+func layeringView(graph *DependencyGraph) *DependencyGraph {
+	view := &DependencyGraph{
+		EntryPackage: graph.EntryPackage,
+		Packages:     make(map[string]*PackageInfo, len(graph.Packages)),
+	}
+	for pkgPath, pkg := range graph.Packages {
+		deps := make([]string, 0, len(pkg.Dependencies))
+		for _, dep := range pkg.Dependencies {
+			if dep != pkgPath && dep != graph.EntryPackage {
+				deps = append(deps, dep)
+			}
+		}
+		view.Packages[pkgPath] = &PackageInfo{Path: pkgPath, Dependencies: deps}
+	}
+	return view
+}
+
 // detectCircularDependencies identifies packages that have circular dependencies.
 func (a *Analyzer) detectCircularDependencies(graph *DependencyGraph) map[string]map[string]bool {
 	circularEdges := make(map[string]map[string]bool)
@@ -615,8 +1546,8 @@ func (a *Analyzer) findAllCycles(graph *DependencyGraph) [][]string {
 	visited := make(map[string]bool)
 	recStack := make(map[string]bool)
 
-	// Try to find cycles starting from each unvisited node
-	for pkgPath := range graph.Packages {
+	// Try to find cycles starting from each unvisited node, in sorted order for determinism
+	for _, pkgPath := range sortedPackagePaths(graph) {
 		if !visited[pkgPath] {
 			path := []string{}
 			a.dfsForCycles(graph, pkgPath, visited, recStack, path, &cycles)
@@ -638,26 +1569,20 @@ func (a *Analyzer) dfsForCycles(
 	recStack[node] = true
 	path = append(path, node)
 
-	if pkg, exists := graph.Packages[node]; exists {
-		a.processDependenciesForCycles(pkg, graph, visited, recStack, path, cycles)
-	}
+	a.processDependenciesForCycles(node, graph, visited, recStack, path, cycles)
 
 	recStack[node] = false
 }
 
 // processDependenciesForCycles processes package dependencies for cycle detection.
 func (a *Analyzer) processDependenciesForCycles(
-	pkg *PackageInfo,
+	pkgPath string,
 	graph *DependencyGraph,
 	visited, recStack map[string]bool,
 	path []string,
 	cycles *[][]string,
 ) {
-	for _, dep := range pkg.Dependencies {
-		if _, depExists := graph.Packages[dep]; !depExists {
-			continue
-		}
-
+	for _, dep := range sortedGraphDependencies(graph, pkgPath) {
 		if !visited[dep] {
 			a.dfsForCycles(graph, dep, visited, recStack, path, cycles)
 		} else if recStack[dep] {
@@ -682,7 +1607,25 @@ func (a *Analyzer) extractCycleFromPath(dep string, path []string, cycles *[][]s
 	}
 }
 
+// UpwardDependencies reports edges where a package imports one of its ancestors,
+// e.g. "a/b/c" importing "a/b". Each edge is returned as [source, target], sorted by source.
+func (a *Analyzer) UpwardDependencies(graph *DependencyGraph) [][2]string {
+	var edges [][2]string
+
+	for _, pkgPath := range sortedPackagePaths(graph) {
+		for _, dep := range sortedGraphDependencies(graph, pkgPath) {
+			if strings.HasPrefix(pkgPath, dep+"/") {
+				edges = append(edges, [2]string{pkgPath, dep})
+			}
+		}
+	}
+
+	return edges
+}
+
 // FindEntryPoints scans a directory tree for Go files containing main functions.
+// Entry points are returned in the lexical order of filepath.Walk, so repeated scans of
+// an unchanged tree list them identically.
 func (a *Analyzer) FindEntryPoints(repoRoot string) ([]string, error) {
 	var entryPoints []string
 
@@ -730,13 +1673,23 @@ func (a *Analyzer) FindEntryPoints(repoRoot string) ([]string, error) {
 }
 
 // fileContainsMainFunction checks if a Go file contains a main function.
+// Files excluded from the default build, or the build for the WithBuildTarget target, by build
+// constraints or GOOS/GOARCH file name suffixes are skipped unless WithConstrainedEntryPoints is set.
 func (a *Analyzer) fileContainsMainFunction(filePath string) (bool, error) {
-	// Parse the file
-	src, err := os.Open(filePath)
+	if !a.constrainedMains {
+		matched, err := a.buildContext().MatchFile(filepath.Dir(filePath), filepath.Base(filePath))
+		if err != nil {
+			return false, err
+		}
+		if !matched {
+			return false, nil
+		}
+	}
+
+	src, err := a.readSourceFile(filePath)
 	if err != nil {
 		return false, err
 	}
-	defer src.Close()
 
 	// Parse the Go source file
 	file, err := parser.ParseFile(a.fileSet, filePath, src, parser.ParseComments)
@@ -801,40 +1754,84 @@ func (a *Analyzer) processEntryPoint(
 		return nil
 	}
 
-	// Get package path for this entry point
-	pkgPath, pkgErr := a.getPackageFromFile(entryPath)
-	if pkgErr != nil {
-		slog.Warn("Warning: failed to get package path for",
-			"entryPath", entryPath,
-			"error", pkgErr)
-		return nil
-	}
-
 	// Create entry point record (DOT content will be generated later)
 	return &EntryPoint{
 		Path:         entryPath,
 		RelativePath: relPath,
-		PackagePath:  pkgPath,
+		Name:         a.entryPointName(relPath),
+		PackagePath:  graph.EntryPackage,
+		EntryName:    graph.EntryName,
 		DOTContent:   "", // Will be populated by the caller
 		Graph:        graph,
 	}
 }
 
+// entryPointName returns the display label for an entry point.
+// Files under a cmd/<name>/ directory are labeled <name>; anything else uses its relative path.
+func (a *Analyzer) entryPointName(relPath string) string {
+	slashPath := filepath.ToSlash(relPath)
+	if !a.cmdEntryNames {
+		return slashPath
+	}
+
+	dirParts := strings.Split(filepath.ToSlash(filepath.Dir(relPath)), "/")
+	for i := len(dirParts) - 2; i >= 0; i-- {
+		if dirParts[i] == "cmd" {
+			return dirParts[i+1]
+		}
+	}
+
+	return slashPath
+}
+
 // processAllEntryPoints processes all entry points and returns a slice of valid EntryPoint structs.
+// Up to WithMaxConcurrency entry points are analyzed at once, and the results keep the order of
+// entryPointPaths whatever order the analyses finish in.
 func (a *Analyzer) processAllEntryPoints(
 	entryPointPaths []string,
 	absRepoRoot string,
 	excludeExternal bool,
 	excludeDirs []string,
 ) []EntryPoint {
-	var entryPoints []EntryPoint
+	limit := a.maxConcurrency
+	if limit == 0 {
+		limit = runtime.GOMAXPROCS(0)
+	}
+
+	results := make([]*EntryPoint, len(entryPointPaths))
+	semaphore := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	var progressMu sync.Mutex
+	done := 0
+	for i, entryPath := range entryPointPaths {
+		semaphore <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			// Each analysis rebinds the module state of its analyzer, so every entry point
+			// gets its own copy
+			worker := *a
+			worker.fileSet = token.NewFileSet()
+			results[i] = worker.processEntryPoint(entryPath, absRepoRoot, excludeExternal, excludeDirs)
+
+			progressMu.Lock()
+			defer progressMu.Unlock()
+			done++
+			if a.onProgress != nil {
+				a.onProgress(done, len(entryPointPaths), entryPath)
+			}
+		}()
+	}
+	wg.Wait()
 
-	for _, entryPath := range entryPointPaths {
-		if entryPoint := a.processEntryPoint(entryPath, absRepoRoot, excludeExternal, excludeDirs); entryPoint != nil {
+	var entryPoints []EntryPoint
+	for _, entryPoint := range results {
+		if entryPoint != nil {
 			entryPoints = append(entryPoints, *entryPoint)
 		}
 	}
-
 	return entryPoints
 }
 
@@ -862,6 +1859,8 @@ func determineResultModuleName(entryPoints []EntryPoint, absRepoRoot string) str
 }
 
 // AnalyzeMultipleEntryPoints finds and analyzes all entry points in a repository.
+// Entry points keep the order of FindEntryPoints, and each graph's layers, dependencies and
+// cycles are sorted, so repeated analyses of an unchanged tree serialize byte for byte alike.
 func (a *Analyzer) AnalyzeMultipleEntryPoints(
 	repoRoot string,
 	excludeExternal bool,
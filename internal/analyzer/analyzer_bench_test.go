@@ -0,0 +1,79 @@
+package analyzer_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/cvsouth/go-package-analyzer/internal/analyzer"
+)
+
+func BenchmarkBuildSyntheticGraph(b *testing.B) {
+	for _, n := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprintf("packages=%d", n), func(b *testing.B) {
+			for b.Loop() {
+				analyzer.BuildSyntheticGraph(n)
+			}
+		})
+	}
+}
+
+func BenchmarkAnalyzeFromFile(b *testing.B) {
+	for _, n := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprintf("packages=%d", n), func(b *testing.B) {
+			mainPath := writeSyntheticProject(b, analyzer.BuildSyntheticGraph(n))
+
+			for b.Loop() {
+				a := analyzer.New()
+				if _, err := a.AnalyzeFromFile(mainPath, true, nil); err != nil {
+					b.Fatalf("AnalyzeFromFile failed: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// writeSyntheticProject writes a graph to disk as a Go module and returns the entry file path.
+func writeSyntheticProject(b *testing.B, graph *analyzer.DependencyGraph) string {
+	b.Helper()
+
+	root := b.TempDir()
+	goMod := fmt.Sprintf("module %s\n\ngo 1.21\n", graph.ModuleName)
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte(goMod), 0644); err != nil {
+		b.Fatalf("Failed to create go.mod: %v", err)
+	}
+
+	for pkgPath, pkg := range graph.Packages {
+		relPath := strings.TrimPrefix(strings.TrimPrefix(pkgPath, graph.ModuleName), "/")
+		pkgDir := filepath.Join(root, filepath.FromSlash(relPath))
+		if err := os.MkdirAll(pkgDir, 0755); err != nil {
+			b.Fatalf("Failed to create package directory: %v", err)
+		}
+
+		pkgName := pkg.Name
+		if pkgPath == graph.EntryPackage {
+			pkgName = "main"
+		}
+
+		deps := append([]string(nil), pkg.Dependencies...)
+		sort.Strings(deps)
+
+		var content strings.Builder
+		fmt.Fprintf(&content, "package %s\n\n", pkgName)
+		for _, dep := range deps {
+			fmt.Fprintf(&content, "import _ %q\n", dep)
+		}
+		if pkgName == "main" {
+			content.WriteString("\nfunc main() {}\n")
+		}
+
+		if err := os.WriteFile(filepath.Join(pkgDir, pkg.Name+".go"), []byte(content.String()), 0644); err != nil {
+			b.Fatalf("Failed to create Go file: %v", err)
+		}
+	}
+
+	return filepath.Join(root, "synthetic.go")
+}
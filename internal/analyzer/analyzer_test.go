@@ -1,10 +1,21 @@
 package analyzer_test
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/cvsouth/go-package-analyzer/internal/analyzer"
 
@@ -65,6 +76,27 @@ func TestAnalyzeFromFile_NonExistentFile(t *testing.T) {
 	if err == nil {
 		t.Error("Expected error for non-existent file")
 	}
+	if !errors.Is(err, analyzer.ErrEntryNotFound) {
+		t.Errorf("Expected ErrEntryNotFound, got %v", err)
+	}
+}
+
+func TestAnalyzeFromFile_PermissionDenied(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("Permission checks do not apply when running as root")
+	}
+
+	tmpDir := t.TempDir()
+	createGoMod(t, tmpDir, "test/project")
+	mainFile := filepath.Join(tmpDir, "main.go")
+	createGoFile(t, mainFile, "package main\n\nfunc main() {}\n")
+	require.NoError(t, os.Chmod(mainFile, 0000))
+	t.Cleanup(func() { _ = os.Chmod(mainFile, 0644) })
+
+	_, err := analyzer.New().AnalyzeFromFile(mainFile, true, nil)
+
+	require.ErrorIs(t, err, analyzer.ErrPermission)
+	assert.NotErrorIs(t, err, analyzer.ErrEntryNotFound)
 }
 
 func TestAnalyzeFromFile_WithExternalDependencies(t *testing.T) {
@@ -225,6 +257,155 @@ func TestFindEntryPoints(t *testing.T) {
 	}
 }
 
+func TestFindEntryPoints_BuildConstraints(t *testing.T) {
+	testDataPath, err := filepath.Abs("../../testing/data/simple_project")
+	require.NoError(t, err)
+	ignoredMain := filepath.Join(testDataPath, "tools", "gen.go")
+
+	entryPoints, err := analyzer.New().FindEntryPoints(testDataPath)
+	require.NoError(t, err)
+	assert.NotContains(t, entryPoints, ignoredMain, "Files behind //go:build ignore should be skipped")
+	assert.Contains(t, entryPoints, filepath.Join(testDataPath, "main.go"))
+
+	entryPoints, err = analyzer.New(analyzer.WithConstrainedEntryPoints(true)).FindEntryPoints(testDataPath)
+	require.NoError(t, err)
+	assert.Contains(t, entryPoints, ignoredMain)
+}
+
+func TestAnalyzeMultipleEntryPoints_CmdNames(t *testing.T) {
+	tmpDir := t.TempDir()
+	createGoMod(t, tmpDir, "test/project")
+	mainContent := "package main\n\nfunc main() {}\n"
+	for _, dir := range []string{"cmd/api", "cmd/worker", "tools"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, dir), 0755))
+		createGoFile(t, filepath.Join(tmpDir, dir, "main.go"), mainContent)
+	}
+
+	testCases := []struct {
+		name     string
+		opts     []analyzer.Option
+		expected map[string]string
+	}{
+		{
+			name: "cmd convention enabled by default",
+			expected: map[string]string{
+				"cmd/api/main.go":    "api",
+				"cmd/worker/main.go": "worker",
+				"tools/main.go":      "tools/main.go",
+			},
+		},
+		{
+			name: "cmd convention disabled",
+			opts: []analyzer.Option{analyzer.WithCmdEntryNames(false)},
+			expected: map[string]string{
+				"cmd/api/main.go":    "cmd/api/main.go",
+				"cmd/worker/main.go": "cmd/worker/main.go",
+				"tools/main.go":      "tools/main.go",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := analyzer.New(tc.opts...).AnalyzeMultipleEntryPoints(tmpDir, true, nil)
+			require.NoError(t, err)
+			require.True(t, result.Success, result.Error)
+
+			names := make(map[string]string)
+			for _, ep := range result.EntryPoints {
+				names[filepath.ToSlash(ep.RelativePath)] = ep.Name
+				assert.True(t, strings.HasSuffix(ep.PackagePath, "/"+ep.EntryName), ep.EntryName)
+				assert.Equal(t, ep.EntryName, ep.Graph.EntryName)
+			}
+			assert.Equal(t, tc.expected, names)
+		})
+	}
+}
+
+func TestAnalyzeMultipleEntryPoints_Progress(t *testing.T) {
+	tmpDir := t.TempDir()
+	createGoMod(t, tmpDir, "test/project")
+	for _, dir := range []string{"cmd/api", "cmd/worker"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, dir), 0755))
+		createGoFile(t, filepath.Join(tmpDir, dir, "main.go"), "package main\n\nfunc main() {}\n")
+	}
+
+	var counts, paths []string
+	onProgress := func(done, total int, current string) {
+		rel, err := filepath.Rel(tmpDir, current)
+		require.NoError(t, err)
+		counts = append(counts, fmt.Sprintf("%d/%d", done, total))
+		paths = append(paths, filepath.ToSlash(rel))
+	}
+
+	result, err := analyzer.New(analyzer.WithProgress(onProgress)).AnalyzeMultipleEntryPoints(tmpDir, true, nil)
+	require.NoError(t, err)
+	require.True(t, result.Success, result.Error)
+	assert.Equal(t, []string{"1/2", "2/2"}, counts)
+	assert.ElementsMatch(t, []string{"cmd/api/main.go", "cmd/worker/main.go"}, paths,
+		"Entry points finish in any order")
+}
+
+func TestAnalyzeMultipleEntryPoints_Deterministic(t *testing.T) {
+	tmpDir := t.TempDir()
+	createGoMod(t, tmpDir, "test/project")
+	createNestedPackage(t, tmpDir, "internal/x", "package x\n\nimport _ \"test/project/internal/y\"\n")
+	createNestedPackage(t, tmpDir, "internal/y", "package y\n\nimport _ \"test/project/internal/x\"\n")
+	for _, pkg := range []string{"alpha", "beta", "gamma", "delta"} {
+		createNestedPackage(t, tmpDir, "lib/"+pkg, "package "+pkg+"\n\nimport _ \"fmt\"\n")
+	}
+	mainContent := `package main
+
+import (
+	_ "os"
+	_ "test/project/internal/x"
+	_ "test/project/lib/alpha"
+	_ "test/project/lib/beta"
+	_ "test/project/lib/delta"
+	_ "test/project/lib/gamma"
+)
+
+func main() {}
+`
+	for _, dir := range []string{"cmd/a-b", "cmd/a", "tools"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, dir), 0755))
+		createGoFile(t, filepath.Join(tmpDir, dir, "main.go"), mainContent)
+	}
+
+	serialize := func(opts ...analyzer.Option) []byte {
+		result, err := analyzer.New(opts...).AnalyzeMultipleEntryPoints(tmpDir, false, nil)
+		require.NoError(t, err)
+		require.True(t, result.Success, result.Error)
+
+		output, err := json.Marshal(result)
+		require.NoError(t, err)
+		for _, ep := range result.EntryPoints {
+			graphJSON, marshalErr := json.Marshal(ep.Graph)
+			require.NoError(t, marshalErr)
+			output = append(output, graphJSON...)
+		}
+		return output
+	}
+
+	first := serialize()
+	for range 5 {
+		assert.Equal(t, string(first), string(serialize()), "Repeated analyses should serialize identically")
+	}
+	assert.Equal(t, string(first), string(serialize(analyzer.WithMaxConcurrency(1))),
+		"Concurrent and sequential analyses should serialize identically")
+	assert.Equal(t, string(first), string(serialize(analyzer.WithMaxConcurrency(3))),
+		"Analyzing every entry point at once should serialize identically")
+
+	result, err := analyzer.New().AnalyzeMultipleEntryPoints(tmpDir, false, nil)
+	require.NoError(t, err)
+	var relPaths []string
+	for _, ep := range result.EntryPoints {
+		relPaths = append(relPaths, filepath.ToSlash(ep.RelativePath))
+	}
+	assert.Equal(t, []string{"cmd/a/main.go", "cmd/a-b/main.go", "tools/main.go"}, relPaths,
+		"Entry points should follow the lexical directory walk")
+}
+
 func TestAnalyzeFromFile_EmptyPackage(t *testing.T) {
 	testDataPath, err := filepath.Abs("../../testing/data/edge_cases")
 	if err != nil {
@@ -279,6 +460,25 @@ func TestAnalyzeFromFile_UnicodeFilename(t *testing.T) {
 	}
 }
 
+func TestAnalyzeFromFile_MixedPackageClauses(t *testing.T) {
+	testDataPath, err := filepath.Abs("../../testing/data/edge_cases")
+	require.NoError(t, err)
+	mainFile := filepath.Join(testDataPath, "mixed_packages", "main.go")
+
+	graph, err := analyzer.New().AnalyzeFromFile(mainFile, true, nil)
+	require.NoError(t, err, "Conflicting package clauses should only warn by default")
+	assert.Equal(t, "testing/data/edge_cases/mixed_packages", graph.EntryPackage)
+
+	_, err = analyzer.New(analyzer.WithStrictPackageClauses(true)).AnalyzeFromFile(mainFile, true, nil)
+	require.ErrorIs(t, err, analyzer.ErrPackageConflict)
+	assert.Contains(t, err.Error(), "helper (helper.go), main (main.go)")
+	assert.NotContains(t, err.Error(), "gen.go", "Files excluded from the build should not be compared")
+
+	unicodeFile := filepath.Join(testDataPath, "unicode", "тест.go")
+	_, err = analyzer.New(analyzer.WithStrictPackageClauses(true)).AnalyzeFromFile(unicodeFile, true, nil)
+	require.NoError(t, err, "A directory with a single package should pass in strict mode")
+}
+
 func TestAnalyzeFromFile_WithExclusions(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -409,6 +609,33 @@ func TestAnalyzeFromFile_InvalidGoSyntax(t *testing.T) {
 	}
 }
 
+// TestAnalyzeFromFile_IncompletePackage tests that packages with unparseable files are marked incomplete.
+func TestAnalyzeFromFile_IncompletePackage(t *testing.T) {
+	tmpDir := t.TempDir()
+	createGoMod(t, tmpDir, "test/project")
+	createNestedPackage(t, tmpDir, "good", "package good\n")
+	createNestedPackage(t, tmpDir, "partial", "package partial\n\nimport \"fmt\"\n")
+	createGoFile(t, filepath.Join(tmpDir, "partial", "broken.go"), "package partial\n\nimport (\n\t\"strings\"\n")
+	mainFile := filepath.Join(tmpDir, "main.go")
+	createGoFile(t, mainFile, `package main
+
+import (
+	_ "test/project/good"
+	_ "test/project/partial"
+)
+
+func main() {}
+`)
+
+	graph, err := analyzer.New().AnalyzeFromFile(mainFile, false, nil)
+	require.NoError(t, err)
+
+	assert.True(t, graph.Packages["test/project"].Complete)
+	assert.True(t, graph.Packages["test/project/good"].Complete)
+	assert.False(t, graph.Packages["test/project/partial"].Complete, "A file failed to parse")
+	assert.Equal(t, []string{"fmt"}, graph.Packages["test/project/partial"].Dependencies)
+}
+
 // TestAnalyzeFromFile_ModuleFinding tests module detection through black-box approach.
 func TestAnalyzeFromFile_ModuleFinding(t *testing.T) {
 	testCases := []struct {
@@ -454,6 +681,892 @@ func TestAnalyzeFromFile_ModuleFinding(t *testing.T) {
 	}
 }
 
+func TestAnalyzeFromFile_InfersModuleNameWithoutGoMod(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	mainContent := `package main
+
+import (
+	"fmt"
+
+	"example.com/inferred/internal/util"
+)
+
+func main() {
+	fmt.Println(util.Name())
+}`
+	mainPath := filepath.Join(tmpDir, "main.go")
+	createGoFile(t, mainPath, mainContent)
+	createNestedPackage(t, tmpDir, filepath.Join("internal", "util"),
+		"package util\nfunc Name() string { return \"\" }")
+
+	a := analyzer.New()
+	graph, err := a.AnalyzeFromFile(mainPath, true, nil)
+	require.NoError(t, err, "AnalyzeFromFile failed")
+
+	assert.Equal(t, "example.com/inferred", graph.ModuleName)
+	assert.Contains(t, graph.Packages, "example.com/inferred/internal/util")
+}
+
+func TestAnalyzeFromFile_InferModuleNameIgnoresStandardLibrary(t *testing.T) {
+	tmpDir := t.TempDir()
+	mainPath := filepath.Join(tmpDir, "main.go")
+	createGoFile(t, mainPath, `package main
+
+import (
+	"net/http"
+
+	"github.com/other/lib"
+)
+
+func main() {
+	http.ListenAndServe(":8080", lib.Handler())
+}`)
+	// A local directory named like the last element of a standard library import
+	createNestedPackage(t, tmpDir, "http", "package http\n")
+
+	graph, err := analyzer.New().AnalyzeFromFile(mainPath, true, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, filepath.Base(tmpDir), graph.ModuleName,
+		"A standard library import should not be taken for the module path")
+	assert.NotContains(t, graph.Packages, "net")
+}
+
+// TestAnalyzeFromFile_PackageDirs tests that analyzed packages record their directory on disk.
+func TestAnalyzeFromFile_PackageDirs(t *testing.T) {
+	tmpDir := t.TempDir()
+	createGoMod(t, tmpDir, "test/project")
+	createNestedPackage(t, tmpDir, "internal/util", "package util\n\nfunc Helper() {}\n")
+	mainFile := filepath.Join(tmpDir, "main.go")
+	createGoFile(t, mainFile, `package main
+
+import (
+	"fmt"
+	"test/project/internal/util"
+)
+
+func main() {
+	fmt.Println("hi")
+	util.Helper()
+}
+`)
+
+	graph, err := analyzer.New().AnalyzeFromFile(mainFile, false, nil)
+	require.NoError(t, err)
+
+	absRoot, err := filepath.Abs(tmpDir)
+	require.NoError(t, err)
+
+	require.Contains(t, graph.Packages, "test/project")
+	require.Contains(t, graph.Packages, "test/project/internal/util")
+	require.Contains(t, graph.Packages, "fmt")
+	assert.Equal(t, filepath.Clean(absRoot), graph.Packages["test/project"].Dir)
+	assert.Equal(t, filepath.Join(absRoot, "internal", "util"), graph.Packages["test/project/internal/util"].Dir)
+	assert.Empty(t, graph.Packages["fmt"].Dir, "External packages should have no directory")
+}
+
+func TestClassifyExplain(t *testing.T) {
+	tmpDir := t.TempDir()
+	createGoFile(t, filepath.Join(tmpDir, "go.mod"),
+		"module myapp\n\ngo 1.21\n\nreplace example.com/lib => ../lib\n")
+	mainFile := filepath.Join(tmpDir, "main.go")
+	createGoFile(t, mainFile, "package main\n\nimport _ \"github.com/me/myapp/util\"\n\nfunc main() {}\n")
+
+	a := analyzer.New()
+	graph, err := a.AnalyzeFromFile(mainFile, false, nil)
+	require.NoError(t, err)
+	assert.Contains(t, graph.Packages, "github.com/me/myapp/util", "Mismatched imports should still be analyzed")
+
+	testCases := []struct {
+		pkgPath  string
+		expected string
+	}{
+		{pkgPath: "myapp/util", expected: "internal: under module prefix 'myapp'"},
+		{
+			pkgPath:  "github.com/me/myapp/util",
+			expected: "external: not under module prefix 'myapp'",
+		},
+		{
+			pkgPath:  "net/http",
+			expected: "external: not under module prefix 'myapp'; stdlib: no dot in first segment 'net'",
+		},
+		{
+			pkgPath:  "example.com/lib/sub",
+			expected: "external: not under module prefix 'myapp', provided by replaced module 'example.com/lib'",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.pkgPath, func(t *testing.T) {
+			assert.Equal(t, tc.expected, a.ClassifyExplain(tc.pkgPath))
+		})
+	}
+}
+
+func TestAnalyzeArchive(t *testing.T) {
+	files := map[string]string{
+		"project/go.mod":            "module example.com/project\n\ngo 1.21\n",
+		"project/cmd/app/main.go":   "package main\n\nimport _ \"example.com/project/util\"\n\nfunc main() {}\n",
+		"project/util/util.go":      "package util\n",
+		"project/tools/gen/main.go": "package main\n\nfunc main() {}\n",
+	}
+
+	testCases := []struct {
+		format  string
+		archive []byte
+	}{
+		{format: analyzer.ArchiveZip, archive: createZipArchive(t, files)},
+		{format: analyzer.ArchiveTarGz, archive: createTarGzArchive(t, files)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.format, func(t *testing.T) {
+			result, err := analyzer.New().AnalyzeArchive(
+				bytes.NewReader(tc.archive), int64(len(tc.archive)), tc.format, true, nil)
+			require.NoError(t, err)
+			require.True(t, result.Success, result.Error)
+
+			assert.Equal(t, "example.com/project", result.ModuleName)
+			require.Len(t, result.EntryPoints, 2)
+			assert.Equal(t, filepath.Join("cmd", "app", "main.go"), result.EntryPoints[0].RelativePath)
+			assert.ElementsMatch(t, []string{"example.com/project/cmd/app", "example.com/project/util"},
+				getPackageNames(result.EntryPoints[0].Graph.Packages))
+			assert.NoDirExists(t, result.RepoRoot, "The extraction directory should be removed")
+		})
+	}
+}
+
+func TestAnalyzeArchive_Errors(t *testing.T) {
+	archive := createZipArchive(t, map[string]string{"go.mod": "module example.com/project\n"})
+	_, err := analyzer.New().AnalyzeArchive(bytes.NewReader(archive), int64(len(archive)), "rar", true, nil)
+	require.ErrorIs(t, err, analyzer.ErrUnsupportedArchive)
+
+	for _, name := range []string{"../escape.go", "/abs/escape.go"} {
+		archive = createTarGzArchive(t, map[string]string{name: "package escape\n"})
+		_, err = analyzer.New().AnalyzeArchive(
+			bytes.NewReader(archive), int64(len(archive)), analyzer.ArchiveTarGz, true, nil)
+		require.ErrorIs(t, err, analyzer.ErrArchiveEntry, name)
+	}
+}
+
+func TestAnalyzeFromFile_Churn(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git command not available")
+	}
+
+	tmpDir := t.TempDir()
+	createGoMod(t, tmpDir, "test/project")
+	mainFile := filepath.Join(tmpDir, "main.go")
+	createGoFile(t, mainFile, "package main\n\nimport _ \"test/project/util\"\n\nfunc main() {}\n")
+	utilFile := createNestedPackage(t, tmpDir, "util", "package util\n")
+
+	graph, err := analyzer.New(analyzer.WithChurn(24*time.Hour)).AnalyzeFromFile(mainFile, true, nil)
+	require.NoError(t, err, "Analysis outside a git repository should still succeed")
+	assert.Zero(t, graph.Packages["test/project/util"].Churn)
+
+	git := func(args ...string) {
+		identity := []string{"-c", "user.name=test", "-c", "user.email=test@example.com"}
+		cmd := exec.Command("git", append(identity, args...)...)
+		cmd.Dir = tmpDir
+		output, gitErr := cmd.CombinedOutput()
+		require.NoError(t, gitErr, string(output))
+	}
+	git("init", "-q")
+	git("add", "-A")
+	git("commit", "-q", "-m", "initial")
+	createGoFile(t, utilFile, "package util\n\nfunc Helper() {}\n")
+	createGoFile(t, filepath.Join(tmpDir, "util", "more.go"), "package util\n")
+	git("add", "-A")
+	git("commit", "-q", "-m", "extend util")
+	createGoFile(t, filepath.Join(tmpDir, "README.md"), "# project\n")
+	git("add", "-A")
+	git("commit", "-q", "-m", "docs")
+
+	graph, err = analyzer.New(analyzer.WithChurn(24*time.Hour)).AnalyzeFromFile(mainFile, true, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, graph.Packages["test/project/util"].Churn, "A commit should count once per package")
+	assert.Equal(t, 1, graph.Packages["test/project"].Churn, "Non-Go files should not count")
+
+	graph, err = analyzer.New().AnalyzeFromFile(mainFile, true, nil)
+	require.NoError(t, err)
+	assert.Zero(t, graph.Packages["test/project/util"].Churn, "Churn should be off by default")
+}
+
+func TestAnalyzeFromFile_WarnsAboutExternalsThatLookInternal(t *testing.T) {
+	tmpDir := t.TempDir()
+	createGoMod(t, tmpDir, "github.com/acme/log")
+	mainFile := filepath.Join(tmpDir, "main.go")
+	createGoFile(t, mainFile, `package main
+
+import (
+	_ "log"
+	_ "log/slog"
+
+	_ "github.com/Acme/Log/util"
+	_ "github.com/acme/shared"
+	_ "github.com/other/log"
+	_ "example.com/api/log"
+)
+
+func main() {}
+`)
+	var logs bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	_, err := analyzer.New().AnalyzeFromFile(mainFile, false, nil)
+	require.NoError(t, err)
+
+	var warned []string
+	for line := range strings.Lines(logs.String()) {
+		if strings.Contains(line, "looks internal") {
+			_, pkgPath, _ := strings.Cut(line, "package=")
+			warned = append(warned, strings.Fields(pkgPath)[0])
+		}
+	}
+	assert.ElementsMatch(t, []string{"github.com/Acme/Log/util", "github.com/acme/shared"}, warned,
+		"Only case mismatches and siblings under the module's owner should be warned about")
+}
+
+func TestAnalyzeFromFile_ExternalDepth(t *testing.T) {
+	tmpDir := t.TempDir()
+	appDir := filepath.Join(tmpDir, "app")
+	libDir := filepath.Join(tmpDir, "lib")
+	require.NoError(t, os.MkdirAll(appDir, 0755))
+	require.NoError(t, os.MkdirAll(libDir, 0755))
+
+	createGoFile(t, filepath.Join(appDir, "go.mod"),
+		"module test/app\n\ngo 1.21\n\nreplace example.com/lib => ../lib\n")
+	mainFile := filepath.Join(appDir, "main.go")
+	createGoFile(t, mainFile, "package main\n\nimport _ \"example.com/lib\"\n\nfunc main() {}\n")
+
+	createGoMod(t, libDir, "example.com/lib")
+	createGoFile(t, filepath.Join(libDir, "lib.go"), "package lib\n\nimport _ \"example.com/lib/internal/deep\"\n")
+	createNestedPackage(t, libDir, "internal/deep", "package deep\n\nimport _ \"strings\"\n")
+
+	testCases := []struct {
+		name     string
+		depth    int
+		expected []string
+	}{
+		{name: "default shows external as leaf", depth: -1, expected: []string{"example.com/lib", "test/app"}},
+		{name: "depth 1", depth: 1, expected: []string{"example.com/lib", "example.com/lib/internal/deep", "test/app"}},
+		{
+			name:     "depth 2",
+			depth:    2,
+			expected: []string{"example.com/lib", "example.com/lib/internal/deep", "strings", "test/app"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := analyzer.New(analyzer.WithExternalDepth(tc.depth))
+			graph, err := a.AnalyzeFromFile(mainFile, false, nil)
+			require.NoError(t, err)
+
+			assert.ElementsMatch(t, tc.expected, getPackageNames(graph.Packages))
+		})
+	}
+}
+
+func TestAnalyzeFromFile_LocalExternalSources(t *testing.T) {
+	tmpDir := t.TempDir()
+	appDir := filepath.Join(tmpDir, "app")
+	cacheDir := filepath.Join(tmpDir, "modcache")
+	require.NoError(t, os.MkdirAll(appDir, 0755))
+	t.Setenv("GOMODCACHE", cacheDir)
+
+	createGoFile(t, filepath.Join(appDir, "go.mod"), `module test/app
+
+go 1.21
+
+require (
+	example.com/Cached v1.2.0
+	example.com/vendored v0.1.0 // indirect
+)
+`)
+	mainFile := filepath.Join(appDir, "main.go")
+	createGoFile(t, mainFile,
+		"package main\n\nimport (\n\t_ \"example.com/Cached/sub\"\n\t_ \"example.com/vendored\"\n)\n\nfunc main() {}\n")
+
+	cachedDir := filepath.Join(cacheDir, "example.com", "!cached@v1.2.0")
+	createNestedPackage(t, cachedDir, "sub", "package sub\n\nimport _ \"strings\"\n")
+	createNestedPackage(t, appDir, "vendor/example.com/vendored", "package vendored\n\nimport _ \"fmt\"\n")
+
+	graph, err := analyzer.New(analyzer.WithExternalDepth(1)).AnalyzeFromFile(mainFile, false, nil)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"example.com/Cached/sub", "example.com/vendored", "test/app"},
+		getPackageNames(graph.Packages), "Without the option, external packages stay leaves")
+
+	a := analyzer.New(analyzer.WithExternalDepth(1), analyzer.WithLocalExternalSources(true))
+	graph, err = a.AnalyzeFromFile(mainFile, false, nil)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"example.com/Cached/sub", "example.com/vendored", "fmt", "strings", "test/app"},
+		getPackageNames(graph.Packages))
+	assert.Equal(t, []string{"strings"}, graph.Packages["example.com/Cached/sub"].Dependencies)
+	assert.Equal(t, "example.com/Cached", graph.Packages["example.com/Cached/sub"].Module)
+	assert.Equal(t, filepath.Join(cachedDir, "sub"), graph.Packages["example.com/Cached/sub"].Dir)
+	assert.Equal(t, []string{"fmt"}, graph.Packages["example.com/vendored"].Dependencies)
+	assert.Equal(t, "example.com/vendored", graph.Packages["example.com/vendored"].Module)
+
+	graph, err = analyzer.New(analyzer.WithLocalExternalSources(true)).AnalyzeFromFile(mainFile, false, nil)
+	require.NoError(t, err)
+	assert.Empty(t, graph.Packages["example.com/vendored"].Dependencies, "External depth still bounds expansion")
+}
+
+// setupGoListProject creates a module whose api package has a file excluded by build constraints,
+// plus a tool package the entry does not import.
+func setupGoListProject(t *testing.T) string {
+	t.Helper()
+
+	tmpDir := t.TempDir()
+	createGoMod(t, tmpDir, "test/app")
+	createGoFile(t, filepath.Join(tmpDir, "main.go"), "package main\n\nimport _ \"test/app/api\"\n\nfunc main() {}\n")
+	createNestedPackage(t, tmpDir, "api", "package api\n\nimport _ \"strings\"\n")
+	createGoFile(t, filepath.Join(tmpDir, "api", "legacy.go"),
+		"//go:build ignore\n\npackage api\n\nimport _ \"test/app/legacy\"\n")
+	createNestedPackage(t, tmpDir, "legacy", "package legacy\n")
+	createNestedPackage(t, tmpDir, "tool", "package tool\n\nimport _ \"fmt\"\n")
+	return tmpDir
+}
+
+func TestAnalyzeWithGoList(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not available")
+	}
+	projectDir := setupGoListProject(t)
+
+	graph, err := analyzer.New().AnalyzeWithGoList(projectDir, false, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "test/app", graph.EntryPackage)
+	assert.ElementsMatch(t, []string{"fmt", "strings", "test/app", "test/app/api", "test/app/legacy", "test/app/tool"},
+		getPackageNames(graph.Packages), "Every package under the directory should be listed")
+	api := graph.Packages["test/app/api"]
+	assert.Equal(t, []string{"strings"}, api.Dependencies, "Files excluded by build constraints add no edges")
+	assert.Equal(t, 1, api.FileCount)
+	assert.Equal(t, "test/app", api.Module)
+	assert.True(t, api.Complete)
+
+	graph, err = analyzer.New().AnalyzeWithGoList(filepath.Join(projectDir, "tool"), true, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "test/app/tool", graph.EntryPackage)
+	assert.ElementsMatch(t, []string{"test/app/tool"}, getPackageNames(graph.Packages))
+}
+
+func TestAnalyzeFromFile_GoList(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not available")
+	}
+	mainFile := filepath.Join(setupGoListProject(t), "main.go")
+
+	graph, err := analyzer.New().AnalyzeFromFile(mainFile, false, nil)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"strings", "test/app", "test/app/api", "test/app/legacy"},
+		getPackageNames(graph.Packages), "The AST scanner reads every file regardless of build constraints")
+
+	graph, err = analyzer.New(analyzer.WithGoList(true)).AnalyzeFromFile(mainFile, false, nil)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"strings", "test/app", "test/app/api"}, getPackageNames(graph.Packages),
+		"Only packages reachable from the entry should be kept")
+	assert.Equal(t, [][]string{{"test/app"}, {"test/app/api"}, {"strings"}}, graph.Layers)
+}
+
+func TestAnalyzeWithGoList_FallsBackWithoutGo(t *testing.T) {
+	projectDir := setupGoListProject(t)
+	t.Setenv("PATH", "")
+
+	graph, err := analyzer.New().AnalyzeWithGoList(projectDir, false, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "test/app", graph.EntryPackage)
+	assert.Contains(t, graph.Packages, "test/app/legacy", "The AST scanner should have been used")
+	assert.NotContains(t, graph.Packages, "test/app/tool")
+}
+
+func TestAnalyzeFromFile_ReachableOnly(t *testing.T) {
+	tmpDir := t.TempDir()
+	appDir := filepath.Join(tmpDir, "app")
+	libDir := filepath.Join(tmpDir, "lib")
+	require.NoError(t, os.MkdirAll(appDir, 0755))
+	require.NoError(t, os.MkdirAll(libDir, 0755))
+
+	createGoFile(t, filepath.Join(appDir, "go.mod"),
+		"module test/app\n\ngo 1.21\n\nreplace example.com/lib => ../lib\n")
+	mainFile := filepath.Join(appDir, "main.go")
+	createGoFile(t, mainFile, "package main\n\nimport _ \"example.com/lib\"\n\nfunc main() {}\n")
+	createNestedPackage(t, appDir, "plugin", "package plugin\n")
+
+	// The plugin is only reached through lib, which the fan-in filter then drops
+	createGoMod(t, libDir, "example.com/lib")
+	createGoFile(t, filepath.Join(libDir, "lib.go"), "package lib\n\nimport _ \"test/app/plugin\"\n")
+
+	opts := []analyzer.Option{analyzer.WithExternalDepth(1), analyzer.WithMinExternalFanIn(2)}
+	graph, err := analyzer.New(opts...).AnalyzeFromFile(mainFile, false, nil)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"test/app", "test/app/plugin"}, getPackageNames(graph.Packages))
+
+	opts = append(opts, analyzer.WithReachableOnly(true))
+	graph, err = analyzer.New(opts...).AnalyzeFromFile(mainFile, false, nil)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"test/app"}, getPackageNames(graph.Packages))
+	assert.Equal(t, [][]string{{"test/app"}}, graph.Layers)
+}
+
+func TestAnalyzeFromFile_PackageModules(t *testing.T) {
+	tmpDir := t.TempDir()
+	appDir := filepath.Join(tmpDir, "app")
+	libDir := filepath.Join(tmpDir, "lib")
+	require.NoError(t, os.MkdirAll(appDir, 0755))
+	require.NoError(t, os.MkdirAll(libDir, 0755))
+
+	createGoFile(t, filepath.Join(appDir, "go.mod"),
+		"module test/app\n\ngo 1.21\n\nreplace example.com/lib => ../lib\n")
+	mainFile := filepath.Join(appDir, "main.go")
+	createGoFile(t, mainFile,
+		"package main\n\nimport (\n\t_ \"example.com/lib\"\n\t_ \"test/app/api\"\n)\n\nfunc main() {}\n")
+	createNestedPackage(t, appDir, "api", "package api\n\nimport _ \"fmt\"\n")
+	createGoMod(t, libDir, "example.com/lib")
+	createGoFile(t, filepath.Join(libDir, "lib.go"), "package lib\n\nimport _ \"example.com/lib/internal/deep\"\n")
+	createNestedPackage(t, libDir, "internal/deep", "package deep\n")
+
+	graph, err := analyzer.New(analyzer.WithExternalDepth(1)).AnalyzeFromFile(mainFile, false, nil)
+	require.NoError(t, err)
+
+	modules := make(map[string]string)
+	for pkgPath, pkg := range graph.Packages {
+		modules[pkgPath] = pkg.Module
+	}
+	assert.Equal(t, map[string]string{
+		"test/app":                      "test/app",
+		"test/app/api":                  "test/app",
+		"example.com/lib":               "example.com/lib",
+		"example.com/lib/internal/deep": "example.com/lib",
+		"fmt":                           "",
+	}, modules)
+}
+
+func TestAnalyzeFromFile_MajorVersionModule(t *testing.T) {
+	tmpDir, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	createGoFile(t, filepath.Join(tmpDir, "go.mod"), `module example.com/foo/v2
+
+go 1.21
+
+require github.com/bar/baz/v3 v3.1.0
+`)
+	mainFile := filepath.Join(tmpDir, "main.go")
+	createGoFile(t, mainFile, `package main
+
+import (
+	_ "example.com/foo/v2/api"
+	_ "github.com/bar/baz/v3"
+)
+
+func main() {}
+`)
+	createNestedPackage(t, tmpDir, "api", "package api\n\nimport _ \"example.com/foo/v2/api/v2\"\n")
+	createNestedPackage(t, tmpDir, "api/v2", "package v2\n")
+
+	graph, err := analyzer.New().AnalyzeFromFile(mainFile, false, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "example.com/foo/v2", graph.EntryPackage)
+	assert.Equal(t, "foo", graph.EntryName, "The major version suffix is not the package name")
+	assert.Equal(t, "foo", graph.Packages["example.com/foo/v2"].Name)
+	assert.Equal(t, "baz", graph.Packages["github.com/bar/baz/v3"].Name)
+
+	api := graph.Packages["example.com/foo/v2/api"]
+	require.NotNil(t, api)
+	assert.Equal(t, filepath.Join(tmpDir, "api"), api.Dir, "Packages resolve below the module root, not a v2 directory")
+	assert.Equal(t, 1, api.FileCount)
+	assert.Equal(t, "api", api.Name)
+
+	apiV2 := graph.Packages["example.com/foo/v2/api/v2"]
+	require.NotNil(t, apiV2)
+	assert.Equal(t, filepath.Join(tmpDir, "api", "v2"), apiV2.Dir)
+	assert.Equal(t, "v2", apiV2.Name, "A v2 package directory inside the module keeps its name")
+}
+
+func TestAnalyzeFromFile_NamedReplacements(t *testing.T) {
+	tmpDir, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	appDir := filepath.Join(tmpDir, "app")
+	libDir := filepath.Join(tmpDir, "lib")
+	require.NoError(t, os.MkdirAll(appDir, 0755))
+	require.NoError(t, os.MkdirAll(libDir, 0755))
+
+	createGoFile(t, filepath.Join(appDir, "go.mod"), `module test/app
+
+go 1.21
+
+replace example.com/lib => ../lib
+
+replace (
+	example.com/vendored => ./third_party/vendored
+	github.com/orig/x => github.com/fork/x v1.2.0
+)
+`)
+	mainFile := filepath.Join(appDir, "main.go")
+	createGoFile(t, mainFile, `package main
+
+import (
+	_ "example.com/lib"
+	_ "example.com/vendored"
+	_ "github.com/orig/x/sub"
+)
+
+func main() {}
+`)
+	createGoMod(t, libDir, "example.com/lib")
+	createGoFile(t, filepath.Join(libDir, "lib.go"), "package lib\n\nimport _ \"fmt\"\n")
+	createNestedPackage(t, appDir, "third_party/vendored", "package vendored\n\nimport _ \"fmt\"\n")
+
+	opts := []analyzer.Option{analyzer.WithExternalDepth(1)}
+	graph, err := analyzer.New(opts...).AnalyzeFromFile(mainFile, false, nil)
+	require.NoError(t, err)
+	assert.Empty(t, graph.Packages["example.com/lib"].ReplacedBy, "Replacements should only be named when enabled")
+	assert.Equal(t, []string{"fmt"}, graph.Packages["example.com/lib"].Dependencies)
+
+	opts = append(opts, analyzer.WithNamedReplacements(true))
+	graph, err = analyzer.New(opts...).AnalyzeFromFile(mainFile, false, nil)
+	require.NoError(t, err)
+
+	lib := graph.Packages["example.com/lib"]
+	assert.Equal(t, libDir, lib.ReplacedBy)
+	assert.Equal(t, "example.com/lib", lib.Module)
+	assert.Empty(t, lib.Dependencies, "Replacements outside the module root should be leaves")
+
+	vendored := graph.Packages["example.com/vendored"]
+	assert.Empty(t, vendored.ReplacedBy, "Replacements inside the module root should be analyzed as usual")
+	assert.Equal(t, []string{"fmt"}, vendored.Dependencies)
+
+	fork := graph.Packages["github.com/orig/x/sub"]
+	assert.Equal(t, "github.com/fork/x@v1.2.0", fork.ReplacedBy)
+	assert.Equal(t, "github.com/orig/x", fork.Module)
+}
+
+func TestAnalyzeFromFile_MinExternalFanIn(t *testing.T) {
+	tmpDir := t.TempDir()
+	createGoMod(t, tmpDir, "test/project")
+	createNestedPackage(t, tmpDir, "api", "package api\n\nimport (\n\t\"fmt\"\n\t\"strings\"\n)\n")
+	createNestedPackage(t, tmpDir, "store", "package store\n\nimport \"fmt\"\n")
+	mainFile := filepath.Join(tmpDir, "main.go")
+	createGoFile(t, mainFile, `package main
+
+import (
+	_ "test/project/api"
+	_ "test/project/store"
+)
+
+func main() {}
+`)
+
+	graph, err := analyzer.New(analyzer.WithMinExternalFanIn(2)).AnalyzeFromFile(mainFile, false, nil)
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{"fmt", "test/project", "test/project/api", "test/project/store"},
+		getPackageNames(graph.Packages))
+	assert.Equal(t, []string{"fmt"}, graph.Packages["test/project/api"].Dependencies,
+		"Edges to dropped externals should be removed")
+}
+
+func TestAnalyzeFromFile_DepsRange(t *testing.T) {
+	tmpDir := t.TempDir()
+	createGoMod(t, tmpDir, "test/project")
+	createNestedPackage(t, tmpDir, "util", "package util\n")
+	createNestedPackage(t, tmpDir, "store", "package store\n\nimport _ \"test/project/util\"\n")
+	createNestedPackage(t, tmpDir, "api", `package api
+
+import (
+	_ "fmt"
+	_ "test/project/store"
+	_ "test/project/util"
+)
+`)
+	mainFile := filepath.Join(tmpDir, "main.go")
+	createGoFile(t, mainFile, `package main
+
+import (
+	_ "test/project/api"
+	_ "test/project/store"
+	_ "test/project/util"
+)
+
+func main() {}
+`)
+
+	testCases := []struct {
+		name     string
+		opts     []analyzer.Option
+		expected []string
+	}{
+		{
+			name:     "no range keeps every package",
+			expected: []string{"fmt", "test/project", "test/project/api", "test/project/store", "test/project/util"},
+		},
+		{
+			name:     "minimum drops leaves",
+			opts:     []analyzer.Option{analyzer.WithMinDeps(1)},
+			expected: []string{"fmt", "test/project", "test/project/api", "test/project/store"},
+		},
+		{
+			name:     "maximum drops hubs but keeps the entry",
+			opts:     []analyzer.Option{analyzer.WithMaxDeps(1)},
+			expected: []string{"fmt", "test/project", "test/project/store", "test/project/util"},
+		},
+		{
+			name:     "range",
+			opts:     []analyzer.Option{analyzer.WithMinDeps(1), analyzer.WithMaxDeps(1)},
+			expected: []string{"fmt", "test/project", "test/project/store"},
+		},
+		{
+			name:     "negative maximum removes the limit",
+			opts:     []analyzer.Option{analyzer.WithMaxDeps(1), analyzer.WithMaxDeps(-1)},
+			expected: []string{"fmt", "test/project", "test/project/api", "test/project/store", "test/project/util"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			graph, err := analyzer.New(tc.opts...).AnalyzeFromFile(mainFile, false, nil)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tc.expected, getPackageNames(graph.Packages))
+			for _, pkg := range graph.Packages {
+				for _, dep := range pkg.Dependencies {
+					assert.Contains(t, graph.Packages, dep, "Edges to dropped packages should be removed")
+				}
+			}
+		})
+	}
+}
+
+func TestAnalyzeFromFile_MaxExternalNodes(t *testing.T) {
+	tmpDir := t.TempDir()
+	createGoMod(t, tmpDir, "test/project")
+	createNestedPackage(t, tmpDir, "api", "package api\n\nimport (\n\t_ \"fmt\"\n\t_ \"os\"\n\t_ \"strings\"\n)\n")
+	createNestedPackage(t, tmpDir, "store",
+		"package store\n\nimport (\n\t_ \"fmt\"\n\t_ \"sort\"\n\t_ \"strings\"\n)\n")
+	mainFile := filepath.Join(tmpDir, "main.go")
+	createGoFile(t, mainFile, `package main
+
+import (
+	_ "fmt"
+	_ "test/project/api"
+	_ "test/project/store"
+)
+
+func main() {}
+`)
+
+	graph, err := analyzer.New(analyzer.WithMaxExternalNodes(2)).AnalyzeFromFile(mainFile, false, nil)
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t,
+		[]string{
+			"fmt", "strings", analyzer.CollapsedExternalPackage,
+			"test/project", "test/project/api", "test/project/store",
+		},
+		getPackageNames(graph.Packages), "The most imported externals should be kept")
+	assert.Equal(t, "… and 2 more external", graph.Packages[analyzer.CollapsedExternalPackage].Name)
+	assert.Equal(t, []string{"fmt", "strings", analyzer.CollapsedExternalPackage},
+		graph.Packages["test/project/api"].Dependencies, "Edges to collapsed externals should be redirected")
+	assert.Equal(t, []string{"fmt", "test/project/api", "test/project/store"},
+		graph.Packages["test/project"].Dependencies)
+
+	graph, err = analyzer.New(analyzer.WithMaxExternalNodes(4)).AnalyzeFromFile(mainFile, false, nil)
+	require.NoError(t, err)
+	assert.NotContains(t, graph.Packages, analyzer.CollapsedExternalPackage, "Nothing should collapse under the cap")
+}
+
+func TestAnalyzeFromFile_SymlinkedProject(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Symlinks require elevated privileges on Windows")
+	}
+
+	tmpDir := t.TempDir()
+	realDir := filepath.Join(tmpDir, "real")
+	require.NoError(t, os.MkdirAll(realDir, 0755))
+	createGoMod(t, realDir, "test/project")
+	createNestedPackage(t, realDir, "util", "package util\n")
+	createGoFile(t, filepath.Join(realDir, "main.go"),
+		"package main\n\nimport _ \"test/project/util\"\n\nfunc main() {}\n")
+
+	linkDir := filepath.Join(tmpDir, "link")
+	require.NoError(t, os.Symlink(realDir, linkDir))
+
+	graph, err := analyzer.New().AnalyzeFromFile(filepath.Join(linkDir, "main.go"), true, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "test/project", graph.ModuleName)
+	assert.Equal(t, "test/project", graph.EntryPackage)
+	assert.ElementsMatch(t, []string{"test/project", "test/project/util"}, getPackageNames(graph.Packages))
+}
+
+func TestAnalyzeFromFile_TodoCounts(t *testing.T) {
+	tmpDir := t.TempDir()
+	createGoMod(t, tmpDir, "test/project")
+	mainFile := filepath.Join(tmpDir, "main.go")
+	createGoFile(t, mainFile, `package main
+
+// TODO: split this up
+func main() {
+	/* FIXME: handle errors
+	   TODO: add logging */
+	helper()
+}
+`)
+	createGoFile(t, filepath.Join(tmpDir, "helper.go"),
+		"package main\n\n// helper has no markers.\nfunc helper() {} // TODO inline\n")
+
+	graph, err := analyzer.New().AnalyzeFromFile(mainFile, true, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 0, graph.Packages["test/project"].TodoCount, "TODO counting should be opt-in")
+
+	graph, err = analyzer.New(analyzer.WithTodoCounts(true)).AnalyzeFromFile(mainFile, true, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 4, graph.Packages["test/project"].TodoCount)
+}
+
+func TestAnalyzeFromFile_SkipGenerated(t *testing.T) {
+	tmpDir := t.TempDir()
+	createGoMod(t, tmpDir, "test/project")
+	generatedHeader := "// Code generated by protoc-gen-go. DO NOT EDIT.\n\n"
+	createNestedPackage(t, tmpDir, "pb", generatedHeader+"package pb\n\nimport _ \"test/project/pbruntime\"\n")
+	createNestedPackage(t, tmpDir, "pbruntime", "package pbruntime\n")
+	createNestedPackage(t, tmpDir, "mixed", generatedHeader+"package mixed\n")
+	createGoFile(t, filepath.Join(tmpDir, "mixed", "handwritten.go"), "package mixed\n")
+	mainFile := filepath.Join(tmpDir, "main.go")
+	createGoFile(t, mainFile, `package main
+
+import (
+	_ "test/project/mixed"
+	_ "test/project/pb"
+)
+
+func main() {}
+`)
+
+	graph, err := analyzer.New().AnalyzeFromFile(mainFile, true, nil)
+	require.NoError(t, err)
+	require.Contains(t, graph.Packages, "test/project/pb")
+	assert.True(t, graph.Packages["test/project/pb"].Generated)
+	assert.False(t, graph.Packages["test/project/mixed"].Generated, "Partially generated packages are kept")
+
+	graph, err = analyzer.New(analyzer.WithSkipGenerated(true)).AnalyzeFromFile(mainFile, true, nil)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"test/project", "test/project/mixed"}, getPackageNames(graph.Packages))
+	assert.Equal(t, []string{"test/project/mixed"}, graph.Packages["test/project"].Dependencies)
+}
+
+func TestAnalyzeFromFile_CanonicalExternalPaths(t *testing.T) {
+	tmpDir := t.TempDir()
+	createGoMod(t, tmpDir, "test/project")
+	createNestedPackage(t, tmpDir, "api", "package api\n\nimport _ `github.com/pkg/errors`\n")
+	createGoFile(t, filepath.Join(tmpDir, "api", "raw.go"), "package api\n\nimport _ \"github.com/pkg/errors\"\n")
+	mainFile := filepath.Join(tmpDir, "main.go")
+	createGoFile(t, mainFile,
+		"package main\n\nimport (\n\t_ `github.com/pkg/errors`\n\t_ \"test/project/api\"\n)\n\nfunc main() {}\n")
+
+	graph, err := analyzer.New().AnalyzeFromFile(mainFile, false, nil)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"test/project", "test/project/api", "github.com/pkg/errors"},
+		getPackageNames(graph.Packages), "Raw and interpreted import literals should share one node")
+	assert.Equal(t, map[string]int{"github.com/pkg/errors": 2}, graph.Packages["test/project/api"].ImportCounts)
+}
+
+func TestAnalyzeFromFile_CanonicalInternalPaths(t *testing.T) {
+	tmpDir := t.TempDir()
+	createGoMod(t, tmpDir, "test/project")
+	createNestedPackage(t, tmpDir, "internal/api", "package api\n")
+	mainFile := filepath.Join(tmpDir, "main.go")
+	createGoFile(t, mainFile, `package main
+
+import (
+	_ "test/project/internal/api"
+	_ "test/project/internal/api/"
+	_ "test/project/./internal/api"
+	_ "test/project//internal/./api//"
+)
+
+func main() {}
+`)
+
+	graph, err := analyzer.New().AnalyzeFromFile(mainFile, true, nil)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"test/project", "test/project/internal/api"}, getPackageNames(graph.Packages),
+		"Imports resolving to the same directory should share one node")
+	assert.Equal(t, []string{"test/project/internal/api"}, graph.Packages["test/project"].Dependencies)
+	assert.Equal(t, 1, graph.Packages["test/project"].ImportCounts["test/project/internal/api"])
+}
+
+func TestAnalyzeFromFile_IgnoreDirective(t *testing.T) {
+	tmpDir := t.TempDir()
+	createGoMod(t, tmpDir, "test/project")
+	createNestedPackage(t, tmpDir, "mocks",
+		"//gpa:ignore test doubles only\n\npackage mocks\n\nimport _ \"test/project/fixtures\"\n")
+	createNestedPackage(t, tmpDir, "fixtures", "package fixtures\n")
+	createNestedPackage(t, tmpDir, "late", "package late\n\nfunc f() {}\n\n//gpa:ignore\n")
+	mainFile := filepath.Join(tmpDir, "main.go")
+	createGoFile(t, mainFile, `//gpa:ignore
+
+package main
+
+import (
+	_ "test/project/late"
+	_ "test/project/mocks"
+)
+
+func main() {}
+`)
+
+	graph, err := analyzer.New().AnalyzeFromFile(mainFile, true, nil)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"test/project", "test/project/late"}, getPackageNames(graph.Packages),
+		"Ignored packages and their dependencies should be dropped, but never the entry")
+	assert.Equal(t, []string{"test/project/late"}, graph.Packages["test/project"].Dependencies)
+	assert.False(t, graph.Packages["test/project/late"].Ignored, "Directives after the imports are not seen")
+}
+
+func TestAnalyzeFromFile_ProductionOnly(t *testing.T) {
+	tmpDir := t.TempDir()
+	createGoMod(t, tmpDir, "test/project")
+	createNestedPackage(t, tmpDir, "pb", "// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage pb\n")
+	createNestedPackage(t, tmpDir, "driver", "package driver\n")
+	createNestedPackage(t, tmpDir, "api", "package api\n")
+	createGoFile(t, filepath.Join(tmpDir, "api", "api_test.go"), "package api\n\nimport _ \"test/project/driver\"\n")
+	mainFile := filepath.Join(tmpDir, "main.go")
+	createGoFile(t, mainFile, `package main
+
+import (
+	"test/project/api"
+	"test/project/pb"
+	_ "test/project/driver"
+)
+
+func main() {}
+`)
+
+	graph, err := analyzer.New().AnalyzeFromFile(mainFile, true, nil)
+	require.NoError(t, err)
+	assert.Len(t, graph.Packages, 4)
+
+	opts := []analyzer.Option{analyzer.WithTestFileCounts(true), analyzer.WithProductionOnly(true)}
+	graph, err = analyzer.New(opts...).AnalyzeFromFile(mainFile, true, nil)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"test/project", "test/project/api"}, getPackageNames(graph.Packages))
+	assert.Equal(t, 1, graph.Packages["test/project/api"].FileCount, "Test files should not be counted")
+
+	opts = []analyzer.Option{
+		analyzer.WithSkipGenerated(true), analyzer.WithSkipBlankImports(true), analyzer.WithProductionOnly(false),
+	}
+	graph, err = analyzer.New(opts...).AnalyzeFromFile(mainFile, true, nil)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"test/project", "test/project/api"}, getPackageNames(graph.Packages),
+		"Disabling production only should not undo earlier options")
+}
+
 // TestAnalyzeFromFile_PackagePathHandling tests package path logic through black-box approach.
 func TestAnalyzeFromFile_PackagePathHandling(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -680,7 +1793,47 @@ func TestAnalyzeFromFile_LayerCalculation(t *testing.T) {
 	graph, err := analyzer.AnalyzeFromFile(mainPath, true, nil)
 	require.NoError(t, err, "AnalyzeFromFile failed")
 
-	validateLayerStructure(t, graph)
+	validateLayerStructure(t, graph)
+}
+
+func TestAnalyzeFromFile_RootEntryLayers(t *testing.T) {
+	tmpDir := t.TempDir()
+	createGoMod(t, tmpDir, "test/root")
+	mainFile := filepath.Join(tmpDir, "main.go")
+	createGoFile(t, mainFile, `package main
+
+import (
+	_ "test/root/api"
+	_ "test/root/api/handlers"
+	_ "test/root/internal/store/sql"
+)
+
+func main() {}
+`)
+	createNestedPackage(t, tmpDir, "api", "package api\n\nimport _ \"test/root/api/handlers\"\n")
+	createNestedPackage(t, tmpDir, "api/handlers", "package handlers\n\nimport _ \"test/root/internal/store/sql\"\n")
+	// A subpackage importing the root package back must not push the root out of the top layer
+	createNestedPackage(t, tmpDir, "internal/store/sql", "package sql\n\nimport _ \"test/root\"\n")
+
+	graph, err := analyzer.New().AnalyzeFromFile(mainFile, true, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "test/root", graph.EntryPackage)
+	assert.Equal(t, 0, graph.EntryLayer())
+	assert.Equal(t, [][]string{
+		{"test/root"},
+		{"test/root/api"},
+		{"test/root/api/handlers"},
+		{"test/root/internal/store/sql"},
+	}, graph.Layers)
+
+	// A root package importing itself is equally pinned to the top
+	createGoFile(t, filepath.Join(tmpDir, "self.go"), "package main\n\nimport _ \"test/root\"\n")
+	graph, err = analyzer.New().AnalyzeFromFile(mainFile, true, nil)
+	require.NoError(t, err)
+	assert.Contains(t, graph.Packages["test/root"].Dependencies, "test/root")
+	assert.Equal(t, 0, graph.EntryLayer())
+	assert.Len(t, graph.Layers, 4)
 }
 
 // TestAnalyzeFromFile_WildcardEdgeCases tests edge cases for wildcard pattern matching.
@@ -755,6 +1908,38 @@ func TestAnalyzeFromFile_WildcardEdgeCases(t *testing.T) {
 
 // Helper functions for test project setup
 
+// createZipArchive returns a zip archive of the given files, keyed by slash-separated path.
+func createZipArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for name, content := range files {
+		fileWriter, err := writer.Create(name)
+		require.NoError(t, err)
+		_, err = fileWriter.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+	return buf.Bytes()
+}
+
+// createTarGzArchive returns a gzipped tar archive of the given files, keyed by slash-separated path.
+func createTarGzArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buf)
+	writer := tar.NewWriter(gzipWriter)
+	for name, content := range files {
+		header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}
+		require.NoError(t, writer.WriteHeader(header))
+		_, err := writer.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+	require.NoError(t, gzipWriter.Close())
+	return buf.Bytes()
+}
+
 // createGoMod creates a go.mod file with the specified module name.
 func createGoMod(t *testing.T, dir, moduleName string) {
 	t.Helper()
@@ -1277,3 +2462,997 @@ func TestAnalyzeMultipleEntryPoints_Monorepo(t *testing.T) {
 
 	validateMonorepoResults(t, result)
 }
+
+func TestBuildSyntheticGraph(t *testing.T) {
+	graph := analyzer.BuildSyntheticGraph(20)
+
+	require.Len(t, graph.Packages, 20)
+	assert.Contains(t, graph.Packages, graph.EntryPackage)
+	assert.NotEmpty(t, graph.Layers, "Expected layers to be calculated")
+	assert.Contains(t, graph.Layers[0], graph.EntryPackage, "Entry package should be in the top layer")
+
+	for pkgPath, pkg := range graph.Packages {
+		for _, dep := range pkg.Dependencies {
+			assert.Contains(t, graph.Packages, dep, "Dependency %s of %s should exist", dep, pkgPath)
+		}
+	}
+}
+
+func TestSuggestCycleBreaks(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "test/main",
+		ModuleName:   "test",
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/main": {Name: "main", Path: "test/main", Dependencies: []string{"test/a", "test/x"}},
+			"test/a":    {Name: "a", Path: "test/a", Dependencies: []string{"test/b"}},
+			"test/b":    {Name: "b", Path: "test/b", Dependencies: []string{"test/c"}},
+			"test/c":    {Name: "c", Path: "test/c", Dependencies: []string{"test/a"}},
+			"test/x":    {Name: "x", Path: "test/x", Dependencies: []string{"test/y"}},
+			"test/y":    {Name: "y", Path: "test/y", Dependencies: []string{"test/x"}},
+		},
+	}
+
+	suggestions := analyzer.New().SuggestCycleBreaks(graph)
+
+	assert.Equal(t, []analyzer.Edge{
+		{From: "test/c", To: "test/a"},
+		{From: "test/y", To: "test/x"},
+	}, suggestions)
+}
+
+func TestSuggestCycleBreaks_NoCycles(t *testing.T) {
+	graph := analyzer.BuildSyntheticGraph(10)
+
+	assert.Empty(t, analyzer.New().SuggestCycleBreaks(graph))
+}
+
+func TestWalk(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/main": {Path: "test/main", Dependencies: []string{"test/b", "test/a", "fmt"}},
+			"test/a":    {Path: "test/a", Dependencies: []string{"test/c"}},
+			"test/b":    {Path: "test/b", Dependencies: []string{"test/d", "test/c"}},
+			"test/c":    {Path: "test/c", Dependencies: []string{"test/main"}},
+			"test/d":    {Path: "test/d"},
+		},
+	}
+	walk := func(from, prune string) []string {
+		var visits []string
+		graph.Walk(from, func(pkg *analyzer.PackageInfo, depth int) bool {
+			visits = append(visits, fmt.Sprintf("%s@%d", pkg.Path, depth))
+			return pkg.Path != prune
+		})
+		return visits
+	}
+
+	assert.Equal(t, []string{"test/main@0", "test/a@1", "test/b@1", "test/c@2", "test/d@2"}, walk("test/main", ""),
+		"Packages should be visited once, breadth first in sorted order, despite the cycle")
+	assert.Equal(t, []string{"test/main@0", "test/a@1", "test/b@1", "test/c@2"}, walk("test/main", "test/b"),
+		"Pruning should skip dependencies only reachable through the pruned package")
+	assert.Equal(t, []string{"test/b@0"}, walk("test/b", "test/b"))
+	assert.Empty(t, walk("test/missing", ""))
+}
+
+func TestDiamonds(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/main":      {Path: "test/main", Dependencies: []string{"test/api", "test/worker", "test/store"}},
+			"test/api":       {Path: "test/api", Dependencies: []string{"test/store", "test/log", "fmt"}},
+			"test/worker":    {Path: "test/worker", Dependencies: []string{"test/store", "test/log", "test/worker"}},
+			"test/store":     {Path: "test/store", Dependencies: []string{"test/log", "test/main"}},
+			"test/log":       {Path: "test/log"},
+			"test/unrelated": {Path: "test/unrelated", Dependencies: []string{"test/log"}},
+		},
+	}
+
+	diamonds := analyzer.New().Diamonds(graph)
+	assert.Equal(t, [][]string{
+		{"test/api", "test/store", "test/log"},
+		// The paths through api, worker and store first meet at store, not again at log below it
+		{"test/main", "test/api", "test/worker", "test/store"},
+		// store reaches log through main, which is not the top of this diamond
+		{"test/store", "test/main", "test/log"},
+		{"test/worker", "test/store", "test/log"},
+	}, diamonds, "Self-imports, cycles back to the top and single paths should not form diamonds")
+
+	assert.Empty(t, analyzer.New().Diamonds(analyzer.BuildSyntheticGraph(2)))
+}
+
+func TestDiamonds_UnequalPathLengths(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/main":    {Path: "test/main", Dependencies: []string{"test/api", "test/cli"}},
+			"test/api":     {Path: "test/api", Dependencies: []string{"test/handler"}},
+			"test/handler": {Path: "test/handler", Dependencies: []string{"test/store"}},
+			"test/cli":     {Path: "test/cli", Dependencies: []string{"test/store"}},
+			"test/store":   {Path: "test/store", Dependencies: []string{"test/log"}},
+			"test/log":     {Path: "test/log"},
+		},
+	}
+
+	assert.Equal(t, [][]string{
+		{"test/main", "test/api", "test/cli", "test/store"},
+	}, analyzer.New().Diamonds(graph), "Paths of different lengths should reconverge once, above their shared tail")
+}
+
+func TestDiamonds_DirectImport(t *testing.T) {
+	triangle := &analyzer.DependencyGraph{
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/a": {Path: "test/a", Dependencies: []string{"test/b", "test/c"}},
+			"test/b": {Path: "test/b", Dependencies: []string{"test/c"}},
+			"test/c": {Path: "test/c"},
+		},
+	}
+	assert.Equal(t, [][]string{{"test/a", "test/b", "test/c"}}, analyzer.New().Diamonds(triangle),
+		"A direct import should count as one of the paths")
+
+	tail := &analyzer.DependencyGraph{
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/a": {Path: "test/a", Dependencies: []string{"test/b", "test/c"}},
+			"test/b": {Path: "test/b", Dependencies: []string{"test/c"}},
+			"test/c": {Path: "test/c", Dependencies: []string{"test/d"}},
+			"test/d": {Path: "test/d"},
+		},
+	}
+	assert.Equal(t, [][]string{{"test/a", "test/b", "test/c"}}, analyzer.New().Diamonds(tail),
+		"Only the first meeting point should be reported, not the packages below it")
+}
+
+func TestAllPaths(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/main":   {Path: "test/main", Dependencies: []string{"test/worker", "test/api", "test/log"}},
+			"test/api":    {Path: "test/api", Dependencies: []string{"test/store", "test/log", "test/log", "fmt"}},
+			"test/worker": {Path: "test/worker", Dependencies: []string{"test/cli", "test/store", "test/main"}},
+			"test/store":  {Path: "test/store", Dependencies: []string{"test/log", "test/api"}},
+			"test/log":    {Path: "test/log"},
+			"test/cli":    {Path: "test/cli", Dependencies: []string{"test/unrelated"}},
+		},
+	}
+	a := analyzer.New()
+
+	assert.Equal(t, [][]string{
+		{"test/main", "test/api", "test/log"},
+		{"test/main", "test/api", "test/store", "test/log"},
+		{"test/main", "test/log"},
+		{"test/main", "test/worker", "test/store", "test/api", "test/log"},
+		{"test/main", "test/worker", "test/store", "test/log"},
+	}, a.AllPaths(graph, "test/main", "test/log", 10),
+		"Every path should be found once, without revisiting packages on cycles")
+	assert.Equal(t, [][]string{
+		{"test/main", "test/api", "test/log"},
+		{"test/main", "test/api", "test/store", "test/log"},
+	}, a.AllPaths(graph, "test/main", "test/log", 2))
+
+	assert.Equal(t, [][]string{{"test/store", "test/api"}}, a.AllPaths(graph, "test/store", "test/api", 10))
+	assert.Equal(t, [][]string{{"test/log"}}, a.AllPaths(graph, "test/log", "test/log", 10))
+	assert.Empty(t, a.AllPaths(graph, "test/log", "test/main", 10))
+	assert.Empty(t, a.AllPaths(graph, "test/main", "test/missing", 10))
+	assert.Empty(t, a.AllPaths(graph, "test/main", "test/log", 0))
+}
+
+func TestFocusAndDependents(t *testing.T) {
+	graph := analyzer.BuildSyntheticGraph(7)
+	a := analyzer.New()
+
+	focused, err := a.Focus(graph, "example.com/synthetic/pkg2")
+	require.NoError(t, err)
+	assert.Equal(t, "example.com/synthetic/pkg2", focused.EntryPackage)
+	assert.Equal(t, "pkg2", focused.EntryName)
+	assert.ElementsMatch(t, []string{
+		"example.com/synthetic/pkg2",
+		"example.com/synthetic/pkg3",
+		"example.com/synthetic/pkg4",
+		"example.com/synthetic/pkg5",
+		"example.com/synthetic/pkg6",
+	}, getPackageNames(focused.Packages))
+	assert.Equal(t, []string{"example.com/synthetic/pkg2"}, focused.Layers[0])
+
+	dependents, err := a.Dependents(graph, "example.com/synthetic/pkg2")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"example.com/synthetic", "example.com/synthetic/pkg1"}, dependents)
+
+	_, err = a.Focus(graph, "example.com/missing")
+	require.Error(t, err)
+	_, err = a.Dependents(graph, "example.com/missing")
+	require.Error(t, err)
+}
+
+func TestReverse(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "test/main",
+		ModuleName:   "test",
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/main": {
+				Name: "main", Path: "test/main", Dependencies: []string{"test/api", "test/store", "fmt"},
+				ImportCounts: map[string]int{"test/api": 2, "test/store": 1, "fmt": 1},
+			},
+			"test/api": {
+				Name: "api", Path: "test/api", Dependencies: []string{"test/store"},
+				ImportCounts: map[string]int{"test/store": 3},
+			},
+			"test/store": {Name: "store", Path: "test/store", ImportCounts: map[string]int{}},
+		},
+	}
+	a := analyzer.New()
+
+	reversed := a.Reverse(graph)
+	assert.Empty(t, reversed.EntryPackage)
+	assert.Equal(t, "test", reversed.ModuleName)
+	assert.Equal(t, []string{"test/api", "test/main"}, reversed.Packages["test/store"].Dependencies)
+	assert.Equal(t, []string{"test/main"}, reversed.Packages["test/api"].Dependencies)
+	assert.Empty(t, reversed.Packages["test/main"].Dependencies, "Imports of packages outside the graph are dropped")
+	assert.Equal(t, map[string]int{"test/api": 3, "test/main": 1}, reversed.Packages["test/store"].ImportCounts)
+	assert.Equal(t, [][]string{{"test/store"}, {"test/api"}, {"test/main"}}, reversed.Layers)
+
+	assert.Equal(t, []string{"test/api", "test/store", "fmt"}, graph.Packages["test/main"].Dependencies,
+		"The original graph should be left alone")
+	assert.Equal(t, 2, graph.Packages["test/main"].ImportCounts["test/api"])
+
+	cyclic := &analyzer.DependencyGraph{
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/a": {Name: "a", Path: "test/a", Dependencies: []string{"test/b"}},
+			"test/b": {Name: "b", Path: "test/b", Dependencies: []string{"test/a"}},
+		},
+		ModuleName: "test",
+	}
+	reversed = a.Reverse(cyclic)
+	require.Len(t, reversed.Cycles, 1)
+	assert.Equal(t, []string{"test/a", "test/b"}, reversed.Cycles[0].Packages)
+}
+
+func TestUpwardDependencies(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "test",
+		ModuleName:   "test",
+		Packages: map[string]*analyzer.PackageInfo{
+			"test":         {Name: "test", Path: "test", Dependencies: []string{"test/a/b/c"}},
+			"test/a":       {Name: "a", Path: "test/a", Dependencies: []string{}},
+			"test/a/b":     {Name: "b", Path: "test/a/b", Dependencies: []string{"test/ab"}},
+			"test/a/b/c":   {Name: "c", Path: "test/a/b/c", Dependencies: []string{"test/a/b", "test/a", "test/a/bc"}},
+			"test/a/bc":    {Name: "bc", Path: "test/a/bc", Dependencies: []string{}},
+			"test/ab":      {Name: "ab", Path: "test/ab", Dependencies: []string{"test"}},
+			"test/unknown": {Name: "unknown", Path: "test/unknown", Dependencies: []string{"missing"}},
+		},
+	}
+
+	edges := analyzer.New().UpwardDependencies(graph)
+
+	assert.Equal(t, [][2]string{
+		{"test/a/b/c", "test/a"},
+		{"test/a/b/c", "test/a/b"},
+		{"test/ab", "test"},
+	}, edges)
+}
+
+func TestCrossModuleEdges(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "example.com/api",
+		ModuleName:   "example.com/api",
+		Packages: map[string]*analyzer.PackageInfo{
+			"example.com/api": {
+				Module:       "example.com/api",
+				Dependencies: []string{"example.com/api/handlers", "example.com/shared/auth", "fmt"},
+			},
+			"example.com/api/handlers": {
+				Module:       "example.com/api",
+				Dependencies: []string{"example.com/shared/db", "example.com/missing"},
+			},
+			"example.com/shared/auth": {Module: "example.com/shared", Dependencies: []string{"example.com/shared/db"}},
+			"example.com/shared/db":   {Module: "example.com/shared", Dependencies: []string{"database/sql"}},
+			"database/sql":            {},
+			"fmt":                     {},
+		},
+	}
+
+	edges := analyzer.New().CrossModuleEdges(graph)
+
+	assert.Equal(t, [][2]string{
+		{"example.com/api", "example.com/shared/auth"},
+		{"example.com/api/handlers", "example.com/shared/db"},
+	}, edges)
+}
+
+func TestAnalyzeFromFile_ImportCounts(t *testing.T) {
+	tmpDir := t.TempDir()
+	createGoMod(t, tmpDir, "test/project")
+	createNestedPackage(t, tmpDir, "util", "package util\n")
+	createGoFile(t, filepath.Join(tmpDir, "main.go"),
+		"package main\n\nimport (\n\t\"fmt\"\n\t_ \"test/project/util\"\n)\n\nfunc main() { fmt.Println() }\n")
+	createGoFile(t, filepath.Join(tmpDir, "extra.go"), "package main\n\nimport _ \"test/project/util\"\n")
+
+	graph, err := analyzer.New().AnalyzeFromFile(filepath.Join(tmpDir, "main.go"), false, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]int{"fmt": 1, "test/project/util": 2}, graph.Packages["test/project"].ImportCounts)
+}
+
+func TestDependencyGraph_DepthAndEntryLayer(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "test/main",
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/main": {Path: "test/main", Layer: 0},
+			"test/a":    {Path: "test/a", Layer: 1},
+			"test/b":    {Path: "test/b", Layer: 2},
+		},
+		Layers: [][]string{{"test/main"}, {"test/a"}, {"test/b"}},
+	}
+
+	assert.Equal(t, 3, graph.Depth())
+	assert.Equal(t, 0, graph.EntryLayer())
+
+	graph.EntryPackage = "test/missing"
+	assert.Equal(t, -1, graph.EntryLayer())
+	assert.Equal(t, 0, (&analyzer.DependencyGraph{}).Depth())
+}
+
+func TestValidateEntry(t *testing.T) {
+	tmpDir, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	appDir := filepath.Join(tmpDir, "services", "app")
+	require.NoError(t, os.MkdirAll(appDir, 0755))
+	createGoMod(t, appDir, "example.com/app")
+	mainFile := filepath.Join(appDir, "main.go")
+	createGoFile(t, mainFile, "package main\n\nfunc main() {}\n")
+
+	validation, err := analyzer.New().ValidateEntry(mainFile)
+	require.NoError(t, err)
+	assert.Equal(t, &analyzer.EntryValidation{ModuleName: "example.com/app", ModuleRoot: appDir}, validation)
+
+	goWork := filepath.Join(tmpDir, "go.work")
+	createGoFile(t, goWork, "go 1.21\n\nuse (\n\t./services/app // the app\n\t./services/lib\n)\n")
+	validation, err = analyzer.New().ValidateEntry(mainFile)
+	require.NoError(t, err)
+	assert.Equal(t, goWork, validation.Workspace)
+
+	createGoFile(t, goWork, "go 1.21\n\nuse ./services/lib\n")
+	validation, err = analyzer.New().ValidateEntry(mainFile)
+	require.NoError(t, err)
+	assert.Empty(t, validation.Workspace, "A workspace not using the module should not be reported")
+
+	_, err = analyzer.New().ValidateEntry(filepath.Join(appDir, "missing.go"))
+	require.ErrorIs(t, err, analyzer.ErrEntryNotFound)
+
+	_, err = analyzer.New().ValidateEntry(filepath.Join(appDir, "go.mod"))
+	require.ErrorIs(t, err, analyzer.ErrNotGoFile)
+
+	looseFile := filepath.Join(t.TempDir(), "main.go")
+	createGoFile(t, looseFile, "package main\n\nfunc main() {}\n")
+	_, err = analyzer.New().ValidateEntry(looseFile)
+	require.ErrorIs(t, err, analyzer.ErrNoModule)
+}
+
+func TestDependencyGraph_Hash(t *testing.T) {
+	newGraph := func() *analyzer.DependencyGraph {
+		return &analyzer.DependencyGraph{
+			EntryPackage: "test/main",
+			ModuleName:   "test",
+			Packages: map[string]*analyzer.PackageInfo{
+				"test/main": {Path: "test/main", Dependencies: []string{"test/b", "test/a"}, FileCount: 1},
+				"test/a":    {Path: "test/a", Dependencies: []string{"test/b"}, FileCount: 2},
+				"test/b":    {Path: "test/b", Dependencies: []string{}, FileCount: 3},
+			},
+		}
+	}
+
+	graph := newGraph()
+	hash := graph.Hash()
+	assert.Len(t, hash, 64)
+
+	reordered := newGraph()
+	reordered.Packages["test/main"].Dependencies = []string{"test/a", "test/b"}
+	reordered.Packages["test/a"].FileCount = 20
+	reordered.Layers = [][]string{{"test/main"}, {"test/a"}, {"test/b"}}
+	assert.Equal(t, hash, reordered.Hash(), "Dependency order, counts and layers should not affect the hash")
+	assert.NotEqual(t, graph.Hash(analyzer.HashWithCounts()), reordered.Hash(analyzer.HashWithCounts()))
+
+	changed := newGraph()
+	changed.Packages["test/b"].Dependencies = []string{"test/a"}
+	assert.NotEqual(t, hash, changed.Hash(), "A new edge should change the hash")
+
+	renamed := newGraph()
+	renamed.ModuleName = "other"
+	assert.NotEqual(t, hash, renamed.Hash(), "The module name should be part of the hash")
+}
+
+func TestRelativePath(t *testing.T) {
+	testCases := []struct {
+		name       string
+		pkgPath    string
+		moduleName string
+		expected   string
+	}{
+		{name: "root package", pkgPath: "example.com/app", moduleName: "example.com/app", expected: ""},
+		{
+			name:       "nested package",
+			pkgPath:    "example.com/app/internal/api",
+			moduleName: "example.com/app",
+			expected:   "internal/api",
+		},
+		{
+			name:       "windows separators",
+			pkgPath:    "example.com\\app\\internal\\api",
+			moduleName: "example.com/app",
+			expected:   "internal/api",
+		},
+		{name: "trailing slashes", pkgPath: "example.com/app/api/", moduleName: "example.com/app/", expected: "api"},
+		{name: "root with trailing slash", pkgPath: "example.com/app/", moduleName: "example.com/app", expected: ""},
+		{name: "external package", pkgPath: "fmt", moduleName: "example.com/app", expected: "fmt"},
+		{
+			name:       "sibling module prefix",
+			pkgPath:    "example.com/application/api",
+			moduleName: "example.com/app",
+			expected:   "example.com/application/api",
+		},
+		{name: "no module", pkgPath: "example.com/app/api", moduleName: "", expected: "example.com/app/api"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, analyzer.RelativePath(tc.pkgPath, tc.moduleName))
+		})
+	}
+}
+
+func TestDependencyGraph_Components(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/cmd/a":  {Path: "test/cmd/a", Dependencies: []string{"test/shared", "missing"}},
+			"test/cmd/b":  {Path: "test/cmd/b", Dependencies: []string{"test/shared"}},
+			"test/tool":   {Path: "test/tool", Dependencies: []string{"test/z"}},
+			"test/z":      {Path: "test/z", Dependencies: []string{"test/tool"}},
+			"test/shared": {Path: "test/shared"},
+			"test/alone":  {Path: "test/alone"},
+		},
+	}
+
+	assert.Equal(t, [][]string{
+		{"test/alone"},
+		{"test/cmd/a", "test/cmd/b", "test/shared"},
+		{"test/tool", "test/z"},
+	}, graph.Components())
+	assert.Empty(t, (&analyzer.DependencyGraph{}).Components())
+}
+
+func TestComputeMetrics(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "test/main",
+		ModuleName:   "test",
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/main": {Path: "test/main", Dependencies: []string{"test/a", "test/b"}, FileCount: 1, LineCount: 10},
+			"test/a":    {Path: "test/a", Dependencies: []string{"test/b", "missing"}, FileCount: 2, LineCount: 20},
+			"test/b":    {Path: "test/b", Module: "test", Dependencies: []string{}, FileCount: 3, LineCount: 30},
+		},
+		Layers: [][]string{{"test/main"}, {"test/a"}, {"test/b"}},
+	}
+
+	metrics := analyzer.New().ComputeMetrics(graph)
+
+	assert.Equal(t, 3, metrics.TotalPackages)
+	assert.Equal(t, 3, metrics.TotalEdges, "Edges to packages outside the graph should not count")
+	assert.Equal(t, 6, metrics.TotalFiles)
+	assert.Equal(t, 60, metrics.TotalLines)
+	assert.Equal(t, 3, metrics.LayerCount)
+	assert.Equal(t, []analyzer.PackageMetrics{
+		{Path: "test/a", FanIn: 1, FanOut: 1, Instability: 0.5, FileCount: 2, LineCount: 20},
+		{Path: "test/b", Module: "test", FanIn: 2, FanOut: 0, Instability: 0, FileCount: 3, LineCount: 30},
+		{Path: "test/main", FanIn: 0, FanOut: 2, Instability: 1, FileCount: 1, LineCount: 10},
+	}, metrics.Packages)
+}
+
+func TestAnalyzeFromFile_MaxFileSize(t *testing.T) {
+	tmpDir := t.TempDir()
+	createGoMod(t, tmpDir, "test/project")
+	createNestedPackage(t, tmpDir, "api", "package api\n\nimport _ \"fmt\"\n")
+	createGoFile(t, filepath.Join(tmpDir, "api", "table.go"),
+		"package api\n\nimport _ \"strings\"\n\nvar table = `"+strings.Repeat("x", 512)+"`\n")
+	mainFile := filepath.Join(tmpDir, "main.go")
+	createGoFile(t, mainFile, "package main\n\nimport _ \"test/project/api\"\n\nfunc main() {}\n")
+
+	graph, err := analyzer.New().AnalyzeFromFile(mainFile, false, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"fmt", "strings"}, graph.Packages["test/project/api"].Dependencies)
+	assert.True(t, graph.Packages["test/project/api"].Complete)
+
+	graph, err = analyzer.New(analyzer.WithMaxFileSize(256)).AnalyzeFromFile(mainFile, false, nil)
+	require.NoError(t, err)
+	api := graph.Packages["test/project/api"]
+	assert.Equal(t, []string{"fmt"}, api.Dependencies, "Imports of oversized files should be skipped")
+	assert.Equal(t, 2, api.FileCount)
+	assert.False(t, api.Complete)
+}
+
+func TestAllImports(t *testing.T) {
+	tmpDir := t.TempDir()
+	createGoMod(t, tmpDir, "test/project")
+	mainFile := filepath.Join(tmpDir, "main.go")
+	createGoFile(t, mainFile,
+		"package main\n\nimport (\n\t\"fmt\"\n\t_ \"test/project/api\"\n)\n\nfunc main() { fmt.Println() }\n")
+	createNestedPackage(t, tmpDir, "api", "//gpa:ignore\n\npackage api\n\nimport _ \"net/http/pprof\"\n")
+	createNestedPackage(t, tmpDir, "unused", "package unused\n\nimport `strings`\n")
+	createNestedPackage(t, tmpDir, "vendor/example.com/lib", "package lib\n\nimport \"os\"\n")
+	createNestedPackage(t, tmpDir, "tools/gen", "package gen\n\nimport \"text/template\"\n")
+	createGoMod(t, filepath.Join(tmpDir, "tools"), "test/tools")
+	createGoFile(t, filepath.Join(tmpDir, "main_test.go"), "package main\n\nimport \"testing\"\n")
+
+	a := analyzer.New(analyzer.WithSkipBlankImports(true))
+	imports, err := a.AllImports(mainFile)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"fmt", "net/http/pprof", "strings", "test/project/api"}, imports)
+}
+
+func TestAnalyzeFromFile_ExportedCounts(t *testing.T) {
+	tmpDir := t.TempDir()
+	createGoMod(t, tmpDir, "test/project")
+	createNestedPackage(t, tmpDir, "api", `package api
+
+type Server struct{}
+
+func (Server) Serve() {}
+
+func New() Server { return Server{} }
+
+const (
+	Version, build = "1", "dev"
+)
+
+var handlers int
+`)
+	mainFile := filepath.Join(tmpDir, "main.go")
+	createGoFile(t, mainFile, "package main\n\nimport _ \"test/project/api\"\n\nfunc main() {}\n")
+
+	graph, err := analyzer.New().AnalyzeFromFile(mainFile, true, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 0, graph.Packages["test/project/api"].ExportedCount, "Exported counting should be opt-in")
+
+	graph, err = analyzer.New(analyzer.WithExportedCounts(true)).AnalyzeFromFile(mainFile, true, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, graph.Packages["test/project/api"].ExportedCount)
+}
+
+func TestAnalyzeFromFile_Complexity(t *testing.T) {
+	tmpDir := t.TempDir()
+	createGoMod(t, tmpDir, "test/project")
+	createNestedPackage(t, tmpDir, "api", `package api
+
+type Server struct{}
+
+func Check(a, b int) bool {
+	if a > 0 && b > 0 {
+		return true
+	}
+	for i := range a {
+		switch i {
+		case 1, 2:
+		default:
+		}
+	}
+	return a < 0 || b < 0
+}
+
+func (Server) Run(ch chan int) {
+	go func() {
+		select {
+		case <-ch:
+		default:
+		}
+	}()
+}
+`)
+	mainFile := filepath.Join(tmpDir, "main.go")
+	createGoFile(t, mainFile, "package main\n\nimport _ \"test/project/api\"\n\nfunc main() {}\n")
+
+	graph, err := analyzer.New().AnalyzeFromFile(mainFile, true, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 0, graph.Packages["test/project/api"].Complexity, "Complexity should be opt-in")
+
+	a := analyzer.New(analyzer.WithComplexity(true))
+	graph, err = a.AnalyzeFromFile(mainFile, true, nil)
+	require.NoError(t, err)
+	// Check: 1 + if + && + range + case + ||; Run: 1 + select case in the function literal
+	assert.Equal(t, 8, graph.Packages["test/project/api"].Complexity)
+	assert.Equal(t, 1, graph.Packages["test/project"].Complexity)
+	assert.Equal(t, 9, a.ComputeMetrics(graph).TotalComplexity)
+}
+
+func TestAnalyzeFromFile_Files(t *testing.T) {
+	tmpDir := t.TempDir()
+	createGoMod(t, tmpDir, "test/project")
+	apiFile := createNestedPackage(t, tmpDir, "api", "package api\n\nfunc Serve() {}\n")
+	helperFile := filepath.Join(filepath.Dir(apiFile), "helper.go")
+	createGoFile(t, helperFile, "package api\n")
+	createGoFile(t, filepath.Join(filepath.Dir(apiFile), "api_test.go"), "package api\n")
+	mainFile := filepath.Join(tmpDir, "main.go")
+	createGoFile(t, mainFile, "package main\n\nimport _ \"test/project/api\"\n\nfunc main() {}\n")
+
+	graph, err := analyzer.New().AnalyzeFromFile(mainFile, true, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []analyzer.SourceFile{
+		{Path: apiFile},
+		{Path: helperFile},
+	}, graph.Packages["test/project/api"].Files, "Test files should be left out")
+}
+
+func TestAnalyzeFromFile_HideInternal(t *testing.T) {
+	tmpDir := t.TempDir()
+	createGoMod(t, tmpDir, "test/lib")
+	createNestedPackage(t, tmpDir, "api",
+		"package api\n\nimport (\n\t_ \"test/lib/api/internal/wire\"\n\t_ \"test/lib/internal/codec\"\n)\n")
+	createNestedPackage(t, tmpDir, "api/internal/wire", "package wire\n")
+	createNestedPackage(t, tmpDir, "internal/codec", "package codec\n\nimport _ \"test/lib/encoding\"\n")
+	createNestedPackage(t, tmpDir, "encoding", "package encoding\n")
+	createNestedPackage(t, tmpDir, "internals", "package internals\n")
+	mainFile := filepath.Join(tmpDir, "main.go")
+	createGoFile(t, mainFile,
+		"package main\n\nimport (\n\t_ \"test/lib/api\"\n\t_ \"test/lib/internals\"\n)\n\nfunc main() {}\n")
+
+	graph, err := analyzer.New(analyzer.WithHideInternal(true)).AnalyzeFromFile(mainFile, true, nil)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"test/lib", "test/lib/api", "test/lib/internals"}, getPackageNames(graph.Packages),
+		"Packages below internal/ should be hidden, along with what only they import")
+
+	entryFile := filepath.Join(tmpDir, "internal", "codec", "codec.go")
+	graph, err = analyzer.New(analyzer.WithHideInternal(true)).AnalyzeFromFile(entryFile, true, nil)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"test/lib/encoding", "test/lib/internal/codec"}, getPackageNames(graph.Packages),
+		"An internal entry package should be kept")
+}
+
+func TestAnalyzeFromFile_EmbedCounts(t *testing.T) {
+	tmpDir := t.TempDir()
+	createGoMod(t, tmpDir, "test/project")
+	createNestedPackage(t, tmpDir, "assets", `package assets
+
+import "embed"
+
+// Templates holds the HTML templates.
+//
+//go:embed templates/*.html
+var Templates embed.FS
+
+//go:embed logo.svg
+var Logo []byte
+
+// Not a directive: //go:embed in a trailing comment
+`)
+	createNestedPackage(t, tmpDir, "empty", "package empty\n")
+	mainFile := filepath.Join(tmpDir, "main.go")
+	createGoFile(t, mainFile,
+		"package main\n\nimport (\n\t_ \"test/project/assets\"\n\t_ \"test/project/empty\"\n)\n\nfunc main() {}\n")
+
+	graph, err := analyzer.New().AnalyzeFromFile(mainFile, true, nil)
+	require.NoError(t, err)
+	assert.False(t, graph.Packages["test/project/assets"].HasEmbeds, "Embed counting should be opt-in")
+
+	graph, err = analyzer.New(analyzer.WithEmbedCounts(true)).AnalyzeFromFile(mainFile, true, nil)
+	require.NoError(t, err)
+	assets := graph.Packages["test/project/assets"]
+	assert.True(t, assets.HasEmbeds)
+	assert.Equal(t, 2, assets.EmbedCount)
+	assert.False(t, graph.Packages["test/project/empty"].HasEmbeds)
+	assert.Equal(t, 0, graph.Packages["test/project/empty"].EmbedCount)
+}
+
+func TestAnalyzeFromFile_UnusedImportCheck(t *testing.T) {
+	tmpDir := t.TempDir()
+	createGoMod(t, tmpDir, "test/project")
+	for _, pkg := range []string{"api", "config", "store", "plugins"} {
+		createNestedPackage(t, tmpDir, pkg, "package "+pkg+"\n")
+	}
+	mainFile := filepath.Join(tmpDir, "main.go")
+	createGoFile(t, mainFile, `package main
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+	"test/project/api"
+	cfg "test/project/config"
+	_ "test/project/plugins"
+	"test/project/store"
+)
+
+func main() {
+	store := yaml.Node{}
+	fmt.Println(api.Version, store)
+}
+`)
+
+	graph, err := analyzer.New().AnalyzeFromFile(mainFile, false, nil)
+	require.NoError(t, err)
+	assert.Contains(t, graph.Packages["test/project"].Dependencies, "test/project/store",
+		"Unused imports should be kept unless the check is enabled")
+
+	graph, err = analyzer.New(analyzer.WithUnusedImportCheck(true)).AnalyzeFromFile(mainFile, false, nil)
+	require.NoError(t, err)
+	assert.ElementsMatch(t,
+		[]string{"fmt", "gopkg.in/yaml.v3", "test/project/api", "test/project/plugins"},
+		graph.Packages["test/project"].Dependencies,
+		"Unused imports, named or not, should be dropped while blank imports are kept")
+	assert.NotContains(t, graph.Packages, "test/project/config")
+	assert.NotContains(t, graph.Packages, "os")
+}
+
+func TestAnalyzeFromFile_UnusedImportCheckUnknownPackageName(t *testing.T) {
+	tmpDir := t.TempDir()
+	createGoMod(t, tmpDir, "test/project")
+	createNestedPackage(t, tmpDir, "golang-lru", "package lru\n")
+	mainFile := filepath.Join(tmpDir, "main.go")
+	createGoFile(t, mainFile, `package main
+
+import "test/project/golang-lru"
+
+func main() {
+	_ = lru.New
+}
+`)
+
+	graph, err := analyzer.New(analyzer.WithUnusedImportCheck(true)).AnalyzeFromFile(mainFile, true, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"test/project/golang-lru"}, graph.Packages["test/project"].Dependencies,
+		"An import whose package name differs from its path should be kept when a qualifier may refer to it")
+}
+
+func TestAnalyzeFromFile_BuildTarget(t *testing.T) {
+	tmpDir := t.TempDir()
+	createGoMod(t, tmpDir, "test/project")
+	createNestedPackage(t, tmpDir, "winsvc",
+		"//go:build windows\n\npackage winsvc\n\nimport _ \"test/project/registry\"\n")
+	createNestedPackage(t, tmpDir, "registry", "package registry\n")
+	createNestedPackage(t, tmpDir, "term", "package term\n")
+	createGoFile(t, filepath.Join(tmpDir, "term", "term_darwin.go"), "package term\n\nimport _ \"os\"\n")
+	mainFile := filepath.Join(tmpDir, "main.go")
+	createGoFile(t, mainFile,
+		"package main\n\nimport (\n\t_ \"test/project/term\"\n\t_ \"test/project/winsvc\"\n)\n\nfunc main() {}\n")
+
+	graph, err := analyzer.New().AnalyzeFromFile(mainFile, false, nil)
+	require.NoError(t, err)
+	assert.False(t, graph.Packages["test/project/winsvc"].EmptyForTarget, "Constraints should be ignored by default")
+	assert.Contains(t, graph.Packages, "test/project/registry")
+	assert.Equal(t, 2, graph.Packages["test/project/term"].FileCount)
+
+	graph, err = analyzer.New(analyzer.WithBuildTarget("linux", "amd64")).AnalyzeFromFile(mainFile, false, nil)
+	require.NoError(t, err)
+	winsvc := graph.Packages["test/project/winsvc"]
+	assert.True(t, winsvc.EmptyForTarget)
+	assert.Equal(t, 0, winsvc.FileCount)
+	assert.Empty(t, winsvc.Dependencies)
+	assert.NotContains(t, graph.Packages, "test/project/registry", "Imports of excluded files should add no edges")
+	term := graph.Packages["test/project/term"]
+	assert.False(t, term.EmptyForTarget)
+	assert.Equal(t, 1, term.FileCount, "Files with another GOOS suffix should be skipped")
+	assert.NotContains(t, graph.Packages, "os")
+
+	graph, err = analyzer.New(analyzer.WithBuildTarget("windows", "")).AnalyzeFromFile(mainFile, false, nil)
+	require.NoError(t, err)
+	assert.False(t, graph.Packages["test/project/winsvc"].EmptyForTarget)
+	assert.Contains(t, graph.Packages, "test/project/registry")
+}
+
+func TestAnalyzeFromFile_BuildTags(t *testing.T) {
+	tmpDir := t.TempDir()
+	createGoMod(t, tmpDir, "test/project")
+	createNestedPackage(t, tmpDir, "store", "package store\n")
+	createGoFile(t, filepath.Join(tmpDir, "store", "store_integration.go"),
+		"//go:build integration\n\npackage store\n\nimport _ \"test/project/fixtures\"\n")
+	createGoFile(t, filepath.Join(tmpDir, "store", "store_fake.go"),
+		"//go:build !integration\n\npackage store\n\nimport _ \"test/project/fake\"\n")
+	createNestedPackage(t, tmpDir, "fixtures", "package fixtures\n")
+	createNestedPackage(t, tmpDir, "fake", "package fake\n")
+	mainFile := filepath.Join(tmpDir, "main.go")
+	createGoFile(t, mainFile, "package main\n\nimport _ \"test/project/store\"\n\nfunc main() {}\n")
+
+	graph, err := analyzer.New().AnalyzeFromFile(mainFile, true, nil)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"test/project/fake", "test/project/fixtures"},
+		graph.Packages["test/project/store"].Dependencies, "Constraints should be ignored by default")
+
+	graph, err = analyzer.New(analyzer.WithBuildTags([]string{"integration"})).AnalyzeFromFile(mainFile, true, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"test/project/fixtures"}, graph.Packages["test/project/store"].Dependencies)
+	assert.Equal(t, 2, graph.Packages["test/project/store"].FileCount)
+	assert.NotContains(t, graph.Packages, "test/project/fake")
+
+	// The tags survive a build target set afterwards, and the target survives tags set afterwards
+	for _, opts := range [][]analyzer.Option{
+		{analyzer.WithBuildTags([]string{"integration"}), analyzer.WithBuildTarget("windows", "")},
+		{analyzer.WithBuildTarget("windows", ""), analyzer.WithBuildTags([]string{"integration"})},
+	} {
+		graph, err = analyzer.New(opts...).AnalyzeFromFile(mainFile, true, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"test/project/fixtures"}, graph.Packages["test/project/store"].Dependencies)
+	}
+
+	graph, err = analyzer.New(analyzer.WithBuildTags(nil)).AnalyzeFromFile(mainFile, true, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"test/project/fake"}, graph.Packages["test/project/store"].Dependencies,
+		"Without the tag, files guarded by it should be skipped")
+}
+
+func TestAnalyzeFromFile_TestFileCounts(t *testing.T) {
+	tmpDir := t.TempDir()
+	createGoMod(t, tmpDir, "test/project")
+	createNestedPackage(t, tmpDir, "api", "package api\n")
+	for _, name := range []string{"api_test.go", "server_test.go"} {
+		createGoFile(t, filepath.Join(tmpDir, "api", name), "package api\n\nimport _ \"test/project/mocks\"\n")
+	}
+	mainFile := filepath.Join(tmpDir, "main.go")
+	createGoFile(t, mainFile, "package main\n\nimport _ \"test/project/api\"\n\nfunc main() {}\n")
+
+	graph, err := analyzer.New().AnalyzeFromFile(mainFile, true, nil)
+	require.NoError(t, err)
+	api := graph.Packages["test/project/api"]
+	assert.Equal(t, 1, api.FileCount, "Test files should not count by default")
+	assert.Equal(t, 2, api.TestFileCount)
+
+	graph, err = analyzer.New(analyzer.WithTestFileCounts(true)).AnalyzeFromFile(mainFile, true, nil)
+	require.NoError(t, err)
+	api = graph.Packages["test/project/api"]
+	assert.Equal(t, 3, api.FileCount)
+	assert.Equal(t, 2, api.TestFileCount)
+	assert.Empty(t, api.Dependencies, "Test file imports should never add edges")
+}
+
+func TestUnexportedOnlyPackages(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "test/main",
+		ModuleName:   "test",
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/main":    {Path: "test/main", Dependencies: []string{"test/api", "test/driver", "embed"}},
+			"test/api":     {Path: "test/api", Dependencies: []string{"testing/glue"}, ExportedCount: 2},
+			"test/driver":  {Path: "test/driver", Dependencies: []string{}},
+			"test/orphan":  {Path: "test/orphan", Dependencies: []string{}},
+			"testing/glue": {Path: "testing/glue", Dependencies: []string{}},
+			"embed":        {Path: "embed", Dependencies: []string{}},
+		},
+	}
+
+	packages := analyzer.New().UnexportedOnlyPackages(graph)
+
+	assert.Equal(t, []string{"test/driver"}, packages,
+		"Entry, unimported and external packages should not be flagged")
+}
+
+func TestCheckManifest(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "test/main",
+		ModuleName:   "test",
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/main":  {Path: "test/main", Dependencies: []string{"test/store", "test/api"}},
+			"test/api":   {Path: "test/api", Dependencies: []string{"test/store", "fmt"}},
+			"test/store": {Path: "test/store", Dependencies: []string{"database/sql"}},
+		},
+	}
+	manifest := map[string][]string{
+		"test/main":  {"test/api"},
+		"test/store": {"database/sql"},
+	}
+
+	violations := analyzer.New().CheckManifest(graph, manifest)
+
+	assert.Equal(t, []analyzer.Violation{
+		{Package: "test/main", Dependency: "test/store"},
+	}, violations, "Packages without a manifest entry should be unconstrained")
+}
+
+func TestFindCycles(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "test/main",
+		ModuleName:   "test",
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/main": {Path: "test/main", Dependencies: []string{"test/c", "test/x"}},
+			"test/a":    {Path: "test/a", Dependencies: []string{"test/b"}},
+			"test/b":    {Path: "test/b", Dependencies: []string{"test/c"}},
+			"test/c":    {Path: "test/c", Dependencies: []string{"test/a"}},
+			"test/x":    {Path: "test/x", Dependencies: []string{"test/y"}},
+			"test/y":    {Path: "test/y", Dependencies: []string{"test/x"}},
+		},
+	}
+
+	for range 5 {
+		cycles := analyzer.New().FindCycles(graph)
+
+		assert.Equal(t, []analyzer.Cycle{
+			{
+				Packages: []string{"test/a", "test/b", "test/c"},
+				Edges:    [][2]string{{"test/a", "test/b"}, {"test/b", "test/c"}, {"test/c", "test/a"}},
+			},
+			{
+				Packages: []string{"test/x", "test/y"},
+				Edges:    [][2]string{{"test/x", "test/y"}, {"test/y", "test/x"}},
+			},
+		}, cycles)
+	}
+}
+
+func TestAnalyzeFromFile_RecordsOptions(t *testing.T) {
+	tmpDir := t.TempDir()
+	mainFile := setupProjectWithGoMod(t, tmpDir, "test/project")
+	a := analyzer.New()
+
+	graph, err := a.AnalyzeFromFile(mainFile, true, nil)
+	require.NoError(t, err)
+	assert.False(t, graph.IncludedExternal)
+	assert.Empty(t, graph.ExcludedDirs)
+
+	excludeDirs := []string{"testdata", "gen/*"}
+	graph, err = a.AnalyzeFromFile(mainFile, false, excludeDirs)
+	require.NoError(t, err)
+	assert.True(t, graph.IncludedExternal)
+	assert.Equal(t, []string{"testdata", "gen/*"}, graph.ExcludedDirs)
+
+	excludeDirs[0] = "changed"
+	assert.Equal(t, "testdata", graph.ExcludedDirs[0], "The graph should keep its own copy of the patterns")
+
+	focused, err := a.Focus(graph, graph.EntryPackage)
+	require.NoError(t, err)
+	assert.True(t, focused.IncludedExternal, "Derived graphs should keep the options")
+	assert.Equal(t, graph.ExcludedDirs, focused.ExcludedDirs)
+}
+
+func TestAnalyzeFromFile_StoresCycles(t *testing.T) {
+	tmpDir := t.TempDir()
+	createGoMod(t, tmpDir, "test/project")
+	createNestedPackage(t, tmpDir, "a", "package a\n\nimport _ \"test/project/b\"\n")
+	createNestedPackage(t, tmpDir, "b", "package b\n\nimport _ \"test/project/a\"\n")
+	mainFile := filepath.Join(tmpDir, "main.go")
+	createGoFile(t, mainFile, "package main\n\nimport _ \"test/project/a\"\n\nfunc main() {}\n")
+
+	graph, err := analyzer.New().AnalyzeFromFile(mainFile, true, nil)
+	require.NoError(t, err)
+
+	require.Len(t, graph.Cycles, 1)
+	assert.Equal(t, []string{"test/project/a", "test/project/b"}, graph.Cycles[0].Packages)
+}
+
+func TestAnalyzeFromFile_ImportedEntry(t *testing.T) {
+	tmpDir := t.TempDir()
+	createGoMod(t, tmpDir, "test/project")
+	mainFile := createNestedPackage(t, tmpDir, "tool",
+		"package tool\n\nimport _ \"test/project/shared\"\n\nfunc main() {}\n")
+	createNestedPackage(t, tmpDir, "shared", "package shared\n")
+
+	graph, err := analyzer.New().AnalyzeFromFile(mainFile, true, nil)
+	require.NoError(t, err)
+	assert.False(t, graph.Packages["test/project/tool"].IsImportedEntry, "An entry nothing imports is not flagged")
+
+	// The shared package imports the entry back, as a build-tagged helper might
+	createGoFile(t, filepath.Join(tmpDir, "shared", "shared.go"),
+		"package shared\n\nimport _ \"test/project/tool\"\n")
+	graph, err = analyzer.New().AnalyzeFromFile(mainFile, true, nil)
+	require.NoError(t, err)
+
+	assert.True(t, graph.Packages["test/project/tool"].IsImportedEntry)
+	assert.False(t, graph.Packages["test/project/shared"].IsImportedEntry, "Only the entry package is flagged")
+	assert.Equal(t, 0, graph.EntryLayer(), "The imported entry should stay in the top layer")
+}
+
+func TestDetectCommunities(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "test/a1",
+		ModuleName:   "test",
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/a1":   {Path: "test/a1", Dependencies: []string{"test/a2", "test/a3", "test/b1"}},
+			"test/a2":   {Path: "test/a2", Dependencies: []string{"test/a3"}},
+			"test/a3":   {Path: "test/a3", Dependencies: []string{}},
+			"test/b1":   {Path: "test/b1", Dependencies: []string{"test/b2", "test/b3"}},
+			"test/b2":   {Path: "test/b2", Dependencies: []string{"test/b3"}},
+			"test/b3":   {Path: "test/b3", Dependencies: []string{}},
+			"test/lone": {Path: "test/lone", Dependencies: []string{}},
+		},
+	}
+
+	communities := analyzer.New().DetectCommunities(graph)
+
+	assert.Equal(t, map[string]int{
+		"test/a1":   0,
+		"test/a2":   0,
+		"test/a3":   0,
+		"test/b1":   1,
+		"test/b2":   1,
+		"test/b3":   1,
+		"test/lone": 2,
+	}, communities)
+	assert.Equal(t, communities, analyzer.New().DetectCommunities(graph), "Detection should be deterministic")
+}
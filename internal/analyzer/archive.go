@@ -0,0 +1,165 @@
+package analyzer
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Archive formats accepted by AnalyzeArchive.
+const (
+	ArchiveZip   = "zip"
+	ArchiveTarGz = "tar.gz"
+)
+
+// maxArchiveExtractedBytes bounds how much AnalyzeArchive extracts, guarding against archive bombs.
+const maxArchiveExtractedBytes = 1 << 30
+
+// archiveDirMode is the mode of directories created while extracting an archive.
+const archiveDirMode = 0o755
+
+// Errors returned by AnalyzeArchive, for use with errors.Is.
+var (
+	ErrUnsupportedArchive = errors.New("unsupported archive format")
+	ErrArchiveEntry       = errors.New("invalid archive entry") // Escapes the archive root or exceeds the size limit
+)
+
+// AnalyzeArchive extracts a zip or gzipped tar archive of a project to a temporary directory,
+// analyzes it with AnalyzeMultipleEntryPoints and removes the directory again. When the
+// archive holds a single top-level directory, as source downloads usually do, that directory
+// is the repository root. Symlinks and other special entries are skipped.
+//
+// Absolute paths in the result, such as RepoRoot and each entry point's Path, refer to the
+// removed extraction directory; relative paths and package paths are unaffected.
+func (a *Analyzer) AnalyzeArchive(
+	r io.ReaderAt,
+	size int64,
+	format string,
+	excludeExternal bool,
+	excludeDirs []string,
+) (*MultiEntryAnalysisResult, error) {
+	tmpDir, err := os.MkdirTemp("", "gpa-archive-*")
+	if err != nil {
+		return nil, fmt.Errorf("creating extraction directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	extractor := &archiveExtractor{dir: tmpDir, remaining: maxArchiveExtractedBytes}
+	switch format {
+	case ArchiveZip:
+		err = extractor.extractZip(r, size)
+	case ArchiveTarGz:
+		err = extractor.extractTarGz(io.NewSectionReader(r, 0, size))
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedArchive, format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("extracting archive: %w", err)
+	}
+
+	return a.AnalyzeMultipleEntryPoints(archiveRoot(tmpDir), excludeExternal, excludeDirs)
+}
+
+// archiveExtractor writes archive entries below dir, within a budget of remaining bytes.
+type archiveExtractor struct {
+	dir       string
+	remaining int64
+}
+
+// extractZip extracts the directories and regular files of a zip archive.
+func (e *archiveExtractor) extractZip(r io.ReaderAt, size int64) error {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+	for _, file := range archive.File {
+		mode := file.Mode()
+		if !mode.IsDir() && !mode.IsRegular() {
+			continue
+		}
+		if entryErr := e.extractEntry(file.Name, mode.IsDir(), file.Open); entryErr != nil {
+			return entryErr
+		}
+	}
+	return nil
+}
+
+// extractTarGz extracts the directories and regular files of a gzipped tar archive.
+func (e *archiveExtractor) extractTarGz(r io.Reader) error {
+	gzipReader, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gzipReader.Close()
+
+	archive := tar.NewReader(gzipReader)
+	for {
+		header, nextErr := archive.Next()
+		if errors.Is(nextErr, io.EOF) {
+			return nil
+		}
+		if nextErr != nil {
+			return nextErr
+		}
+		if header.Typeflag != tar.TypeDir && header.Typeflag != tar.TypeReg {
+			continue
+		}
+		open := func() (io.ReadCloser, error) { return io.NopCloser(archive), nil }
+		if entryErr := e.extractEntry(header.Name, header.Typeflag == tar.TypeDir, open); entryErr != nil {
+			return entryErr
+		}
+	}
+}
+
+// extractEntry creates the directory or writes the file an archive entry describes,
+// rejecting names that would land outside the extraction directory.
+func (e *archiveExtractor) extractEntry(name string, isDir bool, open func() (io.ReadCloser, error)) error {
+	relPath := filepath.FromSlash(name)
+	if !filepath.IsLocal(relPath) {
+		return fmt.Errorf("%w: %s escapes the archive root", ErrArchiveEntry, name)
+	}
+	target := filepath.Join(e.dir, relPath)
+	if isDir {
+		return os.MkdirAll(target, archiveDirMode)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), archiveDirMode); err != nil {
+		return err
+	}
+
+	src, err := open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	// Copy one byte past the budget to tell an exact fit from an overflow
+	written, err := io.CopyN(dst, src, e.remaining+1)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	if written > e.remaining {
+		return fmt.Errorf("%w: extracted size exceeds %d bytes", ErrArchiveEntry, int64(maxArchiveExtractedBytes))
+	}
+	e.remaining -= written
+	return nil
+}
+
+// archiveRoot returns the single top-level directory of an extracted archive, or dir itself
+// when the archive has files at the top level or several top-level directories.
+func archiveRoot(dir string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 || !entries[0].IsDir() {
+		return dir
+	}
+	return filepath.Join(dir, entries[0].Name())
+}
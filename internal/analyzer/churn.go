@@ -0,0 +1,86 @@
+package analyzer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// addChurn sets each analyzed package's Churn from the git history under the module root:
+// the number of commits within the churn window touching one of the Go files in the package's
+// directory. Outside a git repository, or without git, churn is left at zero with a warning.
+func (a *Analyzer) addChurn(graph *DependencyGraph) {
+	commits, err := a.gitCommitFiles(time.Now().Add(-a.churnWindow))
+	if err != nil {
+		slog.Warn("Warning: churn unavailable, git history could not be read",
+			"moduleRoot", a.moduleRoot, "error", err)
+		return
+	}
+
+	byDir := make(map[string]*PackageInfo)
+	for _, pkg := range graph.Packages {
+		if pkg.Dir != "" {
+			byDir[filepath.Clean(pkg.Dir)] = pkg
+		}
+	}
+
+	for _, files := range commits {
+		// A commit counts once per package, however many of its files it touches
+		touched := make(map[*PackageInfo]bool)
+		for _, file := range files {
+			if !strings.HasSuffix(file, ".go") {
+				continue
+			}
+			if pkg, exists := byDir[filepath.Join(a.moduleRoot, filepath.Dir(filepath.FromSlash(file)))]; exists {
+				touched[pkg] = true
+			}
+		}
+		for pkg := range touched {
+			pkg.Churn++
+		}
+	}
+}
+
+// gitCommitFiles lists, for each commit since the given time, the files it touched below the
+// module root, relative to the module root.
+func (a *Analyzer) gitCommitFiles(since time.Time) ([][]string, error) {
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(gitPath, "log", "--since="+since.Format(time.RFC3339),
+		"--pretty=format:", "--name-only", "--relative", "--", ".")
+	cmd.Dir = a.moduleRoot
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if runErr := cmd.Run(); runErr != nil {
+		return nil, fmt.Errorf("running git log: %w: %s", runErr, strings.TrimSpace(stderr.String()))
+	}
+
+	// With an empty format, each commit's files form a block separated by blank lines
+	var commits [][]string
+	var files []string
+	lines := bufio.NewScanner(&stdout)
+	for lines.Scan() {
+		line := strings.TrimSpace(lines.Text())
+		if line == "" {
+			if len(files) > 0 {
+				commits = append(commits, files)
+			}
+			files = nil
+			continue
+		}
+		files = append(files, line)
+	}
+	if len(files) > 0 {
+		commits = append(commits, files)
+	}
+	return commits, lines.Err()
+}
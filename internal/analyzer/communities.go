@@ -0,0 +1,120 @@
+package analyzer
+
+import (
+	"sort"
+)
+
+// maxCommunityPasses bounds the local-moving passes of community detection.
+const maxCommunityPasses = 100
+
+// DetectCommunities assigns each package a community ID by greedy modularity optimisation
+// (the local-moving phase of the Louvain method) on the undirected dependency graph.
+// Packages are visited in sorted order and IDs are numbered by first appearance in that
+// order, so the result is deterministic. Packages without edges form their own community.
+func (a *Analyzer) DetectCommunities(graph *DependencyGraph) map[string]int {
+	nodes := sortedPackagePaths(graph)
+	index := make(map[string]int, len(nodes))
+	for i, pkgPath := range nodes {
+		index[pkgPath] = i
+	}
+
+	// Build the undirected, weighted adjacency; an import in each direction counts twice
+	weights := make([]map[int]float64, len(nodes))
+	degree := make([]float64, len(nodes))
+	totalWeight := 0.0
+	for i := range nodes {
+		weights[i] = make(map[int]float64)
+	}
+	for i, pkgPath := range nodes {
+		for _, dep := range sortedGraphDependencies(graph, pkgPath) {
+			j := index[dep]
+			if i == j {
+				continue
+			}
+			weights[i][j]++
+			weights[j][i]++
+			degree[i]++
+			degree[j]++
+			totalWeight += 2
+		}
+	}
+
+	community := make([]int, len(nodes))
+	communityDegree := make([]float64, len(nodes))
+	for i := range nodes {
+		community[i] = i
+		communityDegree[i] = degree[i]
+	}
+
+	if totalWeight > 0 {
+		for range maxCommunityPasses {
+			if !moveNodesToBestCommunity(weights, degree, totalWeight, community, communityDegree) {
+				break
+			}
+		}
+	}
+
+	// Renumber communities by first appearance in sorted package order
+	renumbered := make(map[int]int)
+	communities := make(map[string]int, len(nodes))
+	for i, pkgPath := range nodes {
+		id, exists := renumbered[community[i]]
+		if !exists {
+			id = len(renumbered)
+			renumbered[community[i]] = id
+		}
+		communities[pkgPath] = id
+	}
+
+	return communities
+}
+
+// moveNodesToBestCommunity performs one local-moving pass, moving each node to the
+// neighbouring community with the largest modularity gain. It reports whether any node moved.
+func moveNodesToBestCommunity(
+	weights []map[int]float64,
+	degree []float64,
+	totalWeight float64,
+	community []int,
+	communityDegree []float64,
+) bool {
+	moved := false
+
+	for i := range community {
+		if degree[i] == 0 {
+			continue
+		}
+
+		current := community[i]
+		communityDegree[current] -= degree[i]
+
+		// Sum edge weights from this node into each neighbouring community
+		linksTo := map[int]float64{current: 0}
+		for j, weight := range weights[i] {
+			linksTo[community[j]] += weight
+		}
+		candidates := make([]int, 0, len(linksTo))
+		for c := range linksTo {
+			candidates = append(candidates, c)
+		}
+		sort.Ints(candidates)
+
+		best := current
+		bestGain := linksTo[current] - communityDegree[current]*degree[i]/totalWeight
+		for _, c := range candidates {
+			gain := linksTo[c] - communityDegree[c]*degree[i]/totalWeight
+			if gain > bestGain {
+				best = c
+				bestGain = gain
+			}
+		}
+
+		community[i] = best
+		communityDegree[best] += degree[i]
+		if best != current {
+			moved = true
+		}
+	}
+
+	return moved
+}
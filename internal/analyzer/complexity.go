@@ -0,0 +1,41 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// fileComplexity sums the cyclomatic complexity of the functions declared in a file. Each
+// function starts at 1 and adds one per if, for and range statement, non-default case or
+// select clause, and && or || operator. Function literals count toward the function that
+// contains them.
+func fileComplexity(file *ast.File) int {
+	total := 0
+	for _, decl := range file.Decls {
+		funcDecl, ok := decl.(*ast.FuncDecl)
+		if !ok || funcDecl.Body == nil {
+			continue
+		}
+		total++
+		ast.Inspect(funcDecl.Body, func(node ast.Node) bool {
+			switch node := node.(type) {
+			case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt:
+				total++
+			case *ast.CaseClause:
+				if node.List != nil {
+					total++
+				}
+			case *ast.CommClause:
+				if node.Comm != nil {
+					total++
+				}
+			case *ast.BinaryExpr:
+				if node.Op == token.LAND || node.Op == token.LOR {
+					total++
+				}
+			}
+			return true
+		})
+	}
+	return total
+}
@@ -0,0 +1,42 @@
+package analyzer
+
+import (
+	"sort"
+)
+
+// Components returns the weakly connected components of the graph: groups of packages linked
+// by imports in either direction. Each component is sorted and components are ordered by their
+// first package path. Dependencies on packages missing from the graph are ignored.
+func (g *DependencyGraph) Components() [][]string {
+	neighbors := make(map[string][]string, len(g.Packages))
+	for _, pkgPath := range sortedPackagePaths(g) {
+		for _, dep := range sortedGraphDependencies(g, pkgPath) {
+			neighbors[pkgPath] = append(neighbors[pkgPath], dep)
+			neighbors[dep] = append(neighbors[dep], pkgPath)
+		}
+	}
+
+	seen := make(map[string]bool, len(g.Packages))
+	var components [][]string
+	for _, pkgPath := range sortedPackagePaths(g) {
+		if seen[pkgPath] {
+			continue
+		}
+
+		// Packages are visited in sorted order, so pkgPath is the component's smallest path
+		seen[pkgPath] = true
+		component := []string{pkgPath}
+		for i := 0; i < len(component); i++ {
+			for _, neighbor := range neighbors[component[i]] {
+				if !seen[neighbor] {
+					seen[neighbor] = true
+					component = append(component, neighbor)
+				}
+			}
+		}
+		sort.Strings(component)
+		components = append(components, component)
+	}
+
+	return components
+}
@@ -0,0 +1,237 @@
+package analyzer
+
+import (
+	"sort"
+	"strings"
+)
+
+// Edge represents a dependency from one package to another.
+type Edge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// SuggestCycleBreaks returns, for each import cycle, an edge whose removal breaks it.
+// Cycles are grouped by strongly connected component; within each component a DFS starting
+// from the lexically smallest package reports the first back-edge it encounters.
+func (a *Analyzer) SuggestCycleBreaks(graph *DependencyGraph) []Edge {
+	var suggestions []Edge
+
+	for _, component := range a.StronglyConnectedComponents(graph) {
+		if edge, found := a.findBackEdge(graph, component); found {
+			suggestions = append(suggestions, edge)
+		}
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].From != suggestions[j].From {
+			return suggestions[i].From < suggestions[j].From
+		}
+		return suggestions[i].To < suggestions[j].To
+	})
+
+	return suggestions
+}
+
+// StronglyConnectedComponents returns the components that contain a cycle, using Tarjan's
+// algorithm: groups of packages that all import each other, directly or transitively. Each
+// component is sorted, components are ordered by their first package, and single packages are
+// only included if they import themselves.
+func (a *Analyzer) StronglyConnectedComponents(graph *DependencyGraph) [][]string {
+	state := &tarjanState{
+		graph:   graph,
+		index:   make(map[string]int),
+		lowLink: make(map[string]int),
+		onStack: make(map[string]bool),
+	}
+
+	for _, pkgPath := range sortedPackagePaths(graph) {
+		if _, visited := state.index[pkgPath]; !visited {
+			state.strongConnect(pkgPath)
+		}
+	}
+
+	var components [][]string
+	for _, component := range state.components {
+		if len(component) == 1 && !dependsOn(graph.Packages[component[0]], component[0]) {
+			continue
+		}
+		sort.Strings(component)
+		components = append(components, component)
+	}
+
+	sort.Slice(components, func(i, j int) bool {
+		return components[i][0] < components[j][0]
+	})
+
+	return components
+}
+
+// tarjanState holds the bookkeeping for Tarjan's strongly connected components algorithm.
+type tarjanState struct {
+	graph      *DependencyGraph
+	counter    int
+	index      map[string]int
+	lowLink    map[string]int
+	onStack    map[string]bool
+	stack      []string
+	components [][]string
+}
+
+// strongConnect visits a package and records any component rooted at it.
+func (s *tarjanState) strongConnect(pkgPath string) {
+	s.index[pkgPath] = s.counter
+	s.lowLink[pkgPath] = s.counter
+	s.counter++
+	s.stack = append(s.stack, pkgPath)
+	s.onStack[pkgPath] = true
+
+	for _, dep := range sortedGraphDependencies(s.graph, pkgPath) {
+		if _, visited := s.index[dep]; !visited {
+			s.strongConnect(dep)
+			s.lowLink[pkgPath] = min(s.lowLink[pkgPath], s.lowLink[dep])
+		} else if s.onStack[dep] {
+			s.lowLink[pkgPath] = min(s.lowLink[pkgPath], s.index[dep])
+		}
+	}
+
+	if s.lowLink[pkgPath] != s.index[pkgPath] {
+		return
+	}
+
+	var component []string
+	for {
+		top := s.stack[len(s.stack)-1]
+		s.stack = s.stack[:len(s.stack)-1]
+		s.onStack[top] = false
+		component = append(component, top)
+		if top == pkgPath {
+			break
+		}
+	}
+	s.components = append(s.components, component)
+}
+
+// findBackEdge performs a DFS restricted to a component and returns the first back-edge found.
+func (a *Analyzer) findBackEdge(graph *DependencyGraph, component []string) (Edge, bool) {
+	inComponent := make(map[string]bool, len(component))
+	for _, pkgPath := range component {
+		inComponent[pkgPath] = true
+	}
+
+	visited := make(map[string]bool)
+	onPath := make(map[string]bool)
+
+	var visit func(pkgPath string) (Edge, bool)
+	visit = func(pkgPath string) (Edge, bool) {
+		visited[pkgPath] = true
+		onPath[pkgPath] = true
+		defer func() { onPath[pkgPath] = false }()
+
+		for _, dep := range sortedGraphDependencies(graph, pkgPath) {
+			if !inComponent[dep] {
+				continue
+			}
+			if onPath[dep] {
+				return Edge{From: pkgPath, To: dep}, true
+			}
+			if !visited[dep] {
+				if edge, found := visit(dep); found {
+					return edge, true
+				}
+			}
+		}
+		return Edge{}, false
+	}
+
+	return visit(component[0])
+}
+
+// sortedPackagePaths returns the graph's package paths in sorted order.
+func sortedPackagePaths(graph *DependencyGraph) []string {
+	packagePaths := make([]string, 0, len(graph.Packages))
+	for pkgPath := range graph.Packages {
+		packagePaths = append(packagePaths, pkgPath)
+	}
+	sort.Strings(packagePaths)
+	return packagePaths
+}
+
+// sortedGraphDependencies returns a package's dependencies that exist in the graph, in sorted order.
+func sortedGraphDependencies(graph *DependencyGraph, pkgPath string) []string {
+	pkg := graph.Packages[pkgPath]
+	if pkg == nil {
+		return nil
+	}
+
+	deps := make([]string, 0, len(pkg.Dependencies))
+	for _, dep := range pkg.Dependencies {
+		if _, exists := graph.Packages[dep]; exists {
+			deps = append(deps, dep)
+		}
+	}
+	sort.Strings(deps)
+	return deps
+}
+
+// dependsOn checks whether a package lists the given dependency.
+func dependsOn(pkg *PackageInfo, dep string) bool {
+	if pkg == nil {
+		return false
+	}
+	for _, d := range pkg.Dependencies {
+		if d == dep {
+			return true
+		}
+	}
+	return false
+}
+
+// Cycle is an import cycle: its packages in import order and the edges connecting them.
+type Cycle struct {
+	Packages []string    `json:"packages"` // Starts at the lexically smallest member
+	Edges    [][2]string `json:"edges"`    // Consecutive [from, to] pairs, closing back to the first package
+}
+
+// FindCycles returns the distinct import cycles found by a DFS over the graph.
+// Each cycle is rotated to start at its smallest package and cycles are sorted,
+// so the result is deterministic for a given graph.
+func (a *Analyzer) FindCycles(graph *DependencyGraph) []Cycle {
+	seen := make(map[string]bool)
+	cycles := []Cycle{}
+
+	for _, members := range a.findAllCycles(graph) {
+		members = rotateToSmallest(members)
+		key := strings.Join(members, "\x00")
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		edges := make([][2]string, len(members))
+		for i := range members {
+			edges[i] = [2]string{members[i], members[(i+1)%len(members)]}
+		}
+		cycles = append(cycles, Cycle{Packages: members, Edges: edges})
+	}
+
+	sort.Slice(cycles, func(i, j int) bool {
+		return strings.Join(cycles[i].Packages, "\x00") < strings.Join(cycles[j].Packages, "\x00")
+	})
+
+	return cycles
+}
+
+// rotateToSmallest returns a copy of a cycle starting at its lexically smallest member.
+func rotateToSmallest(members []string) []string {
+	start := 0
+	for i, member := range members {
+		if member < members[start] {
+			start = i
+		}
+	}
+
+	rotated := make([]string, 0, len(members))
+	rotated = append(rotated, members[start:]...)
+	return append(rotated, members[:start]...)
+}
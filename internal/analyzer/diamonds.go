@@ -0,0 +1,99 @@
+package analyzer
+
+import (
+	"slices"
+	"sort"
+)
+
+// Diamonds finds diamond dependencies: a package importing two or more packages whose paths
+// first meet at a common package, as in A→B→D and A→C→D or, with paths of different lengths,
+// A→B→X→D and A→C→D. A direct import counts as a path of its own, so A→B→C with A→C is the
+// triangle [A B C]. Each diamond lists the top package, the packages it imports that lead to
+// the meeting point in sorted order and the meeting point itself, such as [A B C D]; a bottom
+// package shared by many diamonds is a central join point. Only the first meeting point of a
+// set of paths is reported, not the packages below it that the same paths reach through it,
+// and paths do not pass back through the top package. Diamonds are ordered by top, then
+// bottom package, and only packages in the graph are considered.
+func (a *Analyzer) Diamonds(graph *DependencyGraph) [][]string {
+	var diamonds [][]string
+	for _, top := range sortedPackagePaths(graph) {
+		// Packages reachable from top, each with the imports of top leading to it in sorted
+		// order; an import leads to itself, which makes the direct import one of the paths
+		between := make(map[string][]string)
+		for _, middle := range slices.Compact(sortedGraphDependencies(graph, top)) {
+			if middle == top {
+				continue
+			}
+			for _, bottom := range reachableAvoiding(graph, middle, top) {
+				between[bottom] = append(between[bottom], middle)
+			}
+		}
+
+		var meetings []string
+		for bottom, middles := range between {
+			if len(middles) > 1 {
+				meetings = append(meetings, bottom)
+			}
+		}
+		sort.Strings(meetings)
+
+		// Packages below each meeting point, which the paths into it reach through it
+		below := make(map[string]map[string]bool, len(meetings))
+		for _, meeting := range meetings {
+			below[meeting] = make(map[string]bool)
+			for _, pkgPath := range reachableAvoiding(graph, meeting, top)[1:] {
+				below[meeting][pkgPath] = true
+			}
+		}
+
+		for _, bottom := range meetings {
+			if meetsEarlier(bottom, meetings, between, below) {
+				continue
+			}
+			diamond := []string{top}
+			for _, middle := range between[bottom] {
+				if middle != bottom {
+					diamond = append(diamond, middle)
+				}
+			}
+			diamonds = append(diamonds, append(diamond, bottom))
+		}
+	}
+	return diamonds
+}
+
+// meetsEarlier reports whether the paths meeting at bottom already met at another meeting
+// point, one joining the same imports of the top package that leads on to bottom. When two
+// such meeting points lead to each other through a cycle, the first in sorted order is kept.
+func meetsEarlier(
+	bottom string,
+	meetings []string,
+	between map[string][]string,
+	below map[string]map[string]bool,
+) bool {
+	for _, meeting := range meetings {
+		if meeting == bottom || !below[meeting][bottom] || !slices.Equal(between[meeting], between[bottom]) {
+			continue
+		}
+		if !below[bottom][meeting] || meeting < bottom {
+			return true
+		}
+	}
+	return false
+}
+
+// reachableAvoiding returns the packages reachable from the package from, itself included,
+// without passing through the package avoid.
+func reachableAvoiding(graph *DependencyGraph, from, avoid string) []string {
+	seen := map[string]bool{from: true, avoid: true}
+	reachable := []string{from}
+	for i := 0; i < len(reachable); i++ {
+		for _, dep := range sortedGraphDependencies(graph, reachable[i]) {
+			if !seen[dep] {
+				seen[dep] = true
+				reachable = append(reachable, dep)
+			}
+		}
+	}
+	return reachable
+}
@@ -0,0 +1,181 @@
+package analyzer
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+)
+
+// CollapsedExternalPackage is the path of the node standing in for the external packages
+// collapsed by WithMaxExternalNodes. Its name reads "… and N more external".
+const CollapsedExternalPackage = "…"
+
+// applyFilters removes packages that the configured filters exclude from an analyzed graph.
+// It runs before layers are calculated, so removed packages never affect the layout.
+func (a *Analyzer) applyFilters(graph *DependencyGraph) {
+	removePackages(graph, nonEntryPackages(graph, func(pkg *PackageInfo) bool {
+		return pkg.Ignored || (a.skipGenerated && pkg.Generated)
+	}))
+	if a.minDeps > 0 || a.maxDeps >= 0 {
+		removePackages(graph, nonEntryPackages(graph, func(pkg *PackageInfo) bool {
+			return a.isInternalPackage(pkg.Path) && !a.withinDepsRange(a.internalDependencyCount(pkg))
+		}))
+	}
+	if a.minExternalFanIn > 0 {
+		removePackages(graph, a.lowFanInExternals(graph))
+	}
+	if a.maxExternalNodes > 0 {
+		a.collapseExternals(graph)
+	}
+	if a.reachableOnly {
+		unreachable := unreachablePackages(graph)
+		if len(unreachable) > 0 {
+			slog.Warn("Warning: pruned packages unreachable from the entry package",
+				"entry", graph.EntryPackage,
+				"count", len(unreachable))
+		}
+		removePackages(graph, unreachable)
+	}
+}
+
+// nonEntryPackages returns every package except the entry package for which match returns true.
+func nonEntryPackages(graph *DependencyGraph, match func(*PackageInfo) bool) map[string]bool {
+	remove := make(map[string]bool)
+	for pkgPath, pkg := range graph.Packages {
+		if match(pkg) && pkgPath != graph.EntryPackage {
+			remove[pkgPath] = true
+		}
+	}
+	return remove
+}
+
+// internalDependencyCount returns the number of internal packages a package imports.
+func (a *Analyzer) internalDependencyCount(pkg *PackageInfo) int {
+	count := 0
+	for _, dep := range pkg.Dependencies {
+		if a.isInternalPackage(dep) {
+			count++
+		}
+	}
+	return count
+}
+
+// withinDepsRange reports whether an internal dependency count lies within the range set by
+// WithMinDeps and WithMaxDeps.
+func (a *Analyzer) withinDepsRange(count int) bool {
+	return count >= a.minDeps && (a.maxDeps < 0 || count <= a.maxDeps)
+}
+
+// lowFanInExternals returns the external packages imported by fewer than minExternalFanIn internal packages.
+func (a *Analyzer) lowFanInExternals(graph *DependencyGraph) map[string]bool {
+	fanIn := make(map[string]int)
+	for pkgPath, pkg := range graph.Packages {
+		if !a.isInternalPackage(pkgPath) {
+			continue
+		}
+		for _, dep := range pkg.Dependencies {
+			fanIn[dep]++
+		}
+	}
+
+	remove := make(map[string]bool)
+	for pkgPath := range graph.Packages {
+		if !a.isInternalPackage(pkgPath) && fanIn[pkgPath] < a.minExternalFanIn {
+			remove[pkgPath] = true
+		}
+	}
+	return remove
+}
+
+// collapseExternals replaces all but the maxExternalNodes most imported external packages with
+// a single CollapsedExternalPackage node, redirecting every edge into them to it.
+func (a *Analyzer) collapseExternals(graph *DependencyGraph) {
+	fanIn := make(map[string]int)
+	for _, pkg := range graph.Packages {
+		for _, dep := range pkg.Dependencies {
+			fanIn[dep]++
+		}
+	}
+
+	var externals []string
+	for pkgPath := range graph.Packages {
+		if !a.isInternalPackage(pkgPath) && pkgPath != graph.EntryPackage {
+			externals = append(externals, pkgPath)
+		}
+	}
+	if len(externals) <= a.maxExternalNodes {
+		return
+	}
+	sort.Slice(externals, func(i, j int) bool {
+		if fanIn[externals[i]] != fanIn[externals[j]] {
+			return fanIn[externals[i]] > fanIn[externals[j]]
+		}
+		return externals[i] < externals[j]
+	})
+
+	collapsed := make(map[string]bool)
+	for _, pkgPath := range externals[a.maxExternalNodes:] {
+		collapsed[pkgPath] = true
+	}
+	for _, pkg := range graph.Packages {
+		for _, dep := range pkg.Dependencies {
+			if collapsed[dep] {
+				pkg.Dependencies = append(pkg.Dependencies, CollapsedExternalPackage)
+				sort.Strings(pkg.Dependencies)
+				break
+			}
+		}
+	}
+	removePackages(graph, collapsed)
+
+	graph.Packages[CollapsedExternalPackage] = &PackageInfo{
+		Name:         fmt.Sprintf("%s and %d more external", CollapsedExternalPackage, len(collapsed)),
+		Path:         CollapsedExternalPackage,
+		Dependencies: []string{},
+	}
+}
+
+// unreachablePackages returns the packages that no chain of imports leads to from the entry package.
+func unreachablePackages(graph *DependencyGraph) map[string]bool {
+	reached := make(map[string]bool)
+	queue := []string{graph.EntryPackage}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		pkg, exists := graph.Packages[current]
+		if !exists || reached[current] {
+			continue
+		}
+		reached[current] = true
+		queue = append(queue, pkg.Dependencies...)
+	}
+
+	remove := make(map[string]bool)
+	for pkgPath := range graph.Packages {
+		if !reached[pkgPath] {
+			remove[pkgPath] = true
+		}
+	}
+	return remove
+}
+
+// removePackages deletes packages from the graph along with every edge pointing at them.
+func removePackages(graph *DependencyGraph, remove map[string]bool) {
+	if len(remove) == 0 {
+		return
+	}
+
+	for pkgPath := range remove {
+		delete(graph.Packages, pkgPath)
+	}
+
+	for _, pkg := range graph.Packages {
+		kept := make([]string, 0, len(pkg.Dependencies))
+		for _, dep := range pkg.Dependencies {
+			if !remove[dep] {
+				kept = append(kept, dep)
+			}
+		}
+		pkg.Dependencies = kept
+	}
+}
@@ -0,0 +1,294 @@
+package analyzer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// errGoUnavailable reports that no go command is on the PATH, so the AST scanner is used instead.
+var errGoUnavailable = errors.New("go command not found")
+
+// goListPackage holds the fields of a `go list -json` package record used to build the graph.
+type goListPackage struct {
+	ImportPath     string
+	Dir            string
+	GoFiles        []string
+	CgoFiles       []string
+	TestGoFiles    []string
+	XTestGoFiles   []string
+	Imports        []string
+	IgnoredGoFiles []string
+	Incomplete     bool
+	Module         *struct {
+		Path    string
+		Replace *struct {
+			Path    string
+			Version string
+			Dir     string
+		}
+	}
+}
+
+// AnalyzeWithGoList builds the dependency graph of the packages under dir from
+// `go list -json -deps ./...`, so build constraints, replace directives and vendoring are
+// resolved exactly as the toolchain resolves them. The package in dir, if any, is the entry.
+// Dependencies come from the toolchain while file metadata (lines, TODOs, ignore directives)
+// is still read from the files it selects. When no go command is available, the package in
+// dir is analyzed with the AST scanner instead.
+func (a *Analyzer) AnalyzeWithGoList(
+	dir string,
+	excludeExternal bool,
+	excludeDirs []string,
+) (*DependencyGraph, error) {
+	a.excludeDirs = excludeDirs
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("resolving directory: %w", err)
+	}
+	if resolved, resolveErr := filepath.EvalSymlinks(absDir); resolveErr == nil {
+		absDir = resolved
+	}
+	if moduleErr := a.findModule(absDir); moduleErr != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNoModule, moduleErr)
+	}
+
+	listed, err := a.goList(absDir)
+	if errors.Is(err, errGoUnavailable) {
+		entryFile, findErr := firstGoFile(absDir)
+		if findErr != nil {
+			return nil, fmt.Errorf("%w, and no Go file to scan instead: %w", err, findErr)
+		}
+		slog.Warn("Warning: go command not found, falling back to the AST scanner", "dir", absDir)
+		return a.AnalyzeFromFile(entryFile, excludeExternal, excludeDirs)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	graph := &DependencyGraph{
+		Packages:         make(map[string]*PackageInfo),
+		ModuleName:       a.moduleName,
+		IncludedExternal: !excludeExternal,
+		ExcludedDirs:     slices.Clone(excludeDirs),
+	}
+	for _, pkg := range listed {
+		if pkg.Dir == absDir && a.isInternalPackage(pkg.ImportPath) {
+			graph.EntryPackage = pkg.ImportPath
+		}
+	}
+	a.addGoListPackages(graph, listed, excludeExternal)
+
+	return a.finishGraph(graph), nil
+}
+
+// goList runs `go list -e -json -deps ./...` in dir, for the build target and tags if set,
+// and decodes the package records.
+func (a *Analyzer) goList(dir string) ([]*goListPackage, error) {
+	goPath, err := exec.LookPath("go")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errGoUnavailable, err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	args := []string{"list", "-e", "-json", "-deps"}
+	if a.buildTarget != nil && len(a.buildTarget.BuildTags) > 0 {
+		args = append(args, "-tags="+strings.Join(a.buildTarget.BuildTags, ","))
+	}
+	cmd := exec.Command(goPath, append(args, "./...")...)
+	cmd.Dir = dir
+	if a.buildTarget != nil {
+		cmd.Env = append(os.Environ(), "GOOS="+a.buildTarget.GOOS, "GOARCH="+a.buildTarget.GOARCH)
+	}
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if runErr := cmd.Run(); runErr != nil {
+		return nil, fmt.Errorf("running go list: %w: %s", runErr, strings.TrimSpace(stderr.String()))
+	}
+
+	var listed []*goListPackage
+	decoder := json.NewDecoder(&stdout)
+	for {
+		var pkg goListPackage
+		if decodeErr := decoder.Decode(&pkg); errors.Is(decodeErr, io.EOF) {
+			break
+		} else if decodeErr != nil {
+			return nil, fmt.Errorf("decoding go list output: %w", decodeErr)
+		}
+		listed = append(listed, &pkg)
+	}
+	return listed, nil
+}
+
+// addGoListPackages adds the module's listed packages to the graph, together with the
+// external packages they import. External packages are expanded while they are at most
+// externalDepth imports away from the module, and are leaves otherwise.
+func (a *Analyzer) addGoListPackages(graph *DependencyGraph, listed []*goListPackage, excludeExternal bool) {
+	byPath := make(map[string]*goListPackage, len(listed))
+	for _, pkg := range listed {
+		byPath[pkg.ImportPath] = pkg
+	}
+
+	type queued struct {
+		path  string
+		depth int
+	}
+	var queue []queued
+	for _, pkg := range listed {
+		if a.isInternalPackage(pkg.ImportPath) {
+			queue = append(queue, queued{path: pkg.ImportPath})
+		}
+	}
+
+	// Like analyzePackage, revisit a package only when it is reached more shallowly
+	visited := make(map[string]int)
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if previousDepth, seen := visited[current.path]; seen && previousDepth <= current.depth {
+			continue
+		}
+		visited[current.path] = current.depth
+		if a.isExcludedPackage(current.path) || a.isHiddenPackage(current.path, graph) {
+			continue
+		}
+
+		pkg, found := byPath[current.path]
+		internal := a.isInternalPackage(current.path)
+		if _, seen := graph.Packages[current.path]; !seen && !internal {
+			a.warnIfLooksInternal(current.path)
+		}
+		replacement := a.goListReplacement(pkg)
+		if !found || (!internal && (current.depth > a.externalDepth || replacement != "")) {
+			graph.Packages[current.path] = &PackageInfo{
+				Name:         a.getPackageName(current.path),
+				Path:         current.path,
+				Module:       goListModule(pkg),
+				Dependencies: []string{},
+				ReplacedBy:   replacement,
+			}
+			continue
+		}
+
+		pkgInfo := a.goListPackageInfo(pkg, excludeExternal)
+		graph.Packages[current.path] = pkgInfo
+
+		skipped := pkgInfo.Ignored || (a.skipGenerated && pkgInfo.Generated)
+		if skipped && current.path != graph.EntryPackage {
+			continue
+		}
+		for _, dep := range pkgInfo.Dependencies {
+			depDepth := 0
+			if !a.isInternalPackage(dep) {
+				depDepth = current.depth + 1
+			}
+			queue = append(queue, queued{path: dep, depth: depDepth})
+		}
+	}
+}
+
+// goListPackageInfo describes a listed package, reading metadata from the Go files the
+// toolchain selected and taking its dependencies from the toolchain's resolved imports.
+func (a *Analyzer) goListPackageInfo(pkg *goListPackage, excludeExternal bool) *PackageInfo {
+	parsed := &packageImports{importCounts: make(map[string]int)}
+	for _, file := range append(append([]string(nil), pkg.GoFiles...), pkg.CgoFiles...) {
+		a.addPackageFile(parsed, filepath.Join(pkg.Dir, file))
+	}
+
+	dependencies := make([]string, 0, len(pkg.Imports))
+	for _, imp := range pkg.Imports {
+		// Skipped blank imports and unused imports are missing from importCounts
+		if (a.skipBlankImports || a.unusedImports) && parsed.importCounts[imp] == 0 {
+			continue
+		}
+		if excludeExternal && !a.isInternalPackage(imp) {
+			continue
+		}
+		dependencies = append(dependencies, imp)
+	}
+	sort.Strings(dependencies)
+	sort.Slice(parsed.files, func(i, j int) bool { return parsed.files[i].Path < parsed.files[j].Path })
+
+	module := goListModule(pkg)
+	if a.isInternalPackage(pkg.ImportPath) {
+		module = a.moduleName
+	}
+
+	pkgInfo := &PackageInfo{
+		Name:          a.getPackageName(pkg.ImportPath),
+		Path:          pkg.ImportPath,
+		Module:        module,
+		Dependencies:  dependencies,
+		FileCount:     parsed.fileCount,
+		TestFileCount: len(pkg.TestGoFiles) + len(pkg.XTestGoFiles),
+		LineCount:     parsed.lineCount,
+		TodoCount:     parsed.todoCount,
+		ExportedCount: parsed.exportedCount,
+		EmbedCount:    parsed.embedCount,
+		HasEmbeds:     parsed.embedCount > 0,
+		Complexity:    parsed.complexity,
+		Files:         parsed.files,
+		Dir:           pkg.Dir,
+		ImportCounts:  parsed.importCounts,
+		Generated:     parsed.allGenerated(),
+		Complete:      !pkg.Incomplete && parsed.parseFailures == 0,
+		Ignored:       parsed.ignored,
+		// go list always applies constraints, but like the AST scanner only a chosen target marks packages
+		EmptyForTarget: a.buildTarget != nil && len(pkg.GoFiles)+len(pkg.CgoFiles) == 0 &&
+			len(pkg.IgnoredGoFiles) > 0,
+	}
+	if a.countTestFiles {
+		pkgInfo.FileCount += pkgInfo.TestFileCount
+	}
+	return pkgInfo
+}
+
+// goListReplacement returns the replacement of a listed package's module to name with
+// WithNamedReplacements, like namedReplacement does from go.mod, or "".
+func (a *Analyzer) goListReplacement(pkg *goListPackage) string {
+	if !a.showReplacements || pkg == nil || pkg.Module == nil || pkg.Module.Replace == nil {
+		return ""
+	}
+	replace := pkg.Module.Replace
+	if replace.Version != "" {
+		return replace.Path + "@" + replace.Version
+	}
+	if rel, err := filepath.Rel(a.moduleRoot, replace.Dir); err == nil && filepath.IsLocal(rel) {
+		return ""
+	}
+	return replace.Dir
+}
+
+// goListModule returns the module path of a listed package, or "" for standard library
+// packages and packages that were not listed.
+func goListModule(pkg *goListPackage) string {
+	if pkg == nil || pkg.Module == nil {
+		return ""
+	}
+	return pkg.Module.Path
+}
+
+// firstGoFile returns the first non-test Go file in dir, in name order.
+func firstGoFile(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, "_test.go") {
+			return filepath.Join(dir, name), nil
+		}
+	}
+	return "", fmt.Errorf("no Go files in %s", dir)
+}
@@ -0,0 +1,61 @@
+package analyzer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"sort"
+)
+
+// hashConfig selects the fields covered by DependencyGraph.Hash.
+type hashConfig struct {
+	counts bool
+}
+
+// HashOption configures DependencyGraph.Hash.
+type HashOption func(*hashConfig)
+
+// HashWithCounts includes each package's file and line counts in the hash, so edits that
+// do not change the structure still change the fingerprint.
+func HashWithCounts() HashOption {
+	return func(c *hashConfig) {
+		c.counts = true
+	}
+}
+
+// Hash returns a hex-encoded SHA-256 fingerprint of the graph's structure: the module name,
+// the package paths and each package's dependencies, all in sorted order. Structurally
+// identical graphs hash equal regardless of map order, layers or counts.
+func (g *DependencyGraph) Hash(opts ...HashOption) string {
+	var config hashConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	h := sha256.New()
+	writeHashField(h, "module", g.ModuleName)
+
+	for _, pkgPath := range sortedPackagePaths(g) {
+		pkg := g.Packages[pkgPath]
+		writeHashField(h, "package", pkgPath)
+
+		deps := append([]string(nil), pkg.Dependencies...)
+		sort.Strings(deps)
+		for _, dep := range deps {
+			writeHashField(h, "dependency", dep)
+		}
+
+		if config.counts {
+			writeHashField(h, "files", fmt.Sprint(pkg.FileCount))
+			writeHashField(h, "lines", fmt.Sprint(pkg.LineCount))
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// writeHashField writes a length-prefixed field, so no two field sequences encode alike.
+func writeHashField(h hash.Hash, kind, value string) {
+	fmt.Fprintf(h, "%s:%d:%s\n", kind, len(value), value)
+}
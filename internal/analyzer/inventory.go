@@ -0,0 +1,90 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/parser"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// AllImports returns every distinct import path found in the non-test Go files of the module
+// containing entryFile, sorted. Unlike the graph, it ignores reachability, exclusions, ignore
+// directives and every filter option, so blank imports and imports of excluded directories are
+// included. The vendor and .git directories and nested modules are not walked.
+func (a *Analyzer) AllImports(entryFile string) ([]string, error) {
+	if err := checkEntryFile(entryFile); err != nil {
+		return nil, err
+	}
+	if resolved, resolveErr := filepath.EvalSymlinks(entryFile); resolveErr == nil {
+		entryFile = resolved
+	}
+	if err := a.findModule(entryFile); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNoModule, err)
+	}
+
+	seen := make(map[string]bool)
+	err := filepath.WalkDir(a.moduleRoot, func(path string, entry fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+
+		if entry.IsDir() {
+			if entry.Name() == "vendor" || entry.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			if _, statErr := os.Stat(filepath.Join(path, "go.mod")); statErr == nil && path != a.moduleRoot {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+
+		imports, parseErr := a.fileImportPaths(path)
+		if parseErr != nil {
+			// Log warning but continue processing other files
+			slog.Warn("Warning: failed to parse", "path", path, "error", parseErr)
+			return nil
+		}
+		for _, imp := range imports {
+			seen[imp] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("walking module: %w", err)
+	}
+
+	allImports := make([]string, 0, len(seen))
+	for imp := range seen {
+		allImports = append(allImports, imp)
+	}
+	sort.Strings(allImports)
+	return allImports, nil
+}
+
+// fileImportPaths returns the canonical paths of every import in a Go file, blank imports included.
+func (a *Analyzer) fileImportPaths(filePath string) ([]string, error) {
+	src, err := a.readSourceFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := parser.ParseFile(a.fileSet, filePath, src, parser.ImportsOnly)
+	if err != nil {
+		return nil, err
+	}
+
+	imports := make([]string, 0, len(file.Imports))
+	for _, imp := range file.Imports {
+		if importPath := canonicalImportPath(imp.Path.Value); importPath != "" {
+			imports = append(imports, importPath)
+		}
+	}
+	return imports, nil
+}
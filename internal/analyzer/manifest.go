@@ -0,0 +1,40 @@
+package analyzer
+
+import (
+	"sort"
+)
+
+// Violation is a dependency that a package is not allowed to have.
+type Violation struct {
+	Package    string `json:"package"`
+	Dependency string `json:"dependency"`
+}
+
+// CheckManifest reports every dependency missing from its package's allowed list.
+// The manifest maps package paths to their permitted dependencies; packages without
+// an entry are unconstrained. Violations are sorted by package and then dependency.
+func (a *Analyzer) CheckManifest(graph *DependencyGraph, manifest map[string][]string) []Violation {
+	var violations []Violation
+
+	for _, pkgPath := range sortedPackagePaths(graph) {
+		allowedList, constrained := manifest[pkgPath]
+		if !constrained {
+			continue
+		}
+
+		allowed := make(map[string]bool, len(allowedList))
+		for _, dep := range allowedList {
+			allowed[dep] = true
+		}
+
+		deps := append([]string(nil), graph.Packages[pkgPath].Dependencies...)
+		sort.Strings(deps)
+		for _, dep := range deps {
+			if !allowed[dep] {
+				violations = append(violations, Violation{Package: pkgPath, Dependency: dep})
+			}
+		}
+	}
+
+	return violations
+}
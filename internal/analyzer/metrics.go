@@ -0,0 +1,105 @@
+package analyzer
+
+import (
+	"strings"
+)
+
+// PackageMetrics holds the coupling and size metrics of a single package.
+type PackageMetrics struct {
+	Path        string  `json:"path"`
+	Module      string  `json:"module,omitempty"` // Owning module, empty for unattributed external packages
+	Layer       int     `json:"layer"`
+	FanIn       int     `json:"fanIn"`       // Packages in the graph importing this package
+	FanOut      int     `json:"fanOut"`      // Packages in the graph imported by this package
+	Instability float64 `json:"instability"` // FanOut / (FanIn + FanOut), 0 for isolated packages
+	FileCount   int     `json:"fileCount"`
+	LineCount   int     `json:"lineCount"`
+	TodoCount   int     `json:"todoCount"`
+	Complexity  int     `json:"complexity"` // Only computed by an analyzer created with WithComplexity
+}
+
+// GraphMetrics holds per-package metrics and graph-level totals.
+type GraphMetrics struct {
+	Packages        []PackageMetrics `json:"packages"` // Sorted by package path
+	TotalPackages   int              `json:"totalPackages"`
+	TotalEdges      int              `json:"totalEdges"`
+	TotalFiles      int              `json:"totalFiles"`
+	TotalLines      int              `json:"totalLines"`
+	TotalTodos      int              `json:"totalTodos"`
+	TotalComplexity int              `json:"totalComplexity"`
+	LayerCount      int              `json:"layerCount"`
+}
+
+// ComputeMetrics calculates coupling and size metrics for every package in the graph.
+// Only edges between packages present in the graph are counted.
+func (a *Analyzer) ComputeMetrics(graph *DependencyGraph) *GraphMetrics {
+	fanIn := make(map[string]int)
+	for pkgPath := range graph.Packages {
+		for _, dep := range sortedGraphDependencies(graph, pkgPath) {
+			fanIn[dep]++
+		}
+	}
+
+	metrics := &GraphMetrics{
+		Packages:      make([]PackageMetrics, 0, len(graph.Packages)),
+		TotalPackages: len(graph.Packages),
+		LayerCount:    len(graph.Layers),
+	}
+
+	for _, pkgPath := range sortedPackagePaths(graph) {
+		pkg := graph.Packages[pkgPath]
+		fanOut := len(sortedGraphDependencies(graph, pkgPath))
+
+		instability := 0.0
+		if total := fanIn[pkgPath] + fanOut; total > 0 {
+			instability = float64(fanOut) / float64(total)
+		}
+
+		metrics.Packages = append(metrics.Packages, PackageMetrics{
+			Path:        pkgPath,
+			Module:      pkg.Module,
+			Layer:       pkg.Layer,
+			FanIn:       fanIn[pkgPath],
+			FanOut:      fanOut,
+			Instability: instability,
+			FileCount:   pkg.FileCount,
+			LineCount:   pkg.LineCount,
+			TodoCount:   pkg.TodoCount,
+			Complexity:  pkg.Complexity,
+		})
+		metrics.TotalEdges += fanOut
+		metrics.TotalFiles += pkg.FileCount
+		metrics.TotalLines += pkg.LineCount
+		metrics.TotalTodos += pkg.TodoCount
+		metrics.TotalComplexity += pkg.Complexity
+	}
+
+	return metrics
+}
+
+// UnexportedOnlyPackages returns the module packages that export no top-level identifiers
+// yet are imported by another package in the graph, sorted by path. Such packages are
+// either glue reached only for side effects or dead code. ExportedCount is only populated
+// by an analyzer created with WithExportedCounts.
+func (a *Analyzer) UnexportedOnlyPackages(graph *DependencyGraph) []string {
+	imported := make(map[string]bool)
+	for pkgPath := range graph.Packages {
+		for _, dep := range sortedGraphDependencies(graph, pkgPath) {
+			imported[dep] = true
+		}
+	}
+
+	var packages []string
+	for _, pkgPath := range sortedPackagePaths(graph) {
+		pkg := graph.Packages[pkgPath]
+		if imported[pkgPath] && pkg.ExportedCount == 0 && inModule(pkgPath, graph.ModuleName) {
+			packages = append(packages, pkgPath)
+		}
+	}
+	return packages
+}
+
+// inModule reports whether a package path is the module path or lies below it.
+func inModule(pkgPath, moduleName string) bool {
+	return pkgPath == moduleName || strings.HasPrefix(pkgPath, moduleName+"/")
+}
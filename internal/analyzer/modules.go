@@ -0,0 +1,383 @@
+package analyzer
+
+import (
+	"fmt"
+	"go/build"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ownerPrefixSegments is the number of leading segments of a module path naming its host and
+// owner, as in "github.com/acme".
+const ownerPrefixSegments = 2
+
+// parseReplaceDirectives extracts replace directives that point at local directories.
+// Both single-line and block forms are supported; replacements by another module version are ignored.
+func parseReplaceDirectives(goModContent, moduleRoot string) map[string]string {
+	replacements := make(map[string]string)
+	forEachReplaceDirective(goModContent, func(oldFields, newFields []string) {
+		if len(newFields) != 1 || !isLocalModulePath(newFields[0]) {
+			return
+		}
+		dir := filepath.FromSlash(newFields[0])
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(moduleRoot, dir)
+		}
+		replacements[oldFields[0]] = filepath.Clean(dir)
+	})
+	return replacements
+}
+
+// parseModuleReplaceDirectives extracts replace directives that point at another module, such
+// as a fork, mapping the replaced module path to "path@version" of its replacement.
+func parseModuleReplaceDirectives(goModContent string) map[string]string {
+	replacements := make(map[string]string)
+	forEachReplaceDirective(goModContent, func(oldFields, newFields []string) {
+		if len(newFields) != 2 || isLocalModulePath(newFields[0]) {
+			return
+		}
+		replacements[oldFields[0]] = newFields[0] + "@" + newFields[1]
+	})
+	return replacements
+}
+
+// forEachReplaceDirective calls fn with the fields on either side of the arrow of each
+// replace directive in go.mod, in single-line or block form. oldFields is never empty.
+func forEachReplaceDirective(goModContent string, fn func(oldFields, newFields []string)) {
+	inBlock := false
+
+	for _, line := range strings.Split(goModContent, "\n") {
+		if idx := strings.Index(line, "//"); idx >= 0 {
+			line = line[:idx]
+		}
+		line = strings.TrimSpace(line)
+
+		switch {
+		case inBlock && line == ")":
+			inBlock = false
+			continue
+		case inBlock:
+			// Entries inside a replace block have no keyword to strip
+		case line == "replace (":
+			inBlock = true
+			continue
+		case strings.HasPrefix(line, "replace "):
+			line = strings.TrimSpace(strings.TrimPrefix(line, "replace "))
+		default:
+			continue
+		}
+
+		oldSpec, newSpec, found := strings.Cut(line, "=>")
+		if !found {
+			continue
+		}
+		if oldFields := strings.Fields(oldSpec); len(oldFields) > 0 {
+			fn(oldFields, strings.Fields(newSpec))
+		}
+	}
+}
+
+// parseRequireDirectives extracts the required module versions from go.mod.
+// Both single-line and block forms are supported.
+func parseRequireDirectives(goModContent string) map[string]string {
+	requirements := make(map[string]string)
+	inBlock := false
+
+	for _, line := range strings.Split(goModContent, "\n") {
+		if idx := strings.Index(line, "//"); idx >= 0 {
+			line = line[:idx]
+		}
+		line = strings.TrimSpace(line)
+
+		switch {
+		case inBlock && line == ")":
+			inBlock = false
+			continue
+		case inBlock:
+			// Entries inside a require block have no keyword to strip
+		case line == "require (":
+			inBlock = true
+			continue
+		case strings.HasPrefix(line, "require "):
+			line = strings.TrimSpace(strings.TrimPrefix(line, "require "))
+		default:
+			continue
+		}
+
+		if fields := strings.Fields(line); len(fields) == 2 {
+			requirements[strings.Trim(fields[0], "\"`")] = fields[1]
+		}
+	}
+
+	return requirements
+}
+
+// isLocalModulePath reports whether a replace target is a filesystem path rather than a module path.
+func isLocalModulePath(target string) bool {
+	return strings.HasPrefix(target, "./") || strings.HasPrefix(target, "../") ||
+		strings.HasPrefix(target, "/") || filepath.IsAbs(target)
+}
+
+// getReplacedPackageDir resolves a package from a locally replaced module to its directory.
+// The longest matching module path wins.
+func (a *Analyzer) getReplacedPackageDir(pkgPath string) (string, bool) {
+	bestModule := a.replacedModule(pkgPath)
+	if bestModule == "" {
+		return "", false
+	}
+
+	relPath := RelativePath(pkgPath, bestModule)
+	return filepath.Join(a.replacements[bestModule], filepath.FromSlash(relPath)), true
+}
+
+// replacedModule returns the longest locally replaced module path containing pkgPath,
+// or "" if no replace directive covers it.
+func (a *Analyzer) replacedModule(pkgPath string) string {
+	return longestModulePath(pkgPath, a.replacements)
+}
+
+// isModulePath reports whether pkgPath is the path of the analyzed module or of a module
+// named by its go.mod, rather than of a package inside one.
+func (a *Analyzer) isModulePath(pkgPath string) bool {
+	_, required := a.requirements[pkgPath]
+	_, replaced := a.replacements[pkgPath]
+	_, renamed := a.modReplacements[pkgPath]
+	return pkgPath == a.moduleName || required || replaced || renamed
+}
+
+// majorVersionPrefix returns modulePath without a major version suffix such as the "/v2" of
+// "example.com/foo/v2", and reports whether it had one. Suffixes start at v2, as v0 and v1
+// module paths carry none.
+func majorVersionPrefix(modulePath string) (string, bool) {
+	slash := strings.LastIndexByte(modulePath, '/')
+	version := modulePath[slash+1:]
+	if slash < 0 || len(version) < len("v2") || version[0] != 'v' || version[1] == '0' || version == "v1" {
+		return modulePath, false
+	}
+	for _, c := range version[1:] {
+		if c < '0' || c > '9' {
+			return modulePath, false
+		}
+	}
+	return modulePath[:slash], true
+}
+
+// requiredModule returns the longest module path required by go.mod containing pkgPath,
+// or "" if no require directive covers it.
+func (a *Analyzer) requiredModule(pkgPath string) string {
+	return longestModulePath(pkgPath, a.requirements)
+}
+
+// longestModulePath returns the longest module path among the keys of modules containing
+// pkgPath, or "" if none does.
+func longestModulePath(pkgPath string, modules map[string]string) string {
+	bestModule := ""
+	for modulePath := range modules {
+		if pkgPath != modulePath && !strings.HasPrefix(pkgPath, modulePath+"/") {
+			continue
+		}
+		if len(modulePath) > len(bestModule) {
+			bestModule = modulePath
+		}
+	}
+	return bestModule
+}
+
+// namedReplacement returns the module owning pkgPath and the replacement to name on its node
+// with WithNamedReplacements: the directory of a local replacement outside the module root,
+// or the path@version of a replacement module. It returns "" for both otherwise.
+func (a *Analyzer) namedReplacement(pkgPath string) (modulePath, replacement string) {
+	if !a.showReplacements {
+		return "", ""
+	}
+	if modulePath = a.replacedModule(pkgPath); modulePath != "" {
+		dir := a.replacements[modulePath]
+		if rel, err := filepath.Rel(a.moduleRoot, dir); err == nil && filepath.IsLocal(rel) {
+			// Replacements within the module root are part of the analyzed tree
+			return "", ""
+		}
+		return modulePath, dir
+	}
+	if modulePath = longestModulePath(pkgPath, a.modReplacements); modulePath != "" {
+		return modulePath, a.modReplacements[modulePath]
+	}
+	return "", ""
+}
+
+// packageModule returns the path of the module owning pkgPath: the analyzed module for
+// internal packages, the replaced module for locally replaced ones, the required module
+// with WithLocalExternalSources, and "" otherwise.
+func (a *Analyzer) packageModule(pkgPath string) string {
+	if a.isInternalPackage(pkgPath) {
+		return a.moduleName
+	}
+	if replaced := a.replacedModule(pkgPath); replaced != "" || !a.localExternals {
+		return replaced
+	}
+	return a.requiredModule(pkgPath)
+}
+
+// ClassifyExplain explains whether pkgPath is internal or external to the analyzed module, to
+// debug surprising classifications. A package expected to be internal but classified as
+// external usually points at a module name that does not match the import paths.
+func (a *Analyzer) ClassifyExplain(pkgPath string) string {
+	if a.isInternalPackage(pkgPath) {
+		return fmt.Sprintf("internal: under module prefix '%s'", a.moduleName)
+	}
+	if replaced := a.replacedModule(pkgPath); replaced != "" {
+		return fmt.Sprintf("external: not under module prefix '%s', provided by replaced module '%s'",
+			a.moduleName, replaced)
+	}
+	if firstSegment, _, _ := strings.Cut(pkgPath, "/"); !strings.Contains(firstSegment, ".") {
+		return fmt.Sprintf("external: not under module prefix '%s'; stdlib: no dot in first segment '%s'",
+			a.moduleName, firstSegment)
+	}
+	return fmt.Sprintf("external: not under module prefix '%s'", a.moduleName)
+}
+
+// warnIfLooksInternal warns when an external package's path suggests it belongs to the analyzed
+// module: it matches the module path but for case, or shares the host and owner of a module
+// path such as "github.com/acme/app" while falling outside it, as a sibling module missing a
+// replace directive would. Standard library and replaced packages are external by design and
+// never warned about.
+func (a *Analyzer) warnIfLooksInternal(pkgPath string) {
+	if a.isInternalPackage(pkgPath) || a.replacedModule(pkgPath) != "" {
+		return
+	}
+	if !strings.Contains(strings.Split(pkgPath, "/")[0], ".") {
+		return
+	}
+
+	lowerPath, lowerModule := strings.ToLower(pkgPath), strings.ToLower(a.moduleName)
+	suspicious := lowerPath == lowerModule || strings.HasPrefix(lowerPath, lowerModule+"/")
+	if moduleSegments := strings.Split(lowerModule, "/"); len(moduleSegments) > ownerPrefixSegments {
+		owner := strings.Join(moduleSegments[:ownerPrefixSegments], "/")
+		suspicious = suspicious || strings.HasPrefix(lowerPath, owner+"/")
+	}
+	if suspicious {
+		slog.Warn("Warning: package looks internal but is treated as external",
+			"package", pkgPath, "reason", a.ClassifyExplain(pkgPath))
+	}
+}
+
+// getLocalExternalDir resolves a third-party package to its source on disk, preferring
+// the module's vendor directory over the module cache.
+func (a *Analyzer) getLocalExternalDir(pkgPath string) (string, bool) {
+	vendorDir := filepath.Join(a.moduleRoot, "vendor", filepath.FromSlash(pkgPath))
+	if isDir(vendorDir) {
+		return vendorDir, true
+	}
+
+	modulePath := a.requiredModule(pkgPath)
+	if modulePath == "" {
+		return "", false
+	}
+	cacheDir := moduleCacheDir()
+	if cacheDir == "" {
+		return "", false
+	}
+
+	relPath := RelativePath(pkgPath, modulePath)
+	moduleDir := escapeModulePath(modulePath) + "@" + escapeModulePath(a.requirements[modulePath])
+	pkgDir := filepath.Join(cacheDir, filepath.FromSlash(moduleDir), filepath.FromSlash(relPath))
+	if !isDir(pkgDir) {
+		return "", false
+	}
+	return pkgDir, true
+}
+
+// moduleCacheDir returns the module cache root: GOMODCACHE if set, else the first GOPATH entry's pkg/mod.
+func moduleCacheDir() string {
+	if cacheDir := os.Getenv("GOMODCACHE"); cacheDir != "" {
+		return cacheDir
+	}
+	gopath := os.Getenv("GOPATH")
+	if gopath == "" {
+		gopath = build.Default.GOPATH
+	}
+	gopaths := filepath.SplitList(gopath)
+	if len(gopaths) == 0 || gopaths[0] == "" {
+		return ""
+	}
+	return filepath.Join(gopaths[0], "pkg", "mod")
+}
+
+// escapeModulePath applies the module cache's case encoding, where each upper-case
+// letter is written as '!' followed by its lower-case form.
+func escapeModulePath(modulePath string) string {
+	var b strings.Builder
+	for _, r := range modulePath {
+		if r >= 'A' && r <= 'Z' {
+			b.WriteByte('!')
+			r += 'a' - 'A'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// isDir reports whether dir exists and is a directory.
+func isDir(dir string) bool {
+	info, err := os.Stat(dir)
+	return err == nil && info.IsDir()
+}
+
+// RelativePath returns pkgPath relative to the root of moduleName, with forward slashes and
+// no leading or trailing slash, or "" for the module's root package. Backslash separators are
+// accepted in either argument. Paths outside the module are returned normalized but otherwise
+// unchanged; a module prefix only matches at a path segment boundary.
+func RelativePath(pkgPath, moduleName string) string {
+	pkgPath = strings.Trim(strings.ReplaceAll(pkgPath, "\\", "/"), "/")
+	moduleName = strings.Trim(strings.ReplaceAll(moduleName, "\\", "/"), "/")
+
+	if moduleName == "" {
+		return pkgPath
+	}
+	if pkgPath == moduleName {
+		return ""
+	}
+	if relPath, found := strings.CutPrefix(pkgPath, moduleName+"/"); found {
+		return strings.TrimLeft(relPath, "/")
+	}
+	return pkgPath
+}
+
+// canonicalImportPath turns an import path literal into the key used in graph.Packages.
+// Interpreted and raw string literals of the same path, and paths differing only in
+// surrounding whitespace, separators, trailing slashes or "." and empty segments,
+// map to the same key.
+func canonicalImportPath(literal string) string {
+	importPath, err := strconv.Unquote(literal)
+	if err != nil {
+		importPath = strings.Trim(literal, "\"`")
+	}
+	importPath = strings.ReplaceAll(strings.TrimSpace(importPath), "\\", "/")
+	if importPath == "" {
+		return ""
+	}
+	return path.Clean(importPath)
+}
+
+// CrossModuleEdges reports edges between packages attributed to different modules, the
+// integration points of a graph spanning several modules, such as services of a monorepo
+// joined through replace directives. Packages without a module, like the standard library,
+// never count. Each edge is returned as [source, target], sorted by source, then target.
+func (a *Analyzer) CrossModuleEdges(graph *DependencyGraph) [][2]string {
+	var edges [][2]string
+
+	for _, pkgPath := range sortedPackagePaths(graph) {
+		from := graph.Packages[pkgPath]
+		for _, dep := range sortedGraphDependencies(graph, pkgPath) {
+			to := graph.Packages[dep]
+			if from.Module != "" && to.Module != "" && from.Module != to.Module {
+				edges = append(edges, [2]string{pkgPath, dep})
+			}
+		}
+	}
+
+	return edges
+}
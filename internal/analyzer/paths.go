@@ -0,0 +1,65 @@
+package analyzer
+
+import (
+	"slices"
+)
+
+// AllPaths returns up to maxPaths distinct import paths from the package from to the package
+// to, each listing the packages along it from from to to, such as [A B D]. Paths never visit
+// a package twice, so cycles do not repeat, and they are returned in the order of a depth-first
+// search through sorted dependencies, so results are stable but not ordered by length. The
+// search only enters packages that can reach to and stops once maxPaths paths are found, which
+// bounds the work on dense graphs. No paths are returned when maxPaths is not positive or either
+// package is not in the graph; when from and to are the same package the path is just [from].
+func (a *Analyzer) AllPaths(graph *DependencyGraph, from, to string, maxPaths int) [][]string {
+	if maxPaths <= 0 || graph.Packages[from] == nil || graph.Packages[to] == nil {
+		return nil
+	}
+
+	// Packages that transitively import to; any other package is a dead end
+	reachesTarget := map[string]bool{to: true}
+	reverseDeps := a.buildReverseDependencyMap(graph, nil)
+	queue := []string{to}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dependent := range reverseDeps[current] {
+			if !reachesTarget[dependent] {
+				reachesTarget[dependent] = true
+				queue = append(queue, dependent)
+			}
+		}
+	}
+	if !reachesTarget[from] {
+		return nil
+	}
+
+	var paths [][]string
+	onPath := make(map[string]bool)
+	var extend func(path []string)
+	extend = func(path []string) {
+		current := path[len(path)-1]
+		if current == to {
+			paths = append(paths, slices.Clone(path))
+			return
+		}
+
+		onPath[current] = true
+		previous := ""
+		for _, dep := range sortedGraphDependencies(graph, current) {
+			if len(paths) >= maxPaths {
+				break
+			}
+			// Skip repeated imports of the same package, which would duplicate paths
+			if dep == previous || onPath[dep] || !reachesTarget[dep] {
+				continue
+			}
+			previous = dep
+			extend(append(path, dep))
+		}
+		onPath[current] = false
+	}
+	extend([]string{from})
+
+	return paths
+}
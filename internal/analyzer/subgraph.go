@@ -0,0 +1,109 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Focus returns the subgraph rooted at pkgPath: the package and everything it transitively imports.
+// The focused package becomes the subgraph's entry and layers are recalculated.
+func (a *Analyzer) Focus(graph *DependencyGraph, pkgPath string) (*DependencyGraph, error) {
+	if _, exists := graph.Packages[pkgPath]; !exists {
+		return nil, fmt.Errorf("package not in graph: %s", pkgPath)
+	}
+
+	subgraph := &DependencyGraph{
+		EntryPackage:     pkgPath,
+		EntryName:        a.getPackageName(pkgPath),
+		Packages:         make(map[string]*PackageInfo),
+		ModuleName:       graph.ModuleName,
+		IncludedExternal: graph.IncludedExternal,
+		ExcludedDirs:     graph.ExcludedDirs,
+	}
+
+	queue := []string{pkgPath}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if _, seen := subgraph.Packages[current]; seen {
+			continue
+		}
+
+		pkg := graph.Packages[current]
+		if pkg == nil {
+			continue
+		}
+		pkgCopy := *pkg
+		pkgCopy.Dependencies = append([]string(nil), pkg.Dependencies...)
+		subgraph.Packages[current] = &pkgCopy
+		queue = append(queue, pkg.Dependencies...)
+	}
+
+	a.calculateLayers(subgraph)
+	subgraph.Cycles = a.FindCycles(subgraph)
+
+	return subgraph, nil
+}
+
+// Dependents returns every package that transitively imports pkgPath, sorted by path.
+func (a *Analyzer) Dependents(graph *DependencyGraph, pkgPath string) ([]string, error) {
+	if _, exists := graph.Packages[pkgPath]; !exists {
+		return nil, fmt.Errorf("package not in graph: %s", pkgPath)
+	}
+
+	reverseDeps := a.buildReverseDependencyMap(graph, nil)
+	seen := map[string]bool{pkgPath: true}
+	queue := []string{pkgPath}
+	dependents := []string{}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dependent := range reverseDeps[current] {
+			if seen[dependent] {
+				continue
+			}
+			seen[dependent] = true
+			dependents = append(dependents, dependent)
+			queue = append(queue, dependent)
+		}
+	}
+
+	sort.Strings(dependents)
+	return dependents, nil
+}
+
+// Reverse returns a copy of the graph with every edge between its packages reversed, so each
+// package points at the packages importing it and the graph shows what a change would impact.
+// ImportCounts follow their edges, and layers and cycles are recalculated: packages nothing in
+// the graph imports from are at the top. The reversed graph has no entry package, as the
+// original entry, importing everything and imported by nothing, ends up at the bottom.
+func (a *Analyzer) Reverse(graph *DependencyGraph) *DependencyGraph {
+	reversed := &DependencyGraph{
+		Packages:         make(map[string]*PackageInfo, len(graph.Packages)),
+		ModuleName:       graph.ModuleName,
+		IncludedExternal: graph.IncludedExternal,
+		ExcludedDirs:     graph.ExcludedDirs,
+	}
+
+	reverseDeps := a.buildReverseDependencyMap(graph, nil)
+	for pkgPath, pkg := range graph.Packages {
+		pkgCopy := *pkg
+		pkgCopy.Dependencies = append([]string{}, reverseDeps[pkgPath]...)
+		sort.Strings(pkgCopy.Dependencies)
+		pkgCopy.IsImportedEntry = false
+		if pkg.ImportCounts != nil {
+			// The files counted for an edge are those of the importing package, now its target
+			pkgCopy.ImportCounts = make(map[string]int, len(pkgCopy.Dependencies))
+			for _, dependent := range pkgCopy.Dependencies {
+				pkgCopy.ImportCounts[dependent] = graph.Packages[dependent].ImportCounts[pkgPath]
+			}
+		}
+		reversed.Packages[pkgPath] = &pkgCopy
+	}
+
+	a.calculateLayers(reversed)
+	reversed.Cycles = a.FindCycles(reversed)
+
+	return reversed
+}
@@ -0,0 +1,59 @@
+package analyzer
+
+import (
+	"strconv"
+)
+
+// syntheticModuleName is the module name used for synthetic graphs.
+const syntheticModuleName = "example.com/synthetic"
+
+// BuildSyntheticGraph builds an acyclic dependency graph with n packages for benchmarks.
+// Package 0 is the module root and entry package; every other package i is imported by
+// package (i-1)/2, giving a balanced tree, and each package also imports its next sibling
+// so that layers contain shared dependencies.
+func BuildSyntheticGraph(n int) *DependencyGraph {
+	graph := &DependencyGraph{
+		EntryPackage: syntheticModuleName,
+		EntryName:    "synthetic",
+		Packages:     make(map[string]*PackageInfo, n),
+		ModuleName:   syntheticModuleName,
+	}
+
+	for i := range n {
+		pkgPath := syntheticPackagePath(i)
+		var dependencies []string
+		for _, child := range []int{2*i + 1, 2*i + 2, i + 1} {
+			if child < n {
+				dependencies = append(dependencies, syntheticPackagePath(child))
+			}
+		}
+
+		graph.Packages[pkgPath] = &PackageInfo{
+			Name:         syntheticPackageName(i),
+			Path:         pkgPath,
+			Module:       syntheticModuleName,
+			Dependencies: dependencies,
+			FileCount:    1,
+		}
+	}
+
+	New().calculateLayers(graph)
+
+	return graph
+}
+
+// syntheticPackagePath returns the import path of the i-th synthetic package.
+func syntheticPackagePath(i int) string {
+	if i == 0 {
+		return syntheticModuleName
+	}
+	return syntheticModuleName + "/" + syntheticPackageName(i)
+}
+
+// syntheticPackageName returns the short name of the i-th synthetic package.
+func syntheticPackageName(i int) string {
+	if i == 0 {
+		return "synthetic"
+	}
+	return "pkg" + strconv.Itoa(i)
+}
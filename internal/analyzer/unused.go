@@ -0,0 +1,136 @@
+package analyzer
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// unusedImports returns the imports of a fully parsed file that are never referenced.
+// Without type information the name an unnamed import binds is guessed from its path, so an
+// unnamed import is only reported when no qualifier in the file could belong to it: every
+// qualifier must be declared in the file or match another import. Blank and dot imports are
+// never reported.
+func unusedImports(file *ast.File) []*ast.ImportSpec {
+	qualifiers, declared := fileIdentifiers(file)
+
+	// Qualifiers that neither a local declaration nor an import explains may belong to an
+	// import whose package name differs from its path
+	explained := declared
+	var unused, unmatched []*ast.ImportSpec
+	for _, spec := range file.Imports {
+		if spec.Name != nil && (spec.Name.Name == "_" || spec.Name.Name == ".") {
+			continue
+		}
+
+		names := importNameCandidates(canonicalImportPath(spec.Path.Value))
+		if spec.Name != nil {
+			names = []string{spec.Name.Name}
+		}
+
+		used := false
+		for _, name := range names {
+			if qualifiers[name] {
+				explained[name] = true
+				used = true
+			}
+		}
+		if used {
+			continue
+		}
+		if spec.Name != nil {
+			unused = append(unused, spec)
+		} else {
+			unmatched = append(unmatched, spec)
+		}
+	}
+
+	for qualifier := range qualifiers {
+		if !explained[qualifier] {
+			return unused
+		}
+	}
+	return append(unused, unmatched...)
+}
+
+// fileIdentifiers returns the identifiers used as selector qualifiers (the x of x.y) in a file,
+// and the names the file declares anywhere: top-level and local declarations, parameters,
+// results, receivers and struct fields.
+func fileIdentifiers(file *ast.File) (map[string]bool, map[string]bool) {
+	qualifiers := make(map[string]bool)
+	declared := make(map[string]bool)
+	declare := func(idents ...*ast.Ident) {
+		for _, ident := range idents {
+			declared[ident.Name] = true
+		}
+	}
+	declareExprs := func(exprs ...ast.Expr) {
+		for _, expr := range exprs {
+			if ident, ok := expr.(*ast.Ident); ok {
+				declare(ident)
+			}
+		}
+	}
+
+	ast.Inspect(file, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.SelectorExpr:
+			if ident, ok := node.X.(*ast.Ident); ok {
+				qualifiers[ident.Name] = true
+			}
+		case *ast.FuncDecl:
+			declare(node.Name)
+		case *ast.TypeSpec:
+			declare(node.Name)
+		case *ast.ValueSpec:
+			declare(node.Names...)
+		case *ast.Field:
+			declare(node.Names...)
+		case *ast.AssignStmt:
+			if node.Tok == token.DEFINE {
+				declareExprs(node.Lhs...)
+			}
+		case *ast.RangeStmt:
+			if node.Tok == token.DEFINE {
+				declareExprs(node.Key, node.Value)
+			}
+		}
+		return true
+	})
+	return qualifiers, declared
+}
+
+// importNameCandidates returns the names an unnamed import of importPath plausibly binds:
+// its last path element, skipping a major version suffix such as /v2 or .v3, with and without
+// the customary go- prefix and -go or .go suffix.
+func importNameCandidates(importPath string) []string {
+	segments := strings.Split(importPath, "/")
+	name := segments[len(segments)-1]
+	if len(segments) > 1 && isMajorVersion(name) {
+		name = segments[len(segments)-2]
+	}
+	if i := strings.LastIndex(name, ".v"); i > 0 && isMajorVersion(name[i+1:]) {
+		name = name[:i]
+	}
+
+	candidates := []string{name}
+	trimmed := strings.TrimPrefix(name, "go-")
+	trimmed = strings.TrimSuffix(strings.TrimSuffix(trimmed, "-go"), ".go")
+	if trimmed != name && trimmed != "" {
+		candidates = append(candidates, trimmed)
+	}
+	return candidates
+}
+
+// isMajorVersion reports whether s is a major version element such as v2.
+func isMajorVersion(s string) bool {
+	if len(s) < 2 || s[0] != 'v' {
+		return false
+	}
+	for _, r := range s[1:] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
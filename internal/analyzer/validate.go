@@ -0,0 +1,110 @@
+package analyzer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Errors returned by ValidateEntry, in addition to ErrEntryNotFound and ErrPermission.
+var (
+	ErrNotGoFile = errors.New("entry is not a .go file")
+	ErrNoModule  = errors.New("no go.mod found")
+)
+
+// EntryValidation describes the module an entry file belongs to.
+type EntryValidation struct {
+	ModuleName string `json:"moduleName"`
+	ModuleRoot string `json:"moduleRoot"`          // Absolute directory containing go.mod
+	Workspace  string `json:"workspace,omitempty"` // Absolute path of a go.work using the module, if any
+}
+
+// ValidateEntry checks that an entry file exists, is a Go file and belongs to a module,
+// without analyzing any packages. Failures wrap ErrEntryNotFound, ErrPermission,
+// ErrNotGoFile or ErrNoModule.
+func (a *Analyzer) ValidateEntry(entryFile string) (*EntryValidation, error) {
+	if err := checkEntryFile(entryFile); err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(entryFile)
+	if err != nil {
+		return nil, fmt.Errorf("accessing entry file: %w", err)
+	}
+	if info.IsDir() || !strings.HasSuffix(entryFile, ".go") {
+		return nil, fmt.Errorf("%w: %s", ErrNotGoFile, entryFile)
+	}
+
+	if moduleErr := a.findModule(entryFile); moduleErr != nil {
+		return nil, fmt.Errorf("%w: %w", ErrNoModule, moduleErr)
+	}
+
+	return &EntryValidation{
+		ModuleName: a.moduleName,
+		ModuleRoot: a.moduleRoot,
+		Workspace:  findWorkspace(a.moduleRoot),
+	}, nil
+}
+
+// findWorkspace returns the nearest go.work at or above moduleRoot if one of its use
+// directives names moduleRoot, and "" otherwise.
+func findWorkspace(moduleRoot string) string {
+	dir := moduleRoot
+	for {
+		goWorkPath := filepath.Join(dir, "go.work")
+		if content, err := os.ReadFile(goWorkPath); err == nil {
+			for _, useDir := range parseUseDirectives(string(content), dir) {
+				if useDir == moduleRoot {
+					return goWorkPath
+				}
+			}
+			return ""
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// parseUseDirectives extracts the module directories listed by a go.work file's use
+// directives, resolved against the workspace root. Both single-line and block forms are supported.
+func parseUseDirectives(goWorkContent, workspaceRoot string) []string {
+	var dirs []string
+	inBlock := false
+
+	for _, line := range strings.Split(goWorkContent, "\n") {
+		if idx := strings.Index(line, "//"); idx >= 0 {
+			line = line[:idx]
+		}
+		line = strings.TrimSpace(line)
+
+		switch {
+		case inBlock && line == ")":
+			inBlock = false
+			continue
+		case inBlock:
+			// Entries inside a use block have no keyword to strip
+		case line == "use (":
+			inBlock = true
+			continue
+		case strings.HasPrefix(line, "use "):
+			line = strings.TrimSpace(strings.TrimPrefix(line, "use "))
+		default:
+			continue
+		}
+
+		if useDir := strings.Trim(line, "\"`"); useDir != "" {
+			if !filepath.IsAbs(useDir) {
+				useDir = filepath.Join(workspaceRoot, filepath.FromSlash(useDir))
+			}
+			dirs = append(dirs, filepath.Clean(useDir))
+		}
+	}
+
+	return dirs
+}
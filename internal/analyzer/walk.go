@@ -0,0 +1,32 @@
+package analyzer
+
+// Walk visits the packages reachable from the package from, breadth first, calling visit with
+// each package and its import distance from from, which is 0 for from itself. Every package is
+// visited once, at its shortest distance, and the dependencies of a package are visited in
+// sorted order, so walks of the same graph are identical. When visit returns false the walk
+// does not continue into that package's dependencies, though they may still be reached through
+// other packages. Dependencies missing from the graph are skipped, and nothing is visited when
+// from is not in the graph.
+func (g *DependencyGraph) Walk(from string, visit func(pkg *PackageInfo, depth int) bool) {
+	if _, exists := g.Packages[from]; !exists {
+		return
+	}
+
+	seen := map[string]bool{from: true}
+	current := []string{from}
+	for depth := 0; len(current) > 0; depth++ {
+		var next []string
+		for _, pkgPath := range current {
+			if !visit(g.Packages[pkgPath], depth) {
+				continue
+			}
+			for _, dep := range sortedGraphDependencies(g, pkgPath) {
+				if !seen[dep] {
+					seen[dep] = true
+					next = append(next, dep)
+				}
+			}
+		}
+		current = next
+	}
+}
@@ -0,0 +1,89 @@
+package scanner
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// maxProjectCacheEntries bounds a ProjectCache; a full cache is cleared before the next store.
+const maxProjectCacheEntries = 10000
+
+// ProjectCache memoizes Go project detection by directory path and modification time, so
+// browsing the same tree again does not repeat the go.mod search. It is safe for concurrent
+// use and can be shared by several scanners.
+//
+// A cached result is reused while the directory's own modification time is unchanged. That
+// time changes when an entry such as go.mod is added or removed directly in the directory,
+// but not for changes further down, so a go.mod added deep inside a repository is only
+// noticed once the cache entry is replaced.
+type ProjectCache struct {
+	mu      sync.Mutex
+	entries map[projectCacheKey]projectCacheEntry
+}
+
+// projectCacheKey identifies a detection result. Hidden directories change which
+// subdirectories are searched, so results with and without them are kept apart.
+type projectCacheKey struct {
+	path          string
+	includeHidden bool
+}
+
+// projectCacheEntry is a detection result and the modification time it was computed for.
+type projectCacheEntry struct {
+	modTime time.Time
+	isGo    bool
+}
+
+// NewProjectCache creates an empty ProjectCache.
+func NewProjectCache() *ProjectCache {
+	return &ProjectCache{entries: make(map[projectCacheKey]projectCacheEntry)}
+}
+
+// WithProjectCache makes the scanner look up and store Go project detection results in
+// cache. A nil cache disables caching, which is the default.
+func WithProjectCache(cache *ProjectCache) Option {
+	return func(s *Scanner) {
+		s.projectCache = cache
+	}
+}
+
+// lookup returns the cached result for key if it was computed for modTime.
+func (c *ProjectCache) lookup(key projectCacheKey, modTime time.Time) (isGo, found bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, found := c.entries[key]
+	if !found || !entry.modTime.Equal(modTime) {
+		return false, false
+	}
+	return entry.isGo, true
+}
+
+// store records the result for key, computed for modTime.
+func (c *ProjectCache) store(key projectCacheKey, modTime time.Time, isGo bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, replacing := c.entries[key]; !replacing && len(c.entries) >= maxProjectCacheEntries {
+		clear(c.entries)
+	}
+	c.entries[key] = projectCacheEntry{modTime: modTime, isGo: isGo}
+}
+
+// isGoProject reports whether dirPath is a Go project, using the project cache when one is set.
+func (s *Scanner) isGoProject(dirPath string) bool {
+	if s.projectCache == nil {
+		return s.detectGoProject(dirPath)
+	}
+
+	info, err := os.Stat(dirPath)
+	if err != nil {
+		return false
+	}
+	key := projectCacheKey{path: dirPath, includeHidden: s.includeHidden}
+	if isGo, found := s.projectCache.lookup(key, info.ModTime()); found {
+		return isGo
+	}
+	isGo := s.detectGoProject(dirPath)
+	s.projectCache.store(key, info.ModTime(), isGo)
+	return isGo
+}
@@ -0,0 +1,134 @@
+package scanner
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// gitignoreRule is one pattern of a .gitignore file. Only the parts of the format that
+// matter for directories are supported: comments, negation, anchoring, trailing slashes,
+// shell globs and ** segments.
+type gitignoreRule struct {
+	base     string   // Directory holding the .gitignore file
+	segments []string // Pattern split at slashes
+	anchored bool     // Matched against the path below base rather than the directory name alone
+	negated  bool     // A "!" pattern, which re-includes what earlier patterns ignored
+}
+
+// WithGitignore skips directories ignored by a .gitignore file in the listed directory or
+// any directory above it, up to the enclosing repository root, in addition to the built-in
+// exclusions. Patterns in deeper files take precedence, and within a file later patterns
+// take precedence, as in git.
+func WithGitignore(enabled bool) Option {
+	return func(s *Scanner) {
+		s.useGitignore = enabled
+	}
+}
+
+// gitignoreRules returns the rules applying to the children of dirPath, outermost first.
+// Files are read from dirPath upward until a directory containing .git has been read.
+func (s *Scanner) gitignoreRules(dirPath string) []gitignoreRule {
+	if !s.useGitignore {
+		return nil
+	}
+
+	var files [][]gitignoreRule
+	dir := absPath(dirPath)
+	for {
+		if content, err := os.ReadFile(filepath.Join(dir, ".gitignore")); err == nil {
+			files = append(files, parseGitignore(dir, string(content)))
+		}
+		parent := filepath.Dir(dir)
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil || parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	var rules []gitignoreRule
+	for i := len(files) - 1; i >= 0; i-- {
+		rules = append(rules, files[i]...)
+	}
+	return rules
+}
+
+// parseGitignore parses the content of the .gitignore file in base.
+func parseGitignore(base, content string) []gitignoreRule {
+	var rules []gitignoreRule
+	for line := range strings.Lines(content) {
+		pattern := strings.TrimRight(line, " \t\r\n")
+		if pattern == "" || strings.HasPrefix(pattern, "#") {
+			continue
+		}
+
+		rule := gitignoreRule{base: base}
+		if negated, found := strings.CutPrefix(pattern, "!"); found {
+			rule.negated = true
+			pattern = negated
+		}
+		// A leading backslash escapes a literal "#" or "!"
+		pattern = strings.TrimPrefix(pattern, `\`)
+		pattern = strings.TrimSuffix(pattern, "/")
+		// A slash at the start or in the middle anchors the pattern to base
+		rule.anchored = strings.Contains(pattern, "/")
+		pattern = strings.TrimPrefix(pattern, "/")
+		if pattern == "" {
+			continue
+		}
+		rule.segments = strings.Split(pattern, "/")
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// isGitignored reports whether the last rule matching dirPath ignores it.
+func isGitignored(rules []gitignoreRule, dirPath string) bool {
+	ignored := false
+	for _, rule := range rules {
+		if rule.matches(dirPath) {
+			ignored = !rule.negated
+		}
+	}
+	return ignored
+}
+
+// matches reports whether the rule matches the directory dirPath.
+func (r gitignoreRule) matches(dirPath string) bool {
+	rel, err := filepath.Rel(r.base, dirPath)
+	if err != nil || !filepath.IsLocal(rel) {
+		return false
+	}
+	if !r.anchored {
+		matched, _ := path.Match(r.segments[0], filepath.Base(dirPath))
+		return matched
+	}
+	return matchGitignoreSegments(r.segments, strings.Split(filepath.ToSlash(rel), "/"))
+}
+
+// matchGitignoreSegments matches path segments against pattern segments, where a "**"
+// segment matches any number of path segments, and at least one at the end of the pattern.
+func matchGitignoreSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		if len(pattern) == 1 {
+			return len(segments) > 0
+		}
+		for skip := 0; skip <= len(segments); skip++ {
+			if matchGitignoreSegments(pattern[1:], segments[skip:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	if matched, _ := path.Match(pattern[0], segments[0]); !matched {
+		return false
+	}
+	return matchGitignoreSegments(pattern[1:], segments[1:])
+}
@@ -2,10 +2,14 @@
 package scanner
 
 import (
+	"errors"
+	"io/fs"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 )
 
 // Operating system constants.
@@ -18,6 +22,12 @@ const (
 // Search depth constant for go.mod file recursive search.
 const maxGoModSearchDepth = 3
 
+// Directory read retry defaults, for network filesystems that fail transiently.
+const (
+	defaultReadRetries  = 2                     // Retries after the first failed read
+	defaultRetryBackoff = 20 * time.Millisecond // Delay before the first retry, doubled for each later one
+)
+
 // DirectoryNode represents a directory in the filesystem tree.
 type DirectoryNode struct {
 	Name        string           `json:"name"`
@@ -38,20 +48,118 @@ type ScanResult struct {
 type DirectoryListResult struct {
 	Success     bool             `json:"success"`
 	Directories []*DirectoryNode `json:"directories"`
+	ModuleRoot  string           `json:"moduleRoot,omitempty"` // Root of the module enclosing the listed directory
+	ModuleName  string           `json:"moduleName,omitempty"`
 	Error       string           `json:"error,omitempty"`
 }
 
 // Scanner handles filesystem scanning operations.
-type Scanner struct{}
+type Scanner struct {
+	readRetries   int
+	retryBackoff  time.Duration
+	excludes      []string // Cleaned absolute directory prefixes to skip
+	scanRoot      string   // Cleaned absolute directory whose children are the roots, or "" for the OS roots
+	includeHidden bool
+	projectCache  *ProjectCache // Set by WithProjectCache, nil when detection is not cached
+	useGitignore  bool
+	readDirFunc   func(name string) ([]os.DirEntry, error)
+}
+
+// Option configures a Scanner.
+type Option func(*Scanner)
+
+// WithReadRetries sets how many times a failed directory read is retried and the delay
+// before the first retry, which doubles for each later one. Zero retries disables retrying.
+// Missing directories and permission errors are never retried.
+func WithReadRetries(retries int, backoff time.Duration) Option {
+	return func(s *Scanner) {
+		s.readRetries = max(retries, 0)
+		s.retryBackoff = backoff
+	}
+}
+
+// WithExcludePrefixes skips every directory whose cleaned absolute path is one of the prefixes
+// or lies below one, such as a mounted backup. Prefixes match whole path elements, so
+// /mnt/backup does not exclude /mnt/backups. Relative prefixes are resolved against the
+// working directory.
+func WithExcludePrefixes(prefixes []string) Option {
+	return func(s *Scanner) {
+		for _, prefix := range prefixes {
+			if prefix != "" {
+				s.excludes = append(s.excludes, absPath(prefix))
+			}
+		}
+	}
+}
+
+// WithScanRoot makes GetFilesystemRoots return the children of path instead of the
+// operating system's roots, so browsing can start from a mounted workspace in a container
+// where / holds mostly system directories. A relative path is resolved against the working
+// directory, and an empty path keeps the OS roots.
+func WithScanRoot(path string) Option {
+	return func(s *Scanner) {
+		if path != "" {
+			s.scanRoot = absPath(path)
+		}
+	}
+}
+
+// WithIncludeHidden lists hidden directories that the Linux and macOS rules would otherwise
+// skip, for browsing into dot-directories on purpose. Version control metadata, caches,
+// application data such as .mozilla, and system directories like .Spotlight-V100 at the
+// root stay excluded.
+func WithIncludeHidden(include bool) Option {
+	return func(s *Scanner) {
+		s.includeHidden = include
+	}
+}
 
 // New creates a new Scanner instance.
-func New() *Scanner {
-	return &Scanner{}
+func New(opts ...Option) *Scanner {
+	s := &Scanner{
+		readRetries:  defaultReadRetries,
+		retryBackoff: defaultRetryBackoff,
+		readDirFunc:  os.ReadDir,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
-// GetFilesystemRoots returns just the filesystem roots (/ for Unix, drives for Windows).
+// readDir reads a directory, retrying transient failures with exponential backoff.
+func (s *Scanner) readDir(dirPath string) ([]os.DirEntry, error) {
+	entries, err := s.readDirFunc(dirPath)
+	if err == nil || errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrPermission) {
+		return entries, err
+	}
+
+	backoff := s.retryBackoff
+	for range s.readRetries {
+		time.Sleep(backoff)
+		backoff *= 2
+		if entries, err = s.readDirFunc(dirPath); err == nil {
+			return entries, nil
+		}
+	}
+
+	if s.readRetries > 0 {
+		slog.Warn("Warning: directory read failed after retries",
+			"path", dirPath,
+			"retries", s.readRetries,
+			"error", err)
+	}
+	return nil, err
+}
+
+// GetFilesystemRoots returns just the filesystem roots (/ for Unix, drives for Windows),
+// or the children of the directory set with WithScanRoot.
 func (s *Scanner) GetFilesystemRoots() (*ScanResult, error) {
-	rootPaths := getFilesystemRoots()
+	rootPaths := s.getFilesystemRoots()
+	rootBase := s.scanRoot
+	if rootBase == "" && (runtime.GOOS == osLinux || runtime.GOOS == osDarwin) {
+		rootBase = "/"
+	}
 
 	if len(rootPaths) == 0 {
 		return &ScanResult{
@@ -72,9 +180,10 @@ func (s *Scanner) GetFilesystemRoots() (*ScanResult, error) {
 		var actualPath string
 		var displayName string
 
-		// For Unix systems, rootPaths contains directory names that need to be converted to absolute paths
-		if (runtime.GOOS == osLinux || runtime.GOOS == osDarwin) && !strings.HasPrefix(rootPath, "/") {
-			actualPath = "/" + rootPath
+		// For Unix systems and a scan root, rootPaths contains directory names that need to be converted
+		// to absolute paths
+		if rootBase != "" && !filepath.IsAbs(rootPath) {
+			actualPath = filepath.Join(rootBase, rootPath)
 			displayName = rootPath
 		} else {
 			actualPath = rootPath
@@ -82,12 +191,12 @@ func (s *Scanner) GetFilesystemRoots() (*ScanResult, error) {
 		}
 
 		// Check if root is accessible - only include if it can be accessed and read
-		if isDirectoryAccessible(actualPath) {
-			isGo := isGoProject(actualPath)
+		if s.isDirectoryAccessible(actualPath) {
+			isGo := s.isGoProject(actualPath)
 
 			// If it's not a Go project, check if it has subdirectories or Go files
 			// Skip root directories that are not Go projects, have no subdirectories, AND have no Go files
-			if !isGo && !hasSubdirectories(actualPath) && !hasGoFiles(actualPath) {
+			if !isGo && !s.hasSubdirectories(actualPath) && !s.hasGoFiles(actualPath) {
 				continue // Skip this root directory - it's a dead end with no useful content
 			}
 
@@ -109,8 +218,9 @@ func (s *Scanner) GetFilesystemRoots() (*ScanResult, error) {
 }
 
 // processDirectoryEntries processes directory entries and returns valid directory nodes.
-func processDirectoryEntries(dirPath string, entries []os.DirEntry) []*DirectoryNode {
+func (s *Scanner) processDirectoryEntries(dirPath string, entries []os.DirEntry) []*DirectoryNode {
 	directories := make([]*DirectoryNode, 0)
+	ignoreRules := s.gitignoreRules(dirPath)
 
 	for _, entry := range entries {
 		if !entry.IsDir() {
@@ -121,17 +231,20 @@ func processDirectoryEntries(dirPath string, entries []os.DirEntry) []*Directory
 		childName := entry.Name()
 
 		// Skip excluded directories
-		if shouldExcludeDirectory(childPath, childName) {
+		if s.shouldExcludeDirectory(childPath, childName) || s.hasExcludedPrefix(childPath) {
+			continue
+		}
+		if isGitignored(ignoreRules, absPath(childPath)) {
 			continue
 		}
 
 		// Only include child directories that are accessible
-		if isDirectoryAccessible(childPath) {
-			if shouldIncludeDirectory(childPath) {
+		if s.isDirectoryAccessible(childPath) {
+			if s.shouldIncludeDirectory(childPath) {
 				child := &DirectoryNode{
 					Name:        childName,
 					Path:        childPath,
-					IsGoProject: isGoProject(childPath),
+					IsGoProject: s.isGoProject(childPath),
 					Children:    nil, // Will be loaded on demand when expanded
 				}
 				directories = append(directories, child)
@@ -143,9 +256,33 @@ func processDirectoryEntries(dirPath string, entries []os.DirEntry) []*Directory
 	return directories
 }
 
+// hasExcludedPrefix reports whether a directory is excluded by WithExcludePrefixes.
+func (s *Scanner) hasExcludedPrefix(dirPath string) bool {
+	if len(s.excludes) == 0 {
+		return false
+	}
+	dirPath = absPath(dirPath)
+	for _, prefix := range s.excludes {
+		// A root prefix already ends in a separator
+		within := strings.TrimSuffix(prefix, string(filepath.Separator)) + string(filepath.Separator)
+		if dirPath == prefix || strings.HasPrefix(dirPath, within) {
+			return true
+		}
+	}
+	return false
+}
+
+// absPath returns the cleaned absolute form of a path, or the cleaned path if it cannot be resolved.
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
 // shouldIncludeDirectory determines if a directory should be included in the results.
-func shouldIncludeDirectory(childPath string) bool {
-	isGo := isGoProject(childPath)
+func (s *Scanner) shouldIncludeDirectory(childPath string) bool {
+	isGo := s.isGoProject(childPath)
 
 	// If it's a Go project, always include
 	if isGo {
@@ -154,13 +291,13 @@ func shouldIncludeDirectory(childPath string) bool {
 
 	// If it's not a Go project, check if it has subdirectories or Go files
 	// Skip directories that are not Go projects, have no subdirectories, AND have no Go files (dead ends)
-	return hasSubdirectories(childPath) || hasGoFiles(childPath)
+	return s.hasSubdirectories(childPath) || s.hasGoFiles(childPath)
 }
 
 // validateDirectoryPath validates that the directory path exists and is accessible.
-func validateDirectoryPath(dirPath string) *DirectoryListResult {
+func (s *Scanner) validateDirectoryPath(dirPath string) *DirectoryListResult {
 	// Check if directory should be excluded (but allow if it's a filesystem root)
-	if !isFilesystemRoot(dirPath) && shouldExcludeDirectory(dirPath, filepath.Base(dirPath)) {
+	if !isFilesystemRoot(dirPath) && s.shouldExcludeDirectory(dirPath, filepath.Base(dirPath)) {
 		return &DirectoryListResult{
 			Success: false,
 			Error:   "Directory is excluded from scanning",
@@ -175,17 +312,17 @@ func (s *Scanner) ListDirectory(dirPath string) (*DirectoryListResult, error) {
 	dirPath = filepath.Clean(dirPath)
 
 	// Check if directory is accessible upfront
-	if !isDirectoryAccessible(dirPath) {
+	if !s.isDirectoryAccessible(dirPath) {
 		return handleInaccessibleDirectory(dirPath)
 	}
 
 	// Validate directory path
-	if result := validateDirectoryPath(dirPath); result != nil {
+	if result := s.validateDirectoryPath(dirPath); result != nil {
 		return result, nil
 	}
 
 	// Read directory contents (we know this will work because we checked accessibility above)
-	entries, err := os.ReadDir(dirPath)
+	entries, err := s.readDir(dirPath)
 	if err != nil {
 		// This shouldn't happen since we verified accessibility, but handle it just in case
 		return &DirectoryListResult{
@@ -195,20 +332,53 @@ func (s *Scanner) ListDirectory(dirPath string) (*DirectoryListResult, error) {
 	}
 
 	// Process entries and get valid directories
-	directories := processDirectoryEntries(dirPath, entries)
+	directories := s.processDirectoryEntries(dirPath, entries)
+	moduleRoot, moduleName, _ := s.FindEnclosingModule(dirPath)
 
 	return &DirectoryListResult{
 		Success:     true,
 		Directories: directories,
+		ModuleRoot:  moduleRoot,
+		ModuleName:  moduleName,
 	}, nil
 }
 
-// isGoProject checks if a directory is a Go project by looking for go.mod file.
+// ListDirectoryDepth lists the subdirectories of dirPath like ListDirectory, then loads the
+// children of each listed directory in turn, down to depth levels below dirPath. Directories
+// whose children were loaded are marked expanded. A depth below 1 is treated as 1, which
+// matches ListDirectory.
+func (s *Scanner) ListDirectoryDepth(dirPath string, depth int) (*DirectoryListResult, error) {
+	result, err := s.ListDirectory(dirPath)
+	if err != nil || !result.Success {
+		return result, err
+	}
+	s.expandDirectories(result.Directories, depth-1)
+	return result, nil
+}
+
+// expandDirectories loads the children of each node, recursing until depth levels are loaded.
+// Directories that cannot be listed are left collapsed.
+func (s *Scanner) expandDirectories(nodes []*DirectoryNode, depth int) {
+	if depth <= 0 {
+		return
+	}
+	for _, node := range nodes {
+		entries, err := s.readDir(node.Path)
+		if err != nil {
+			continue
+		}
+		node.Children = s.processDirectoryEntries(node.Path, entries)
+		node.IsExpanded = true
+		s.expandDirectories(node.Children, depth-1)
+	}
+}
+
+// detectGoProject checks if a directory is a Go project by looking for go.mod file.
 // A directory is considered a Go project if:
 // 1. It contains a go.mod file directly in the directory
 // OR
 // 2. It contains a .git folder AND somewhere inside its recursive structure it contains a go.mod file.
-func isGoProject(dirPath string) bool {
+func (s *Scanner) detectGoProject(dirPath string) bool {
 	// First check if go.mod file exists directly in this directory
 	goModPath := filepath.Join(dirPath, "go.mod")
 	if _, err := os.Stat(goModPath); err == nil {
@@ -224,20 +394,49 @@ func isGoProject(dirPath string) bool {
 	gitPath := filepath.Join(dirPath, ".git")
 	if info, err := os.Stat(gitPath); err == nil && info.IsDir() {
 		// .git exists, now recursively search for go.mod in subdirectories
-		return hasGoModFileRecursive(dirPath, 0, maxGoModSearchDepth)
+		return s.hasGoModFileRecursive(dirPath, 0, maxGoModSearchDepth)
 	}
 
 	// No go.mod directly and no .git folder
 	return false
 }
 
+// FindEnclosingModule walks upward from dirPath, like the analyzer does for an entry file, to
+// the nearest directory with a go.mod and returns that directory and the module path it
+// declares. Symlinks in dirPath are resolved first. found is false when no go.mod is reached
+// or the nearest one declares no module.
+func (s *Scanner) FindEnclosingModule(dirPath string) (moduleRoot, moduleName string, found bool) {
+	dir := absPath(dirPath)
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+
+	for {
+		content, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+		if err == nil {
+			for line := range strings.Lines(string(content)) {
+				if name, isModule := strings.CutPrefix(strings.TrimSpace(line), "module "); isModule {
+					return dir, strings.TrimSpace(name), true
+				}
+			}
+			return "", "", false
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", false
+		}
+		dir = parent
+	}
+}
+
 // hasGoModFileRecursive recursively searches for go.mod files up to maxDepth levels.
-func hasGoModFileRecursive(dirPath string, currentDepth, maxDepth int) bool {
+func (s *Scanner) hasGoModFileRecursive(dirPath string, currentDepth, maxDepth int) bool {
 	if currentDepth >= maxDepth {
 		return false
 	}
 
-	entries, err := os.ReadDir(dirPath)
+	entries, err := s.readDir(dirPath)
 	if err != nil {
 		// If we can't read the directory (e.g., permission denied), return false
 		return false
@@ -250,7 +449,7 @@ func hasGoModFileRecursive(dirPath string, currentDepth, maxDepth int) bool {
 
 		// Skip excluded directories to avoid scanning deep into dependencies
 		childPath := filepath.Join(dirPath, entry.Name())
-		if shouldExcludeDirectory(childPath, entry.Name()) {
+		if s.shouldExcludeDirectory(childPath, entry.Name()) {
 			continue
 		}
 
@@ -262,7 +461,7 @@ func hasGoModFileRecursive(dirPath string, currentDepth, maxDepth int) bool {
 
 		// Recursively check deeper if not found - but only if we can access the directory
 		if _, statErr := os.Stat(childPath); statErr == nil {
-			if hasGoModFileRecursive(childPath, currentDepth+1, maxDepth) {
+			if s.hasGoModFileRecursive(childPath, currentDepth+1, maxDepth) {
 				return true
 			}
 		}
@@ -272,13 +471,17 @@ func hasGoModFileRecursive(dirPath string, currentDepth, maxDepth int) bool {
 	return false
 }
 
-// getFilesystemRoots returns the filesystem roots based on the operating system.
-func getFilesystemRoots() []string {
+// getFilesystemRoots returns the filesystem roots based on the operating system, or the
+// directories within the scan root when one is set.
+func (s *Scanner) getFilesystemRoots() []string {
+	if s.scanRoot != "" {
+		return s.getChildRoots(s.scanRoot)
+	}
 	switch runtime.GOOS {
 	case osWindows:
 		return getWindowsRoots()
 	case osDarwin, osLinux:
-		return getUnixRoots()
+		return s.getChildRoots("/")
 	default:
 		return []string{"/"}
 	}
@@ -297,7 +500,7 @@ func isFilesystemRoot(path string) bool {
 		}
 	case osLinux, osDarwin:
 		// For Unix systems, only "/" is a true filesystem root
-		// The directories returned by getUnixRoots() are just top-level directories to display
+		// The directories returned by getChildRoots() are just top-level directories to display
 		if path == "/" {
 			return true
 		}
@@ -326,16 +529,16 @@ func getWindowsRoots() []string {
 	return roots
 }
 
-// getUnixRoots returns the non-excluded directories within "/" for Linux and macOS.
-func getUnixRoots() []string {
+// getChildRoots returns the names of the non-excluded directories within rootPath, which is
+// "/" for Linux and macOS unless a scan root is set.
+func (s *Scanner) getChildRoots(rootPath string) []string {
 	var roots []string
-	rootPath := "/"
 
 	// Try to read the root directory
-	entries, err := os.ReadDir(rootPath)
+	entries, err := s.readDir(rootPath)
 	if err != nil {
-		// If we can't read /, fallback to just "/"
-		return []string{"/"}
+		// If we can't read the root, fallback to just the root itself
+		return []string{rootPath}
 	}
 
 	// Process each entry in the root directory
@@ -348,17 +551,17 @@ func getUnixRoots() []string {
 		entryPath := filepath.Join(rootPath, entryName)
 
 		// Skip excluded directories - this will exclude system dirs like proc, sys, etc.
-		if shouldExcludeDirectory(entryPath, entryName) {
+		if s.shouldExcludeDirectory(entryPath, entryName) {
 			continue
 		}
 
 		// Check if the directory is both accessible and readable
-		if isDirectoryAccessible(entryPath) {
-			isGo := isGoProject(entryPath)
+		if s.isDirectoryAccessible(entryPath) {
+			isGo := s.isGoProject(entryPath)
 
 			// If it's not a Go project, check if it has subdirectories or Go files
 			// Skip root directories that are not Go projects, have no subdirectories, AND have no Go files
-			if !isGo && !hasSubdirectories(entryPath) && !hasGoFiles(entryPath) {
+			if !isGo && !s.hasSubdirectories(entryPath) && !s.hasGoFiles(entryPath) {
 				continue // Skip this root directory - it's a dead end with no useful content
 			}
 
@@ -368,16 +571,16 @@ func getUnixRoots() []string {
 		// Note: We silently skip inaccessible directories and dead-end directories
 	}
 
-	// If no accessible directories found, fallback to "/"
+	// If no accessible directories found, fallback to the root itself
 	if len(roots) == 0 {
-		roots = []string{"/"}
+		roots = []string{rootPath}
 	}
 
 	return roots
 }
 
 // shouldExcludeDirectory checks if a directory should be excluded from scanning.
-func shouldExcludeDirectory(fullPath, dirName string) bool {
+func (s *Scanner) shouldExcludeDirectory(fullPath, dirName string) bool {
 	// Check system directories
 	if isSystemDirectory(dirName) {
 		return true
@@ -399,7 +602,7 @@ func shouldExcludeDirectory(fullPath, dirName string) bool {
 	}
 
 	// Check OS-specific exclusions
-	return shouldExcludeOSDirectory(fullPath, dirName)
+	return shouldExcludeOSDirectory(fullPath, dirName, s.includeHidden)
 }
 
 // isSystemDirectory checks if a directory is a system directory.
@@ -483,15 +686,16 @@ func isUnixDevToolLocation(fullPath string) bool {
 		strings.Contains(fullPath, "/.config/")
 }
 
-// shouldExcludeOSDirectory checks OS-specific directory exclusions.
-func shouldExcludeOSDirectory(fullPath, dirName string) bool {
+// shouldExcludeOSDirectory checks OS-specific directory exclusions. With includeHidden, hidden
+// directories are only excluded when a more specific rule names them.
+func shouldExcludeOSDirectory(fullPath, dirName string, includeHidden bool) bool {
 	switch runtime.GOOS {
 	case osLinux:
-		return shouldExcludeLinuxDirectory(fullPath, dirName)
+		return shouldExcludeLinuxDirectory(fullPath, dirName, includeHidden)
 	case osWindows:
 		return shouldExcludeWindowsDirectory(fullPath, dirName)
 	case osDarwin:
-		return shouldExcludeMacDirectory(fullPath, dirName)
+		return shouldExcludeMacDirectory(fullPath, dirName, includeHidden)
 	}
 	return false
 }
@@ -573,7 +777,7 @@ func isAllowedHiddenDirectory(dirName string) bool {
 	return false
 }
 
-func shouldExcludeLinuxDirectory(fullPath, dirName string) bool {
+func shouldExcludeLinuxDirectory(fullPath, dirName string, includeHidden bool) bool {
 	// Check system directories
 	if isLinuxSystemDirectory(fullPath, dirName) {
 		return true
@@ -590,7 +794,7 @@ func shouldExcludeLinuxDirectory(fullPath, dirName string) bool {
 	}
 
 	// Check hidden directories (exclude most, allow some)
-	if !isAllowedHiddenDirectory(dirName) {
+	if !includeHidden && !isAllowedHiddenDirectory(dirName) {
 		return true
 	}
 
@@ -627,7 +831,7 @@ func shouldExcludeWindowsDirectory(fullPath, dirName string) bool {
 }
 
 // shouldExcludeMacDirectory checks for macOS-specific directory exclusions.
-func shouldExcludeMacDirectory(fullPath, dirName string) bool {
+func shouldExcludeMacDirectory(fullPath, dirName string, includeHidden bool) bool {
 	macSystemDirs := []string{
 		"System", "Library", "Applications", "Volumes", "cores",
 		"dev", "etc", "tmp", "usr", "bin", "sbin", "var",
@@ -645,7 +849,7 @@ func shouldExcludeMacDirectory(fullPath, dirName string) bool {
 	}
 
 	// Exclude hidden directories (but allow some common ones)
-	if strings.HasPrefix(dirName, ".") &&
+	if !includeHidden && strings.HasPrefix(dirName, ".") &&
 		!strings.HasPrefix(dirName, ".config") &&
 		!strings.HasPrefix(dirName, ".local") {
 		return true
@@ -660,7 +864,7 @@ func (s *Scanner) ScanForGoProjects() (*ScanResult, error) {
 }
 
 // isDirectoryAccessible checks if a directory exists, is accessible, and can be read.
-func isDirectoryAccessible(dirPath string) bool {
+func (s *Scanner) isDirectoryAccessible(dirPath string) bool {
 	// First check if directory exists and is a directory
 	info, err := os.Stat(dirPath)
 	if err != nil {
@@ -671,13 +875,13 @@ func isDirectoryAccessible(dirPath string) bool {
 	}
 
 	// Try to read the directory to ensure we have read permissions
-	_, err = os.ReadDir(dirPath)
+	_, err = s.readDir(dirPath)
 	return err == nil
 }
 
 // hasSubdirectories checks if a directory contains any subdirectories.
-func hasSubdirectories(dirPath string) bool {
-	entries, err := os.ReadDir(dirPath)
+func (s *Scanner) hasSubdirectories(dirPath string) bool {
+	entries, err := s.readDir(dirPath)
 	if err != nil {
 		return false // If we can't read it, assume no subdirectories
 	}
@@ -691,8 +895,8 @@ func hasSubdirectories(dirPath string) bool {
 }
 
 // hasGoFiles checks if a directory contains any .go files.
-func hasGoFiles(dirPath string) bool {
-	entries, err := os.ReadDir(dirPath)
+func (s *Scanner) hasGoFiles(dirPath string) bool {
+	entries, err := s.readDir(dirPath)
 	if err != nil {
 		return false // If we can't read it, assume no Go files
 	}
@@ -0,0 +1,80 @@
+package scanner
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyReadDir fails the first failures reads of each directory with a transient error.
+func flakyReadDir(failures int, calls map[string]int) func(string) ([]os.DirEntry, error) {
+	return func(name string) ([]os.DirEntry, error) {
+		calls[name]++
+		if calls[name] <= failures {
+			return nil, errors.New("stale file handle")
+		}
+		return os.ReadDir(name)
+	}
+}
+
+func TestReadDir_RetriesTransientFailures(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "child"), 0755))
+	calls := make(map[string]int)
+	s := New(WithReadRetries(2, 0))
+	s.readDirFunc = flakyReadDir(2, calls)
+
+	entries, err := s.readDir(dir)
+
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, 3, calls[dir])
+}
+
+func TestReadDir_GivesUpAfterRetries(t *testing.T) {
+	dir := t.TempDir()
+	calls := make(map[string]int)
+	s := New(WithReadRetries(1, 0))
+	s.readDirFunc = flakyReadDir(5, calls)
+
+	_, err := s.readDir(dir)
+
+	require.Error(t, err)
+	assert.Equal(t, 2, calls[dir])
+}
+
+func TestReadDir_DoesNotRetryMissingDirectories(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+	calls := 0
+	s := New(WithReadRetries(3, 0))
+	s.readDirFunc = func(name string) ([]os.DirEntry, error) {
+		calls++
+		return os.ReadDir(name)
+	}
+
+	_, err := s.readDir(missing)
+
+	require.ErrorIs(t, err, fs.ErrNotExist)
+	assert.Equal(t, 1, calls)
+}
+
+func TestListDirectory_RetriesFlakyChildren(t *testing.T) {
+	root := t.TempDir()
+	project := filepath.Join(root, "project")
+	require.NoError(t, os.Mkdir(project, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(project, "go.mod"), []byte("module example.com/project\n"), 0644))
+	s := New(WithReadRetries(1, 0))
+	s.readDirFunc = flakyReadDir(1, make(map[string]int))
+
+	result, err := s.ListDirectory(root)
+
+	require.NoError(t, err)
+	require.True(t, result.Success, result.Error)
+	require.Len(t, result.Directories, 1, "Flaky child directories should not be dropped")
+	assert.True(t, result.Directories[0].IsGoProject)
+}
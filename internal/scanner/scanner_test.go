@@ -327,6 +327,25 @@ func TestScanner_GetFilesystemRoots(t *testing.T) {
 	}
 }
 
+func TestScanner_GetFilesystemRoots_ScanRoot(t *testing.T) {
+	tempDir := createTempDirWithStructure(t)
+
+	result, err := scanner.New(scanner.WithScanRoot(tempDir)).GetFilesystemRoots()
+	require.NoError(t, err)
+	require.True(t, result.Success, result.Error)
+
+	children := make(map[string]*scanner.DirectoryNode)
+	for _, child := range result.Tree.Children {
+		children[child.Name] = child
+	}
+	require.Contains(t, children, "go_project_with_mod")
+	assert.Equal(t, filepath.Join(tempDir, "go_project_with_mod"), children["go_project_with_mod"].Path)
+	assert.True(t, children["go_project_with_mod"].IsGoProject)
+	assert.Contains(t, children, "regular_dir")
+	assert.NotContains(t, children, "node_modules", "Excluded directories should be skipped")
+	assert.NotContains(t, children, "empty_dir", "Dead-end directories should be skipped")
+}
+
 // Test ListDirectory
 
 func TestScanner_ListDirectory_ValidDirectories(t *testing.T) {
@@ -430,6 +449,187 @@ func validateGoProjectDetection(t *testing.T, s *scanner.Scanner, baseDir, testD
 	}
 }
 
+func TestScanner_ListDirectoryDepth(t *testing.T) {
+	s := scanner.New()
+	tempDir := createTempDirWithStructure(t)
+
+	findNode := func(nodes []*scanner.DirectoryNode, name string) *scanner.DirectoryNode {
+		for _, node := range nodes {
+			if node.Name == name {
+				return node
+			}
+		}
+		return nil
+	}
+
+	result, err := s.ListDirectoryDepth(tempDir, 1)
+	require.NoError(t, err)
+	require.True(t, result.Success)
+	gitProject := findNode(result.Directories, "go_project_with_git")
+	require.NotNil(t, gitProject)
+	assert.Nil(t, gitProject.Children, "Depth 1 should match ListDirectory")
+	assert.False(t, gitProject.IsExpanded)
+
+	result, err = s.ListDirectoryDepth(tempDir, 2)
+	require.NoError(t, err)
+	require.True(t, result.Success)
+	gitProject = findNode(result.Directories, "go_project_with_git")
+	require.NotNil(t, gitProject)
+	assert.True(t, gitProject.IsExpanded)
+	subdir := findNode(gitProject.Children, "subdir")
+	require.NotNil(t, subdir, "Children should be loaded one level down")
+	assert.True(t, subdir.IsGoProject)
+	assert.Nil(t, findNode(gitProject.Children, ".git"), "Excluded directories should stay excluded")
+	assert.Nil(t, subdir.Children, "Children should not be loaded past the requested depth")
+	assert.False(t, subdir.IsExpanded)
+}
+
+func TestScanner_ListDirectory_ExcludePrefixes(t *testing.T) {
+	tempDir := createTempDirWithStructure(t)
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDir, "regular_dir2", "subdir"), 0755))
+
+	names := func(s *scanner.Scanner) []string {
+		result, err := s.ListDirectory(tempDir)
+		require.NoError(t, err)
+		require.True(t, result.Success, result.Error)
+		var names []string
+		for _, dir := range result.Directories {
+			names = append(names, dir.Name)
+		}
+		return names
+	}
+
+	assert.Contains(t, names(scanner.New()), "regular_dir")
+
+	excluded := names(scanner.New(scanner.WithExcludePrefixes([]string{
+		filepath.Join(tempDir, "regular_dir") + string(filepath.Separator),
+		filepath.Join(tempDir, "go_project_with_mod", "..", "go_project_with_git"),
+	})))
+	assert.NotContains(t, excluded, "regular_dir")
+	assert.NotContains(t, excluded, "go_project_with_git", "Prefixes should be cleaned")
+	assert.Contains(t, excluded, "regular_dir2", "Prefixes should match whole path elements")
+	assert.Contains(t, excluded, "go_project_with_mod")
+
+	gitProject := filepath.Join(tempDir, "go_project_with_git")
+	result, err := scanner.New().ListDirectory(gitProject)
+	require.NoError(t, err)
+	require.NotEmpty(t, result.Directories)
+	result, err = scanner.New(scanner.WithExcludePrefixes([]string{gitProject})).ListDirectory(gitProject)
+	require.NoError(t, err)
+	assert.Empty(t, result.Directories, "Directories below a prefix should be skipped at any level")
+}
+
+func TestScanner_ListDirectory_IncludeHidden(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Hidden directory rules apply to Linux and macOS")
+	}
+	tempDir := createTempDirWithStructure(t)
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDir, ".hidden_project"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDir, ".git", "objects"), 0755))
+	goMod := filepath.Join(tempDir, ".hidden_project", "go.mod")
+	require.NoError(t, os.WriteFile(goMod, []byte("module hidden\n"), 0644))
+
+	names := func(s *scanner.Scanner) []string {
+		result, err := s.ListDirectory(tempDir)
+		require.NoError(t, err)
+		require.True(t, result.Success, result.Error)
+		var names []string
+		for _, dir := range result.Directories {
+			names = append(names, dir.Name)
+		}
+		return names
+	}
+
+	assert.NotContains(t, names(scanner.New()), ".hidden_project")
+
+	included := names(scanner.New(scanner.WithIncludeHidden(true)))
+	assert.Contains(t, included, ".hidden_project")
+	assert.NotContains(t, included, ".git", "Version control directories should stay excluded")
+}
+
+func TestScanner_ProjectCache(t *testing.T) {
+	tempDir := t.TempDir()
+	project := filepath.Join(tempDir, "project")
+	require.NoError(t, os.MkdirAll(filepath.Join(project, ".git"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(project, "sub"), 0755))
+
+	isGoProject := func(s *scanner.Scanner) bool {
+		result, err := s.ListDirectory(tempDir)
+		require.NoError(t, err)
+		require.Len(t, result.Directories, 1)
+		return result.Directories[0].IsGoProject
+	}
+
+	cached := scanner.New(scanner.WithProjectCache(scanner.NewProjectCache()))
+	assert.False(t, isGoProject(cached))
+
+	// Adding a nested go.mod leaves the project directory's modification time unchanged
+	require.NoError(t, os.WriteFile(filepath.Join(project, "sub", "go.mod"), []byte("module sub\n"), 0644))
+	assert.True(t, isGoProject(scanner.New()))
+	assert.False(t, isGoProject(cached), "Results should be reused while the modification time is unchanged")
+
+	later := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(project, later, later))
+	assert.True(t, isGoProject(cached), "A new modification time should invalidate the cached result")
+}
+
+func TestScanner_FindEnclosingModule(t *testing.T) {
+	tempDir, err := filepath.EvalSymlinks(t.TempDir())
+	require.NoError(t, err)
+	moduleDir := filepath.Join(tempDir, "module")
+	nested := filepath.Join(moduleDir, "internal", "graph")
+	require.NoError(t, os.MkdirAll(filepath.Join(nested, "sub"), 0755))
+	goMod := "// Example module\nmodule example.com/module\n\ngo 1.21\n"
+	require.NoError(t, os.WriteFile(filepath.Join(moduleDir, "go.mod"), []byte(goMod), 0644))
+
+	s := scanner.New()
+	moduleRoot, moduleName, found := s.FindEnclosingModule(nested)
+	assert.True(t, found)
+	assert.Equal(t, moduleDir, moduleRoot)
+	assert.Equal(t, "example.com/module", moduleName)
+
+	_, _, found = s.FindEnclosingModule(tempDir)
+	assert.False(t, found, "Directories outside any module should not be matched")
+
+	result, err := s.ListDirectory(nested)
+	require.NoError(t, err)
+	assert.Equal(t, moduleDir, result.ModuleRoot)
+	assert.Equal(t, "example.com/module", result.ModuleName)
+}
+
+func TestScanner_ListDirectory_Gitignore(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, dir := range []string{
+		"release", "out", "data.cache", "keep.cache", "docs/api/generated", "src/gen", "src/out", "src/tools",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Join(tempDir, dir), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, dir, "main.go"), []byte("package main\n"), 0644))
+	}
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDir, ".git"), 0755))
+	rootIgnore := "# Build output\nrelease/\n/out\n*.cache\n!keep.cache\ndocs/**/generated\n"
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, ".gitignore"), []byte(rootIgnore), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "src", ".gitignore"), []byte("gen\n"), 0644))
+
+	names := func(s *scanner.Scanner, dir string) []string {
+		result, err := s.ListDirectory(filepath.Join(tempDir, dir))
+		require.NoError(t, err)
+		require.True(t, result.Success, result.Error)
+		var names []string
+		for _, node := range result.Directories {
+			names = append(names, node.Name)
+		}
+		return names
+	}
+
+	assert.Contains(t, names(scanner.New(), "."), "release", "Gitignore should only apply when enabled")
+
+	s := scanner.New(scanner.WithGitignore(true))
+	assert.ElementsMatch(t, []string{"docs", "keep.cache", "src"}, names(s, "."))
+	assert.Empty(t, names(s, "docs/api"), "** should match any directories in between")
+	assert.ElementsMatch(t, []string{"out", "tools"}, names(s, "src"),
+		"Nested .gitignore files should apply, and anchored patterns only at their own level")
+}
+
 func TestScanner_ListDirectory_GoProjectDetection(t *testing.T) {
 	s := scanner.New()
 
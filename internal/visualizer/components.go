@@ -0,0 +1,59 @@
+package visualizer
+
+import (
+	"github.com/cvsouth/go-package-analyzer/internal/analyzer"
+)
+
+// GenerateDOTPerComponent creates one DOT document per weakly connected component of the
+// graph, so independent subsystems can be rendered separately. Each document is keyed by a
+// representative package: the entry package for its component, otherwise the component's
+// first package path. With WithLayerCutoff, the components are those of the layers shown.
+func (v *Visualizer) GenerateDOTPerComponent(graph *analyzer.DependencyGraph) map[string]string {
+	groupColors := v.groupColors(graph)
+	graph = v.visibleGraph(graph)
+	documents := make(map[string]string)
+	for _, component := range graph.Components() {
+		subgraph := componentGraph(graph, component)
+		key := component[0]
+		if subgraph.EntryPackage != "" {
+			key = subgraph.EntryPackage
+		}
+		documents[key] = v.renderDOT(subgraph, groupColors)
+	}
+	return documents
+}
+
+// componentGraph restricts a graph to the given packages, keeping their layers, in order and
+// without empty ones, and the cycles among them. The entry package is kept only if included.
+func componentGraph(graph *analyzer.DependencyGraph, component []string) *analyzer.DependencyGraph {
+	subgraph := &analyzer.DependencyGraph{
+		Packages:   make(map[string]*analyzer.PackageInfo, len(component)),
+		ModuleName: graph.ModuleName,
+	}
+	for _, pkgPath := range component {
+		subgraph.Packages[pkgPath] = graph.Packages[pkgPath]
+	}
+	if _, included := subgraph.Packages[graph.EntryPackage]; included {
+		subgraph.EntryPackage = graph.EntryPackage
+	}
+
+	for _, layer := range graph.Layers {
+		var kept []string
+		for _, pkgPath := range layer {
+			if _, included := subgraph.Packages[pkgPath]; included {
+				kept = append(kept, pkgPath)
+			}
+		}
+		if len(kept) > 0 {
+			subgraph.Layers = append(subgraph.Layers, kept)
+		}
+	}
+
+	for _, cycle := range graph.Cycles {
+		if _, included := subgraph.Packages[cycle.Packages[0]]; included {
+			subgraph.Cycles = append(subgraph.Cycles, cycle)
+		}
+	}
+
+	return subgraph
+}
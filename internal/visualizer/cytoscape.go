@@ -0,0 +1,92 @@
+package visualizer
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/cvsouth/go-package-analyzer/internal/analyzer"
+)
+
+// CytoscapeJSON is the Cytoscape.js export of a dependency graph, ready to pass as the
+// elements of a cytoscape instance.
+type CytoscapeJSON struct {
+	Elements CytoscapeElements `json:"elements"`
+}
+
+// CytoscapeElements holds the nodes and edges of a Cytoscape.js export.
+type CytoscapeElements struct {
+	Nodes []CytoscapeNode `json:"nodes"` // Sorted by path
+	Edges []CytoscapeEdge `json:"edges"` // Sorted by source, then target
+}
+
+// CytoscapeNode is a Cytoscape.js node element.
+type CytoscapeNode struct {
+	Data CytoscapeNodeData `json:"data"`
+}
+
+// CytoscapeNodeData describes a package. The id is the package path.
+type CytoscapeNodeData struct {
+	ID        string `json:"id"`
+	Path      string `json:"path"`
+	Name      string `json:"name"`
+	Layer     int    `json:"layer"`
+	FileCount int    `json:"fileCount"`
+}
+
+// CytoscapeEdge is a Cytoscape.js edge element.
+type CytoscapeEdge struct {
+	Data CytoscapeEdgeData `json:"data"`
+}
+
+// CytoscapeEdgeData describes an import from source to target, identified as "source->target".
+type CytoscapeEdgeData struct {
+	ID       string `json:"id"`
+	Source   string `json:"source"`
+	Target   string `json:"target"`
+	Circular bool   `json:"circular"` // The import is part of a cycle, drawn red in the DOT output
+}
+
+// GenerateCytoscapeJSON creates an indented Cytoscape.js export of the graph, with one node per
+// package and one edge per import between packages in the graph. As in the DOT output, edges into
+// external packages are left out with WithHideExternalEdges.
+// HTML escaping is disabled so package paths are written verbatim.
+func (v *Visualizer) GenerateCytoscapeJSON(graph *analyzer.DependencyGraph) ([]byte, error) {
+	export := CytoscapeJSON{Elements: CytoscapeElements{
+		Nodes: make([]CytoscapeNode, 0, len(graph.Packages)),
+		Edges: []CytoscapeEdge{},
+	}}
+	circularDependencies := v.detectCircularDependencies(graph)
+
+	for _, pkgPath := range v.getSortedPackagePaths(graph) {
+		pkg := graph.Packages[pkgPath]
+		export.Elements.Nodes = append(export.Elements.Nodes, CytoscapeNode{Data: CytoscapeNodeData{
+			ID:        pkgPath,
+			Path:      pkgPath,
+			Name:      pkg.Name,
+			Layer:     pkg.Layer,
+			FileCount: pkg.FileCount,
+		}})
+
+		for _, dep := range v.getSortedDependencies(pkg, graph) {
+			if v.hideExternalEdges && v.isExternalPackage(dep, graph.ModuleName) {
+				continue
+			}
+			export.Elements.Edges = append(export.Elements.Edges, CytoscapeEdge{Data: CytoscapeEdgeData{
+				ID:       pkgPath + "->" + dep,
+				Source:   pkgPath,
+				Target:   dep,
+				Circular: circularDependencies[pkgPath][dep],
+			}})
+		}
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(export); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,53 @@
+package visualizer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cvsouth/go-package-analyzer/internal/scanner"
+)
+
+// Directory tree colors: Go projects stand out from the directories leading to them.
+const (
+	goProjectColor = "#6fdc8c" // Same mint as the first dependency path
+	directoryColor = "#8a8a8a"
+)
+
+// GenerateDirectoryDOT creates a DOT representation of a scanned directory tree, rooted at
+// rootPath and laid out left to right, with Go project directories highlighted. Only the
+// children the scanner loaded are drawn, so the tree's depth follows the scan.
+func (v *Visualizer) GenerateDirectoryDOT(rootPath string, directories []*scanner.DirectoryNode) string {
+	var dot strings.Builder
+	dot.WriteString("digraph directories {\n")
+	dot.WriteString("  bgcolor=\"transparent\";\n")
+	dot.WriteString("  rankdir=LR;\n")
+	dot.WriteString("  node [shape=box, style=filled, fontname=\"JetBrains Mono\", fontsize=11, penwidth=2];\n")
+	dot.WriteString("  edge [color=\"" + directoryColor + "\"];\n")
+
+	// Sequential IDs avoid collisions between paths that sanitize alike
+	nextID := 0
+	writeNode := func(label string, isGoProject bool) string {
+		nodeID := fmt.Sprintf("dir_%d", nextID)
+		nextID++
+		color := directoryColor
+		if isGoProject {
+			color = goProjectColor
+		}
+		fmt.Fprintf(&dot, "  %s [label=\"%s\", fillcolor=\"%s\", color=\"%s\", fontcolor=\"white\"];\n",
+			nodeID, v.escapeHTML(label), v.hexToRGBA(color, fillColorOpacity), color)
+		return nodeID
+	}
+
+	var writeTree func(parentID string, nodes []*scanner.DirectoryNode)
+	writeTree = func(parentID string, nodes []*scanner.DirectoryNode) {
+		for _, node := range nodes {
+			nodeID := writeNode(node.Name, node.IsGoProject)
+			fmt.Fprintf(&dot, "  %s -> %s;\n", parentID, nodeID)
+			writeTree(nodeID, node.Children)
+		}
+	}
+
+	writeTree(writeNode(rootPath, false), directories)
+	dot.WriteString("}\n")
+	return dot.String()
+}
@@ -0,0 +1,42 @@
+package visualizer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// graphvizTimeout bounds a single run of Graphviz, so a hung or very slow dot cannot block its caller.
+const graphvizTimeout = 30 * time.Second
+
+// ErrGraphvizNotFound is returned when Graphviz's dot command is needed but not on the PATH,
+// for use with errors.Is.
+var ErrGraphvizNotFound = errors.New("graphviz dot command not found")
+
+// runGraphviz runs `dot -T<format>` on a DOT document and returns its output. The run is stopped
+// when ctx is done or after graphvizTimeout, whichever comes first.
+func runGraphviz(ctx context.Context, dot, format string) ([]byte, error) {
+	dotPath, err := exec.LookPath("dot")
+	if err != nil {
+		return nil, ErrGraphvizNotFound
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, graphvizTimeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, dotPath, "-T"+format)
+	cmd.Stdin = strings.NewReader(dot)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if runErr := cmd.Run(); runErr != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, fmt.Errorf("dot -T%s: %w", format, ctxErr)
+		}
+		return nil, fmt.Errorf("dot -T%s: %w: %s", format, runErr, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,91 @@
+package visualizer
+
+import (
+	"bytes"
+	"context"
+	_ "embed" // For the HTML page template
+	"fmt"
+	"html/template"
+	"regexp"
+
+	"github.com/cvsouth/go-package-analyzer/internal/analyzer"
+)
+
+//go:embed page.html.tmpl
+var pageTemplateText string
+
+// pageTemplate renders the standalone HTML page of GenerateHTML.
+var pageTemplate = template.Must(template.New("page.html.tmpl").Parse(pageTemplateText))
+
+// nodeClass matches the class Graphviz writes on the group of each node in SVG.
+var nodeClass = regexp.MustCompile(`class="node( [^"]*)?"`)
+
+// metricClass matches a node class added by WithMetricAttributes.
+var metricClass = regexp.MustCompile(` gpa-(files|layer|fanin)-(-?\d+)`)
+
+// pageData is passed to the HTML page template.
+type pageData struct {
+	Title    string
+	SVG      template.HTML // Rendered by Graphviz, without the XML prolog
+	Packages []pagePackage // Sorted by path
+}
+
+// pagePackage is an entry of the page's package list, linked to its node by DOT node ID.
+type pagePackage struct {
+	ID    string
+	Path  string
+	Label string
+}
+
+// GenerateHTML creates a self-contained HTML page showing the graph rendered to SVG, next to a
+// list of packages that highlights a package's node when clicked. The SVG is rendered ahead of
+// time by Graphviz's dot command, which must be on the PATH when the page is generated,
+// otherwise ErrGraphvizNotFound is returned; viewing the page needs neither Graphviz nor
+// network access. Rendering stops when ctx is done.
+func (v *Visualizer) GenerateHTML(ctx context.Context, graph *analyzer.DependencyGraph) ([]byte, error) {
+	data := pageData{Title: graph.EntryPackage}
+	if data.Title == "" {
+		data.Title = graph.ModuleName
+	}
+
+	// The package list matches the nodes drawn
+	groupColors := v.groupColors(graph)
+	graph = v.visibleGraph(graph)
+	svg, err := runGraphviz(ctx, v.renderDOT(graph, groupColors), "svg")
+	if err != nil {
+		return nil, fmt.Errorf("rendering SVG: %w", err)
+	}
+	// Drop the XML declaration and doctype, which are not allowed inside an HTML document
+	if start := bytes.Index(svg, []byte("<svg")); start > 0 {
+		svg = svg[start:]
+	}
+	if v.metricAttributes {
+		svg = metricDataAttributes(svg)
+	}
+	data.SVG = template.HTML(svg) //nolint:gosec // Graphviz escapes the labels it renders
+	for _, pkgPath := range v.getSortedPackagePaths(graph) {
+		data.Packages = append(data.Packages, pagePackage{
+			ID:    v.sanitizeNodeID(pkgPath),
+			Path:  pkgPath,
+			Label: v.getLabelPath(graph.Packages[pkgPath], pkgPath, graph.ModuleName),
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := pageTemplate.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// metricDataAttributes copies the metric classes on the node groups of an SVG rendered by
+// Graphviz into data-gpa-files, data-gpa-layer and data-gpa-fanin attributes.
+func metricDataAttributes(svg []byte) []byte {
+	return nodeClass.ReplaceAllFunc(svg, func(class []byte) []byte {
+		attributes := bytes.Clone(class)
+		for _, metric := range metricClass.FindAllSubmatch(class, -1) {
+			attributes = fmt.Appendf(attributes, ` data-gpa-%s="%s"`, metric[1], metric[2])
+		}
+		return attributes
+	})
+}
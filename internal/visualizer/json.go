@@ -0,0 +1,214 @@
+package visualizer
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/cvsouth/go-package-analyzer/internal/analyzer"
+)
+
+// GraphJSON is the JSON export of a dependency graph.
+type GraphJSON struct {
+	ModuleName   string           `json:"moduleName"`
+	EntryPackage string           `json:"entryPackage"`
+	EntryName    string           `json:"entryName"` // Short name of the entry package
+	Packages     []PackageJSON    `json:"packages"`  // Sorted by path
+	Layers       [][]string       `json:"layers"`
+	Cycles       []analyzer.Cycle `json:"cycles"`
+
+	// Analysis options the graph was built with, so an export is read as it was meant
+	IncludedExternal bool     `json:"includedExternal"`
+	ExcludedDirs     []string `json:"excludedDirs"`
+}
+
+// PackageJSON is the JSON export of a single package.
+type PackageJSON struct {
+	Path         string       `json:"path"`
+	Name         string       `json:"name"`
+	Module       string       `json:"module,omitempty"` // Owning module, empty for unattributed external packages
+	Layer        int          `json:"layer"`
+	FileCount    int          `json:"fileCount"`
+	Dependencies []string     `json:"dependencies"`      // Sorted, limited to packages in the export
+	Context      bool         `json:"context,omitempty"` // Included only as a neighbor of a matching package
+	Dir          string       `json:"dir,omitempty"`     // Absolute directory, only with JSONWithFiles
+	Files        []SourceJSON `json:"files,omitempty"`   // Sorted by path, only with JSONWithFiles
+}
+
+// SourceJSON is a Go file of an exported package.
+type SourceJSON struct {
+	Path string `json:"path"` // Absolute path
+}
+
+// jsonConfig selects the packages written by GenerateJSON.
+type jsonConfig struct {
+	include []string
+	exclude []string
+	files   bool
+}
+
+// JSONOption configures GenerateJSON.
+type JSONOption func(*jsonConfig)
+
+// JSONWithInclude limits the export to packages matching at least one of the patterns, plus
+// their neighbors. Patterns use the exclude syntax, where * matches any sequence of characters,
+// and are matched against both the full import path and, for packages of the graph's module,
+// the path relative to the module root.
+func JSONWithInclude(patterns ...string) JSONOption {
+	return func(c *jsonConfig) {
+		c.include = append(c.include, patterns...)
+	}
+}
+
+// JSONWithExclude drops packages matching any of the patterns from the matching set, with the
+// same syntax as JSONWithInclude. Excluded packages can still appear as neighbors.
+func JSONWithExclude(patterns ...string) JSONOption {
+	return func(c *jsonConfig) {
+		c.exclude = append(c.exclude, patterns...)
+	}
+}
+
+// JSONWithFiles adds each package's directory and Go source files, with absolute paths, so
+// editor integrations can jump from a package to its code. External packages
+// that were not analyzed have neither.
+func JSONWithFiles() JSONOption {
+	return func(c *jsonConfig) {
+		c.files = true
+	}
+}
+
+// GenerateJSON creates an indented JSON export of the graph, including its cycles.
+// HTML escaping is disabled so package paths are written verbatim.
+//
+// With JSONWithInclude or JSONWithExclude, only matching packages are exported, along with
+// their direct neighbors for context: the packages a matching package imports and the
+// packages importing one. Neighbors are marked with Context and are not expanded further.
+// Dependencies are limited to exported packages, layers keep their positions with only
+// exported members, and cycles are kept when they pass through a matching package.
+func (v *Visualizer) GenerateJSON(graph *analyzer.DependencyGraph, opts ...JSONOption) ([]byte, error) {
+	var config jsonConfig
+	for _, opt := range opts {
+		opt(&config)
+	}
+	matching, exported := v.jsonSelection(graph, &config)
+
+	export := GraphJSON{
+		ModuleName:   graph.ModuleName,
+		EntryPackage: graph.EntryPackage,
+		EntryName:    graph.EntryName,
+		Packages:     make([]PackageJSON, 0, len(exported)),
+		Layers:       filterLayers(graph.Layers, exported),
+		Cycles:       []analyzer.Cycle{},
+
+		IncludedExternal: graph.IncludedExternal,
+		ExcludedDirs:     append([]string{}, graph.ExcludedDirs...),
+	}
+	for _, cycle := range graph.Cycles {
+		for _, pkgPath := range cycle.Packages {
+			if matching[pkgPath] {
+				export.Cycles = append(export.Cycles, cycle)
+				break
+			}
+		}
+	}
+
+	for _, pkgPath := range v.getSortedPackagePaths(graph) {
+		if !exported[pkgPath] {
+			continue
+		}
+		pkg := graph.Packages[pkgPath]
+		deps := []string{}
+		for _, dep := range v.getSortedDependencies(pkg, graph) {
+			if exported[dep] {
+				deps = append(deps, dep)
+			}
+		}
+		pkgJSON := PackageJSON{
+			Path:         pkgPath,
+			Name:         pkg.Name,
+			Module:       pkg.Module,
+			Layer:        pkg.Layer,
+			FileCount:    pkg.FileCount,
+			Dependencies: deps,
+			Context:      !matching[pkgPath],
+		}
+		if config.files {
+			pkgJSON.Dir = pkg.Dir
+			for _, file := range pkg.Files {
+				pkgJSON.Files = append(pkgJSON.Files, SourceJSON{Path: file.Path})
+			}
+		}
+		export.Packages = append(export.Packages, pkgJSON)
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(export); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// jsonSelection returns the packages matching the configured patterns and the packages to
+// export: the matching ones and their direct neighbors. Without patterns every package matches.
+func (v *Visualizer) jsonSelection(
+	graph *analyzer.DependencyGraph,
+	config *jsonConfig,
+) (map[string]bool, map[string]bool) {
+	matching := make(map[string]bool)
+	for pkgPath := range graph.Packages {
+		included := len(config.include) == 0 || matchesAnyPattern(pkgPath, graph.ModuleName, config.include)
+		if included && !matchesAnyPattern(pkgPath, graph.ModuleName, config.exclude) {
+			matching[pkgPath] = true
+		}
+	}
+
+	exported := make(map[string]bool, len(matching))
+	for pkgPath, pkg := range graph.Packages {
+		if matching[pkgPath] {
+			exported[pkgPath] = true
+		}
+		for _, dep := range pkg.Dependencies {
+			if _, exists := graph.Packages[dep]; !exists {
+				continue
+			}
+			if matching[pkgPath] {
+				exported[dep] = true
+			}
+			if matching[dep] {
+				exported[pkgPath] = true
+			}
+		}
+	}
+	return matching, exported
+}
+
+// matchesAnyPattern reports whether a package's import path, or its path relative to the
+// module for packages of the module, matches any of the patterns.
+func matchesAnyPattern(pkgPath, moduleName string, patterns []string) bool {
+	relPath := analyzer.RelativePath(pkgPath, moduleName)
+	for _, pattern := range patterns {
+		if analyzer.MatchPattern(pkgPath, pattern) || analyzer.MatchPattern(relPath, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterLayers returns the layers restricted to exported packages. Emptied layers are kept,
+// so layer indexes still match each package's Layer.
+func filterLayers(layers [][]string, exported map[string]bool) [][]string {
+	filtered := make([][]string, 0, len(layers))
+	for _, layer := range layers {
+		kept := []string{}
+		for _, pkgPath := range layer {
+			if exported[pkgPath] {
+				kept = append(kept, pkgPath)
+			}
+		}
+		filtered = append(filtered, kept)
+	}
+	return filtered
+}
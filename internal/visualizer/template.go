@@ -0,0 +1,42 @@
+package visualizer
+
+import (
+	_ "embed" // For the default DOT template
+	"text/template"
+
+	"github.com/cvsouth/go-package-analyzer/internal/analyzer"
+)
+
+//go:embed default.dot.tmpl
+var defaultTemplateText string
+
+// defaultTemplate renders the standard DOT output.
+var defaultTemplate = template.Must(template.New("default.dot.tmpl").Parse(defaultTemplateText))
+
+// TemplateData is passed to the DOT template. Statements are complete DOT lines, indented
+// and terminated with a semicolon, in the order the default template writes them.
+type TemplateData struct {
+	Graph         *analyzer.DependencyGraph
+	RankDir       string   // Graphviz rank direction, e.g. "TB"
+	NodeSep       string   // Space between nodes of a rank, in inches
+	RankSep       string   // Space between ranks, in inches
+	NodeMarginX   string   // Horizontal node margin, in inches
+	NodeMarginY   string   // Vertical node margin, in inches
+	Nodes         []string // Node statements, wrapped in module clusters when enabled
+	Edges         []string // Dependency edge statements
+	CircularEdges []string // Edge statements for imports that are part of a cycle
+	Constraints   []string // Rank constraint statements
+	Deterministic bool     // Set by WithDeterministicLayout: leave out overlap removal and packing
+}
+
+// WithTemplate renders the DOT output with a custom text/template instead of the embedded
+// default, giving full control over the header and styling. The template receives a
+// *TemplateData. If executing it fails, the default template is used instead.
+// A nil template keeps the default.
+func WithTemplate(tmpl *template.Template) Option {
+	return func(v *Visualizer) {
+		if tmpl != nil {
+			v.template = tmpl
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package visualizer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidDOT is returned by Validate, for use with errors.Is.
+var ErrInvalidDOT = errors.New("invalid DOT")
+
+// Validate checks that dot is syntactically sound DOT with ValidateStructure and, when Graphviz
+// is on the PATH, by parsing it with `dot -Tnull`, which catches what the structural check does
+// not. The Graphviz run stops when ctx is done or after a bounded time.
+func (v *Visualizer) Validate(ctx context.Context, dot string) error {
+	if err := v.ValidateStructure(dot); err != nil {
+		return err
+	}
+
+	if _, err := runGraphviz(ctx, dot, "null"); err != nil && !errors.Is(err, ErrGraphvizNotFound) {
+		return fmt.Errorf("%w: %w", ErrInvalidDOT, err)
+	}
+	return nil
+}
+
+// ValidateStructure checks that dot is syntactically sound DOT: braces and brackets are
+// balanced, attribute lists are closed before the statement ends, strings and comments are
+// terminated, and every unquoted ID is a valid DOT identifier or numeral. It needs no Graphviz,
+// so it is cheap enough to run on every generated graph.
+func (v *Visualizer) ValidateStructure(dot string) error {
+	if err := checkDOTStructure(dot); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidDOT, err)
+	}
+	return nil
+}
+
+// dotScanner walks a DOT document, tracking the open braces and brackets.
+type dotScanner struct {
+	src   string
+	pos   int
+	line  int
+	open  []byte // Open '{' and '[' characters, innermost last
+	lines []int  // Line of each open character
+}
+
+// checkDOTStructure runs the structural part of Validate.
+func checkDOTStructure(dot string) error {
+	s := &dotScanner{src: dot, line: 1}
+	sawBody := false
+	for s.pos < len(s.src) {
+		c := s.src[s.pos]
+		var err error
+		switch {
+		case c == '\n':
+			s.line++
+			s.pos++
+		case c == ' ' || c == '\t' || c == '\r':
+			s.pos++
+		case c == '#' && s.atLineStart():
+			s.skipLine()
+		case strings.HasPrefix(s.src[s.pos:], "//"):
+			s.skipLine()
+		case strings.HasPrefix(s.src[s.pos:], "/*"):
+			err = s.skipBlockComment()
+		case c == '"':
+			err = s.skipString()
+		case c == '<':
+			err = s.skipHTMLString()
+		case c == '{' || c == '[':
+			if c == '{' && s.inAttributeList() {
+				return fmt.Errorf("line %d: '{' inside the attribute list opened on line %d", s.line, s.openLine())
+			}
+			sawBody = sawBody || c == '{'
+			s.open = append(s.open, c)
+			s.lines = append(s.lines, s.line)
+			s.pos++
+		case c == '}' || c == ']':
+			err = s.close(c)
+		case c == ';':
+			if s.inAttributeList() {
+				return fmt.Errorf("line %d: statement ends inside the attribute list opened on line %d",
+					s.line, s.openLine())
+			}
+			s.pos++
+		case c == ',' || c == '=' || c == ':':
+			s.pos++
+		case strings.HasPrefix(s.src[s.pos:], "->") || strings.HasPrefix(s.src[s.pos:], "--"):
+			s.pos += 2
+		default:
+			err = s.skipID()
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(s.open) > 0 {
+		return fmt.Errorf("'%c' opened on line %d is never closed", s.open[len(s.open)-1], s.openLine())
+	}
+	if !sawBody {
+		return errors.New("no graph body")
+	}
+	return nil
+}
+
+// atLineStart reports whether only whitespace precedes the current position on its line.
+func (s *dotScanner) atLineStart() bool {
+	lineStart := strings.LastIndexByte(s.src[:s.pos], '\n') + 1
+	return strings.TrimSpace(s.src[lineStart:s.pos]) == ""
+}
+
+// inAttributeList reports whether the innermost open character is '['.
+func (s *dotScanner) inAttributeList() bool {
+	return len(s.open) > 0 && s.open[len(s.open)-1] == '['
+}
+
+// openLine returns the line of the innermost open character.
+func (s *dotScanner) openLine() int {
+	return s.lines[len(s.lines)-1]
+}
+
+// close pops the innermost open character, which must match c.
+func (s *dotScanner) close(c byte) error {
+	want := byte('{')
+	if c == ']' {
+		want = '['
+	}
+	if len(s.open) == 0 {
+		return fmt.Errorf("line %d: unmatched '%c'", s.line, c)
+	}
+	if s.open[len(s.open)-1] != want {
+		return fmt.Errorf("line %d: '%c' closes the '%c' opened on line %d",
+			s.line, c, s.open[len(s.open)-1], s.openLine())
+	}
+	s.open = s.open[:len(s.open)-1]
+	s.lines = s.lines[:len(s.lines)-1]
+	s.pos++
+	return nil
+}
+
+// skipLine moves to the newline ending the current line.
+func (s *dotScanner) skipLine() {
+	if end := strings.IndexByte(s.src[s.pos:], '\n'); end >= 0 {
+		s.pos += end
+	} else {
+		s.pos = len(s.src)
+	}
+}
+
+// skipBlockComment moves past a /* */ comment.
+func (s *dotScanner) skipBlockComment() error {
+	end := strings.Index(s.src[s.pos+2:], "*/")
+	if end < 0 {
+		return fmt.Errorf("line %d: unterminated comment", s.line)
+	}
+	s.advance(end + len("/**/"))
+	return nil
+}
+
+// skipString moves past a quoted string, in which a backslash escapes the next character.
+func (s *dotScanner) skipString() error {
+	start := s.line
+	for i := s.pos + 1; i < len(s.src); i++ {
+		switch s.src[i] {
+		case '\\':
+			i++
+		case '"':
+			s.advance(i + 1 - s.pos)
+			return nil
+		}
+	}
+	return fmt.Errorf("line %d: unterminated string", start)
+}
+
+// skipHTMLString moves past an HTML string, whose angle brackets must be balanced.
+func (s *dotScanner) skipHTMLString() error {
+	start := s.line
+	depth := 0
+	for i := s.pos; i < len(s.src); i++ {
+		switch s.src[i] {
+		case '<':
+			depth++
+		case '>':
+			depth--
+			if depth == 0 {
+				s.advance(i + 1 - s.pos)
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("line %d: unterminated HTML string", start)
+}
+
+// skipID moves past an unquoted ID: a letter or underscore followed by letters, digits and
+// underscores, or a numeral. Non-ASCII bytes count as letters, as in Graphviz.
+func (s *dotScanner) skipID() error {
+	rest := s.src[s.pos:]
+	if n := dotIdentifierLength(rest); n > 0 {
+		s.pos += n
+		return nil
+	}
+	if n := dotNumeralLength(rest); n > 0 {
+		s.pos += n
+		return nil
+	}
+	return fmt.Errorf("line %d: invalid character %q in ID", s.line, rest[0])
+}
+
+// advance moves n bytes forward, counting the newlines passed.
+func (s *dotScanner) advance(n int) {
+	s.line += strings.Count(s.src[s.pos:s.pos+n], "\n")
+	s.pos += n
+}
+
+// dotIdentifierLength returns the length of the identifier at the start of text, or 0.
+func dotIdentifierLength(text string) int {
+	isLetter := func(c byte) bool {
+		return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c >= 0x80
+	}
+	if text == "" || !isLetter(text[0]) {
+		return 0
+	}
+	n := 1
+	for n < len(text) && (isLetter(text[n]) || isDigit(text[n])) {
+		n++
+	}
+	return n
+}
+
+// dotNumeralLength returns the length of the numeral at the start of text, or 0.
+func dotNumeralLength(text string) int {
+	n := 0
+	if n < len(text) && text[n] == '-' {
+		n++
+	}
+	digits := 0
+	for n < len(text) && isDigit(text[n]) {
+		n++
+		digits++
+	}
+	if n < len(text) && text[n] == '.' {
+		n++
+		for n < len(text) && isDigit(text[n]) {
+			n++
+			digits++
+		}
+	}
+	if digits == 0 {
+		return 0
+	}
+	// An identifier character straight after a numeral, as in "2a", is not a valid ID
+	if n < len(text) && dotIdentifierLength(text[n:]) > 0 {
+		return 0
+	}
+	return n
+}
+
+// isDigit reports whether c is an ASCII digit.
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
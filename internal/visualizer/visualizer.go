@@ -3,9 +3,13 @@ package visualizer
 
 import (
 	"fmt"
+	"hash/fnv"
+	"log/slog"
 	"sort"
 	"strconv"
 	"strings"
+	"text/template"
+	"unicode/utf8"
 
 	"github.com/cvsouth/go-package-analyzer/internal/analyzer"
 )
@@ -17,63 +21,390 @@ const (
 	hexColorLength   = 6    // Standard hex color length (RRGGBB)
 )
 
+// entryBadge is the label line marking the entry package with WithEntryHighlight.
+const entryBadge = "▶ entry"
+
+// churnBadge prefixes the churn of packages flagged by WithChurnBadge.
+const churnBadge = "⚠ churn"
+
+// Node font sizes with WithComplexitySizing, in points. The default template uses the minimum.
+const (
+	minComplexityFontSize = 11
+	maxComplexityFontSize = 22
+)
+
+// crossModuleEdgeAttributes style an edge between modules with WithCrossModuleEdges.
+const crossModuleEdgeAttributes = ", style=\"bold,dashed\""
+
+// Default spacing, in inches.
+const (
+	defaultNodeSep     = 1.0 // Horizontal space between nodes of a rank
+	defaultRankSep     = 1.5 // Vertical space between ranks
+	defaultNodeMarginX = 0.4 // Horizontal space between a node's label and its border
+	defaultNodeMarginY = 0.3 // Vertical space between a node's label and its border
+)
+
 // Visualizer generates DOT representations of package dependency graphs.
-type Visualizer struct{}
+type Visualizer struct {
+	hideExternalEdges bool
+	edgeImportCounts  bool
+	communities       map[string]int
+	rankMode          RankMode
+	rankDir           string
+	edgePorts         bool
+	nodeSep           float64
+	rankSep           float64
+	nodeMarginX       float64
+	nodeMarginY       float64
+	moduleClusters    bool
+	entryHighlight    bool
+	crossModuleEdges  bool
+	metricAttributes  bool
+	deterministic     bool
+	churnBadgeChurn   int // Minimum churn for the churn badge, 0 to disable it
+	churnBadgeFanIn   int
+	complexitySizing  bool
+	dependencyBadge   bool
+	maxLabelLen       int // Characters kept of a wrapped name or path, 0 for no limit
+	layerCutoff       int // First layer hidden from the rendering, 0 to show every layer
+	grouper           func(pkgPath string) string
+	labelPath         LabelPathMode
+	template          *template.Template
+}
+
+// Graphviz rank directions accepted by WithRankDir.
+const (
+	RankDirTopToBottom = "TB"
+	RankDirBottomToTop = "BT"
+	RankDirLeftToRight = "LR"
+	RankDirRightToLeft = "RL"
+)
+
+// RankMode selects how nodes are assigned to ranks in the DOT output.
+type RankMode int
+
+const (
+	// RankByLayer ranks packages by their computed dependency layer (the default).
+	RankByLayer RankMode = iota
+	// RankByPathDepth ranks packages by the number of path segments below the module root,
+	// so all internal/* packages share a rank, all internal/*/* the next, and so on.
+	RankByPathDepth
+)
+
+// LabelPathMode selects the path shown on the last line of each node label.
+type LabelPathMode int
+
+const (
+	// LabelPathRelative shows the path relative to the module root, "/" for the root package (the default).
+	LabelPathRelative LabelPathMode = iota
+	// LabelPathImport shows the full import path.
+	LabelPathImport
+	// LabelPathDir shows the package's absolute directory, falling back to the import path
+	// for packages without one, such as external leaves.
+	LabelPathDir
+)
+
+// Option configures a Visualizer.
+type Option func(*Visualizer)
+
+// WithHideExternalEdges suppresses edges whose target is an external package.
+// External nodes are still rendered, labelled with how many packages import them.
+func WithHideExternalEdges(hide bool) Option {
+	return func(v *Visualizer) {
+		v.hideExternalEdges = hide
+	}
+}
+
+// WithEdgeImportCounts labels each edge with the number of files in the source package
+// that import the target. Edges without import counts are left unlabelled.
+func WithEdgeImportCounts(show bool) Option {
+	return func(v *Visualizer) {
+		v.edgeImportCounts = show
+	}
+}
+
+// WithCommunities colors packages by community ID (see analyzer.DetectCommunities)
+// instead of by top-level directory. Packages missing from the map keep directory coloring.
+func WithCommunities(communities map[string]int) Option {
+	return func(v *Visualizer) {
+		v.communities = communities
+	}
+}
+
+// WithRankMode selects between dependency-layer ranks and path-depth ranks.
+func WithRankMode(mode RankMode) Option {
+	return func(v *Visualizer) {
+		v.rankMode = mode
+	}
+}
+
+// WithRankDir sets the Graphviz rank direction. Unknown directions are ignored,
+// leaving the default top-to-bottom layout.
+func WithRankDir(dir string) Option {
+	return func(v *Visualizer) {
+		switch dir {
+		case RankDirTopToBottom, RankDirBottomToTop, RankDirLeftToRight, RankDirRightToLeft:
+			v.rankDir = dir
+		}
+	}
+}
+
+// WithEdgePorts makes dependency edges leave the bottom of the importing node and enter
+// the top of the imported one, which keeps orthogonal routing clear of node borders.
+// It only applies to the top-to-bottom rank direction; circular edges are never ported.
+func WithEdgePorts(enabled bool) Option {
+	return func(v *Visualizer) {
+		v.edgePorts = enabled
+	}
+}
+
+// WithNodeSep sets the space between nodes of the same rank, in inches.
+// Non-positive values are ignored.
+func WithNodeSep(inches float64) Option {
+	return func(v *Visualizer) {
+		if inches > 0 {
+			v.nodeSep = inches
+		}
+	}
+}
+
+// WithRankSep sets the space between ranks, in inches. Non-positive values are ignored.
+func WithRankSep(inches float64) Option {
+	return func(v *Visualizer) {
+		if inches > 0 {
+			v.rankSep = inches
+		}
+	}
+}
+
+// WithNodeMargin sets the horizontal and vertical space between a node's label and its
+// border, in inches. It is ignored unless both values are positive.
+func WithNodeMargin(x, y float64) Option {
+	return func(v *Visualizer) {
+		if x > 0 && y > 0 {
+			v.nodeMarginX = x
+			v.nodeMarginY = y
+		}
+	}
+}
+
+// WithModuleClusters wraps the packages of each module in a cluster labelled with the
+// module path, using PackageInfo.Module. Packages without a module are left outside any cluster.
+func WithModuleClusters(enabled bool) Option {
+	return func(v *Visualizer) {
+		v.moduleClusters = enabled
+	}
+}
+
+// WithGrouper assigns packages to named groups, such as teams or bounded contexts, with a
+// caller-provided function. Each group is drawn as a cluster labelled with its name and colored
+// by it, overriding the default grouping by top-level directory, communities and
+// WithModuleClusters. Packages for which grouper returns "" are left outside any cluster and
+// keep the default coloring. The function is called several times per package and should
+// return the same name each time.
+func WithGrouper(grouper func(pkgPath string) string) Option {
+	return func(v *Visualizer) {
+		v.grouper = grouper
+	}
+}
+
+// WithCrossModuleEdges draws edges between packages of different modules bold and dashed,
+// so the points where modules couple stand out. Packages without a module never count.
+func WithCrossModuleEdges(enabled bool) Option {
+	return func(v *Visualizer) {
+		v.crossModuleEdges = enabled
+	}
+}
+
+// WithMetricAttributes adds each node's raw metrics as custom attributes, gpa_files, gpa_layer
+// and gpa_fanin, so tooling can read them without parsing labels. Graphviz drops unknown
+// attributes from SVG, so the metrics are also written as node classes such as gpa-files-3,
+// which SVG keeps and GenerateHTML turns into data-gpa-files, data-gpa-layer and data-gpa-fanin
+// attributes.
+func WithMetricAttributes(enabled bool) Option {
+	return func(v *Visualizer) {
+		v.metricAttributes = enabled
+	}
+}
+
+// WithDeterministicLayout writes a header for reproducible rendering, as golden-image tests
+// need: the fixed start seed is kept while overlap removal, separation margins and packing,
+// whose results vary between Graphviz versions, are left out. The document is identical for
+// identical graphs, cycle styling included. Layouts can still differ
+// between Graphviz releases, so baselines should be rendered with the version the web viewer
+// bundles, @hpcc-js/wasm 2.13.0.
+func WithDeterministicLayout(enabled bool) Option {
+	return func(v *Visualizer) {
+		v.deterministic = enabled
+	}
+}
+
+// WithChurnBadge flags refactoring risks: packages whose PackageInfo.Churn (see
+// analyzer.WithChurn) is at least minChurn and that at least minFanIn packages import get a
+// "⚠ churn N" line in their label. A non-positive minChurn disables the badge.
+func WithChurnBadge(minChurn, minFanIn int) Option {
+	return func(v *Visualizer) {
+		v.churnBadgeChurn = max(minChurn, 0)
+		v.churnBadgeFanIn = minFanIn
+	}
+}
+
+// WithComplexitySizing scales each package's node by its PackageInfo.Complexity (see
+// analyzer.WithComplexity) and adds a "complexity N" line to its label. Font sizes grow
+// linearly from the default for packages without complexity to twice that for the graph's
+// most complex package, and nodes grow with their labels.
+func WithComplexitySizing(enabled bool) Option {
+	return func(v *Visualizer) {
+		v.complexitySizing = enabled
+	}
+}
+
+// WithDependencyBadge appends a "(Nd)" badge to each node's file count, where N is the
+// number of packages of the module the package imports directly.
+func WithDependencyBadge(enabled bool) Option {
+	return func(v *Visualizer) {
+		v.dependencyBadge = enabled
+	}
+}
+
+// WithMaxLabelLen bounds node sizes in dense graphs: a package name or label path longer than
+// n characters after wrapping is cut to n characters followed by "…", and the node gets a
+// tooltip with the full name and path. A non-positive n disables truncation.
+func WithMaxLabelLen(n int) Option {
+	return func(v *Visualizer) {
+		v.maxLabelLen = max(n, 0)
+	}
+}
+
+// WithLayerCutoff shows only the upper architecture: packages in layer n and the layers below
+// it, counting the entry's layer as 0, are left out of the rendering along with their edges.
+// Unlike the analyzer's depth limits it does not change the graph. A non-positive n, or one
+// beyond the graph's layers, shows every layer.
+func WithLayerCutoff(n int) Option {
+	return func(v *Visualizer) {
+		v.layerCutoff = max(n, 0)
+	}
+}
+
+// WithLabelPath selects the path shown in node labels. Unknown modes are ignored,
+// leaving module-relative paths.
+func WithLabelPath(mode LabelPathMode) Option {
+	return func(v *Visualizer) {
+		switch mode {
+		case LabelPathRelative, LabelPathImport, LabelPathDir:
+			v.labelPath = mode
+		}
+	}
+}
+
+// WithEntryHighlight marks the entry package beyond its color: its node gets a double
+// border and an "▶ entry" badge above the package name.
+func WithEntryHighlight(enabled bool) Option {
+	return func(v *Visualizer) {
+		v.entryHighlight = enabled
+	}
+}
 
 // New creates a new visualizer.
-func New() *Visualizer {
-	return &Visualizer{}
+func New(opts ...Option) *Visualizer {
+	v := &Visualizer{
+		rankDir:     RankDirTopToBottom,
+		template:    defaultTemplate,
+		nodeSep:     defaultNodeSep,
+		rankSep:     defaultRankSep,
+		nodeMarginX: defaultNodeMarginX,
+		nodeMarginY: defaultNodeMarginY,
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
 }
 
 // GenerateDOTContent creates DOT format content for Graphviz.
 func (v *Visualizer) GenerateDOTContent(
 	graph *analyzer.DependencyGraph,
 ) string {
+	return v.renderDOT(v.visibleGraph(graph), v.groupColors(graph))
+}
+
+// renderDOT executes the DOT template for a graph already restricted by visibleGraph, coloring
+// packages with the groupColors of the unrestricted graph.
+func (v *Visualizer) renderDOT(graph *analyzer.DependencyGraph, groupColors map[string]string) string {
+	data := v.templateData(graph, groupColors)
+
 	var dot strings.Builder
+	if err := v.template.Execute(&dot, data); err != nil {
+		slog.Warn("Warning: DOT template failed, using the default", "error", err)
+		dot.Reset()
+		// The default template only reads fields of TemplateData, so it cannot fail
+		_ = defaultTemplate.Execute(&dot, data)
+	}
+	return dot.String()
+}
 
-	v.writeDOTHeader(&dot)
+// visibleGraph restricts a graph to the layers above the WithLayerCutoff layer. A cutoff that
+// would hide no layer is reported and ignored.
+func (v *Visualizer) visibleGraph(graph *analyzer.DependencyGraph) *analyzer.DependencyGraph {
+	if v.layerCutoff == 0 {
+		return graph
+	}
+	if v.layerCutoff >= len(graph.Layers) {
+		slog.Warn("Warning: layer cutoff is beyond the graph's layers, showing every layer",
+			"cutoff", v.layerCutoff, "layers", len(graph.Layers))
+		return graph
+	}
 
+	var kept []string
+	for _, layer := range graph.Layers[:v.layerCutoff] {
+		kept = append(kept, layer...)
+	}
+	return componentGraph(graph, kept)
+}
+
+// templateData prepares the nodes, edges and rank constraints of a graph for the DOT template.
+func (v *Visualizer) templateData(graph *analyzer.DependencyGraph, groupColors map[string]string) *TemplateData {
 	// Prepare data for node and edge generation
 	packagePaths := v.getSortedPackagePaths(graph)
 	circularDependencies := v.detectCircularDependencies(graph)
-	dependencyPaths := v.initializeDependencyPaths(graph)
 
 	// Generate nodes and edges
-	nodeLines := v.generateNodes(graph, packagePaths, dependencyPaths)
-	normalEdges, circularEdges := v.generateEdges(graph, packagePaths, circularDependencies, dependencyPaths)
-
-	// Write output
-	v.writeNodes(&dot, nodeLines)
-	v.writeEdges(&dot, normalEdges, circularEdges)
-	v.writeLayerConstraints(&dot, graph)
+	nodeLines := v.generateNodes(graph, packagePaths, groupColors)
+	normalEdges, circularEdges := v.generateEdges(graph, packagePaths, circularDependencies, groupColors)
+	switch {
+	case v.grouper != nil:
+		nodeLines = v.clusterNodes(packagePaths, nodeLines, "group", v.grouper)
+	case v.moduleClusters:
+		nodeLines = v.clusterNodes(packagePaths, nodeLines, "module", func(pkgPath string) string {
+			return graph.Packages[pkgPath].Module
+		})
+	}
 
-	dot.WriteString("}\n")
-	return dot.String()
+	var constraints strings.Builder
+	v.writeLayerConstraints(&constraints, graph)
+
+	return &TemplateData{
+		Graph:         graph,
+		RankDir:       v.rankDir,
+		NodeSep:       formatInches(v.nodeSep),
+		RankSep:       formatInches(v.rankSep),
+		NodeMarginX:   formatInches(v.nodeMarginX),
+		NodeMarginY:   formatInches(v.nodeMarginY),
+		Nodes:         nodeLines,
+		Edges:         normalEdges,
+		CircularEdges: circularEdges,
+		Constraints:   strings.FieldsFunc(constraints.String(), func(r rune) bool { return r == '\n' }),
+		Deterministic: v.deterministic,
+	}
 }
 
-// writeDOTHeader writes the DOT file header and configuration.
-func (v *Visualizer) writeDOTHeader(dot *strings.Builder) {
-	dot.WriteString("digraph dependencies {\n")
-	dot.WriteString("  bgcolor=\"transparent\";\n")
-	dot.WriteString("  rankdir=TB;\n")
-	dot.WriteString("  splines=ortho;\n")
-	dot.WriteString("  nodesep=1.0;\n") // Increased from 0.8
-	dot.WriteString("  ranksep=1.5;\n") // Increased from 1.2
-	dot.WriteString("  concentrate=true;\n")
-	dot.WriteString("  start=42;\n")           // Fixed seed for deterministic layout
-	dot.WriteString("  ordering=out;\n")       // Consistent edge ordering
-	dot.WriteString("  overlap=false;\n")      // Prevent node overlap
-	dot.WriteString("  sep=\"+30,30\";\n")     // Increased separation
-	dot.WriteString("  esep=\"+15,15\";\n")    // Increased edge separation
-	dot.WriteString("  dpi=96;\n")             // Fixed DPI for consistent sizing
-	dot.WriteString("  margin=\"1,1\";\n")     // Increased margin to prevent cropping
-	dot.WriteString("  pad=\"1,1\";\n")        // Increased padding around the graph
-	dot.WriteString("  packmode=\"graph\";\n") // Better packing to prevent overflow
-	dot.WriteString(
-		"  node [shape=box, style=filled, fontname=\"JetBrains Mono\", fontsize=11, penwidth=2, margin=\"0.4,0.3\", width=0, height=0, fixedsize=false];\n",
-	)
-	dot.WriteString("  edge [fontsize=10, labelangle=0, labeldistance=1.5];\n")
-	dot.WriteString("  \n")
+// formatInches formats a length for DOT, always with a decimal point (1 becomes "1.0").
+func formatInches(inches float64) string {
+	formatted := strconv.FormatFloat(inches, 'f', -1, 64)
+	if !strings.Contains(formatted, ".") {
+		formatted += ".0"
+	}
+	return formatted
 }
 
 // getSortedPackagePaths returns a sorted slice of package paths for deterministic processing.
@@ -86,56 +417,143 @@ func (v *Visualizer) getSortedPackagePaths(graph *analyzer.DependencyGraph) []st
 	return packagePaths
 }
 
-// initializeDependencyPaths sets up the dependency path tracking with entry point.
-func (v *Visualizer) initializeDependencyPaths(graph *analyzer.DependencyGraph) map[string]int {
-	dependencyPaths := make(map[string]int)
-	// Entry point gets violet (first color)
-	entryDepPath := v.getDependencyPath(graph.EntryPackage, graph.ModuleName)
-	dependencyPaths[entryDepPath] = 0
-	return dependencyPaths
-}
-
 // generateNodes creates all node definitions for the DOT output.
 func (v *Visualizer) generateNodes(
 	graph *analyzer.DependencyGraph,
 	packagePaths []string,
-	dependencyPaths map[string]int,
+	groupColors map[string]string,
 ) []string {
 	var nodeLines []string
+	maxComplexity := 0
+	for _, pkgPath := range packagePaths {
+		maxComplexity = max(maxComplexity, graph.Packages[pkgPath].Complexity)
+	}
 
 	for _, pkgPath := range packagePaths {
 		pkg := graph.Packages[pkgPath]
 		nodeID := v.sanitizeNodeID(pkgPath)
 
 		// Determine border color based on dependency path
-		borderColor := v.getPackageColors(pkgPath, graph.ModuleName, dependencyPaths)
+		borderColor := v.getPackageColors(pkgPath, graph.ModuleName, groupColors)
 
 		// Create fill color as 5% opacity version of border color
 		fillColor := v.hexToRGBA(borderColor, fillColorOpacity)
 
 		// Create simple label with package name, file count, and path
-		relativePath := v.getRelativePath(pkgPath, graph.ModuleName)
-		wrappedPath := v.wrapText(relativePath, textWrapWidth) // Wrap path at 25 characters
-		wrappedName := v.wrapText(pkg.Name, textWrapWidth)     // Wrap package name at 25 characters
-		label := fmt.Sprintf("%s\\n%d files\\n%s",
-			v.escapeHTML(wrappedName),
-			pkg.FileCount,
-			v.escapeHTML(wrappedPath))
-
-		nodeLine := fmt.Sprintf("  %s [label=\"%s\", fillcolor=\"%s\", color=\"%s\", fontcolor=\"white\"];",
-			nodeID, label, fillColor, borderColor)
+		labelPath := v.getLabelPath(pkg, pkgPath, graph.ModuleName)
+		wrappedPath := v.wrapText(labelPath, textWrapWidth) // Wrap path at 25 characters
+		wrappedName := v.wrapText(pkg.Name, textWrapWidth)  // Wrap package name at 25 characters
+		truncatedPath, pathTruncated := v.truncateLabel(wrappedPath)
+		truncatedName, nameTruncated := v.truncateLabel(wrappedName)
+		countLine := fmt.Sprintf("%d files", pkg.FileCount)
+		if v.hideExternalEdges && v.isExternalPackage(pkgPath, graph.ModuleName) {
+			// Without edges, show how many packages reference this external package instead
+			countLine = fmt.Sprintf("imported by %d", v.countImporters(pkgPath, graph))
+		}
+		styleAttr := ""
+		if pkg.EmptyForTarget {
+			// Build constraints exclude every file for the analyzed GOOS/GOARCH
+			countLine = "no files for target"
+			styleAttr = "style=\"filled,dotted\", "
+		}
+		if v.dependencyBadge {
+			countLine += fmt.Sprintf(" (%dd)", v.countInternalDependencies(pkg, graph.ModuleName))
+		}
+		if v.isPartialPackage(pkg) {
+			// Some files failed to parse, so the dependency set may be missing imports
+			countLine += " (partial)"
+			styleAttr = "style=\"filled,dashed\", "
+		}
+		if pkg.ReplacedBy != "" {
+			// Name the local override next to the original import path
+			countLine += "\\nreplaced by\\n" + v.escapeHTML(v.wrapText(pkg.ReplacedBy, textWrapWidth))
+		}
+		if v.isChurnRisk(pkg, pkgPath, graph) {
+			countLine += fmt.Sprintf("\\n%s %d", churnBadge, pkg.Churn)
+		}
+		if v.complexitySizing {
+			countLine += fmt.Sprintf("\\ncomplexity %d", pkg.Complexity)
+			styleAttr += fmt.Sprintf("fontsize=%d, ", complexityFontSize(pkg.Complexity, maxComplexity))
+		}
+		label := fmt.Sprintf("%s\\n%s\\n%s",
+			v.escapeHTML(truncatedName),
+			countLine,
+			v.escapeHTML(truncatedPath))
+		if pathTruncated || nameTruncated {
+			styleAttr += fmt.Sprintf("tooltip=\"%s\\n%s\", ", v.escapeHTML(pkg.Name), v.escapeHTML(labelPath))
+		}
+		if pkgPath == analyzer.CollapsedExternalPackage {
+			// The stand-in for collapsed externals has no files or path of its own
+			label = v.escapeHTML(wrappedName)
+		}
+		if v.entryHighlight && pkgPath == graph.EntryPackage {
+			label = entryBadge + "\\n" + label
+			styleAttr += "peripheries=2, "
+		}
+		if v.metricAttributes {
+			fileCount, fanIn := pkg.FileCount, v.countImporters(pkgPath, graph)
+			styleAttr += fmt.Sprintf("gpa_files=%d, gpa_layer=%d, gpa_fanin=%d, ", fileCount, pkg.Layer, fanIn)
+			styleAttr += fmt.Sprintf("class=\"gpa-files-%d gpa-layer-%d gpa-fanin-%d\", ", fileCount, pkg.Layer, fanIn)
+		}
+
+		nodeLine := fmt.Sprintf("  %s [label=\"%s\", %sfillcolor=\"%s\", color=\"%s\", fontcolor=\"white\"];",
+			nodeID, label, styleAttr, fillColor, borderColor)
 		nodeLines = append(nodeLines, nodeLine)
 	}
 
 	return nodeLines
 }
 
+// clusterNodes regroups node lines, given in packagePaths order, into one cluster per key,
+// sorted by key and labelled with it. Cluster IDs are prefixed by kind. Nodes with an empty key
+// follow the clusters.
+func (v *Visualizer) clusterNodes(
+	packagePaths, nodeLines []string,
+	kind string,
+	key func(pkgPath string) string,
+) []string {
+	nodesByCluster := make(map[string][]string)
+	var unclustered []string
+	for i, pkgPath := range packagePaths {
+		cluster := key(pkgPath)
+		if cluster == "" {
+			unclustered = append(unclustered, nodeLines[i])
+			continue
+		}
+		nodesByCluster[cluster] = append(nodesByCluster[cluster], nodeLines[i])
+	}
+
+	clusters := make([]string, 0, len(nodesByCluster))
+	for cluster := range nodesByCluster {
+		clusters = append(clusters, cluster)
+	}
+	sort.Strings(clusters)
+
+	var lines []string
+	for i, cluster := range clusters {
+		lines = append(lines,
+			fmt.Sprintf("  subgraph cluster_%s_%d {", kind, i),
+			fmt.Sprintf("    label=\"%s\";", v.escapeHTML(cluster)),
+			"    style=\"rounded,dashed\";",
+			"    color=\"#888888\";",
+			"    fontcolor=\"white\";",
+			"    fontname=\"JetBrains Mono\";",
+		)
+		for _, nodeLine := range nodesByCluster[cluster] {
+			lines = append(lines, "  "+nodeLine)
+		}
+		lines = append(lines, "  }")
+	}
+
+	return append(lines, unclustered...)
+}
+
 // generateEdges creates all edge definitions, separating normal and circular dependencies.
 func (v *Visualizer) generateEdges(
 	graph *analyzer.DependencyGraph,
 	packagePaths []string,
 	circularDependencies map[string]map[string]bool,
-	dependencyPaths map[string]int,
+	groupColors map[string]string,
 ) ([]string, []string) {
 	var normalEdgeLines []string
 	var circularEdgeLines []string
@@ -143,19 +561,41 @@ func (v *Visualizer) generateEdges(
 	for _, pkgPath := range packagePaths {
 		pkg := graph.Packages[pkgPath]
 		fromID := v.sanitizeNodeID(pkgPath)
-		sourceBorderColor := v.getPackageColors(pkgPath, graph.ModuleName, dependencyPaths)
+		sourceBorderColor := v.getPackageColors(pkgPath, graph.ModuleName, groupColors)
 
 		// Sort dependencies for consistent edge ordering
 		deps := v.getSortedDependencies(pkg, graph)
 
 		for _, dep := range deps {
+			if v.hideExternalEdges && v.isExternalPackage(dep, graph.ModuleName) {
+				continue
+			}
+
 			toID := v.sanitizeNodeID(dep)
+			labelAttrs := v.edgeLabelAttributes(pkg, dep)
+			if v.crossModuleEdges && isCrossModuleEdge(pkg, graph.Packages[dep]) {
+				labelAttrs += crossModuleEdgeAttributes
+			}
+			if dep == graph.EntryPackage && graph.Packages[dep].IsImportedEntry {
+				// Imports of the entry point back up the graph, so they must not rank the entry
+				labelAttrs += ", constraint=false"
+			}
 
 			if circularDependencies[pkgPath][dep] {
-				edgeLine := v.createCircularEdge(fromID, toID, circularDependencies, pkgPath, dep)
+				// A mutual import is drawn once, from the lexically smaller package, as a
+				// double-headed edge, unless the reverse edge is hidden
+				bidirectional := circularDependencies[dep][pkgPath] &&
+					!(v.hideExternalEdges && v.isExternalPackage(pkgPath, graph.ModuleName))
+				if bidirectional && dep < pkgPath {
+					continue
+				}
+				if bidirectional {
+					labelAttrs += v.reverseEdgeLabelAttributes(graph.Packages[dep], pkgPath)
+				}
+				edgeLine := v.createCircularEdge(fromID, toID, bidirectional, labelAttrs)
 				circularEdgeLines = append(circularEdgeLines, edgeLine)
 			} else {
-				edgeLine := v.createNormalEdge(fromID, toID, sourceBorderColor)
+				edgeLine := v.createNormalEdge(fromID, toID, sourceBorderColor, labelAttrs)
 				normalEdgeLines = append(normalEdgeLines, edgeLine)
 			}
 		}
@@ -180,54 +620,143 @@ func (v *Visualizer) getSortedDependencies(pkg *analyzer.PackageInfo, graph *ana
 	return deps
 }
 
-// createCircularEdge creates a circular dependency edge with appropriate styling.
-func (v *Visualizer) createCircularEdge(
-	fromID, toID string,
-	circularDependencies map[string]map[string]bool,
-	pkgPath, dep string,
-) string {
-	edgeDirection := ""
-	// Check if this is a bidirectional dependency (both directions exist)
-	if circularDependencies[dep] != nil && circularDependencies[dep][pkgPath] {
-		edgeDirection = ", dir=both"
+// isExternalPackage checks if a package lies outside the graph's module.
+func (v *Visualizer) isExternalPackage(pkgPath, moduleName string) bool {
+	return pkgPath != moduleName && !strings.HasPrefix(pkgPath, moduleName+"/")
+}
+
+// countInternalDependencies returns the number of module packages pkg imports.
+func (v *Visualizer) countInternalDependencies(pkg *analyzer.PackageInfo, moduleName string) int {
+	count := 0
+	for _, dep := range pkg.Dependencies {
+		if !v.isExternalPackage(dep, moduleName) {
+			count++
+		}
 	}
-	return fmt.Sprintf("  %s -> %s [color=\"red\", penwidth=1.5%s];", fromID, toID, edgeDirection)
+	return count
 }
 
-// createNormalEdge creates a normal dependency edge.
-func (v *Visualizer) createNormalEdge(fromID, toID, sourceBorderColor string) string {
-	return fmt.Sprintf("  %s -> %s [color=\"%s\", penwidth=1.5];", fromID, toID, sourceBorderColor)
+// isPartialPackage checks if an analyzed package had files that failed to parse.
+// Only packages read from disk (those with a directory) have parse results.
+func (v *Visualizer) isPartialPackage(pkg *analyzer.PackageInfo) bool {
+	return pkg.Dir != "" && !pkg.Complete
+}
+
+// truncateLabel cuts wrapped text, whose lines are separated by DOT \n escapes, after
+// WithMaxLabelLen characters not counting the separators, and appends "…". It reports
+// whether the text was cut.
+func (v *Visualizer) truncateLabel(wrapped string) (string, bool) {
+	if v.maxLabelLen == 0 {
+		return wrapped, false
+	}
+	kept := 0
+	for i := 0; i < len(wrapped); {
+		if strings.HasPrefix(wrapped[i:], "\\n") {
+			i += len("\\n")
+			continue
+		}
+		if kept == v.maxLabelLen {
+			return strings.TrimSuffix(wrapped[:i], "\\n") + "…", true
+		}
+		_, size := utf8.DecodeRuneInString(wrapped[i:])
+		i += size
+		kept++
+	}
+	return wrapped, false
+}
+
+// complexityFontSize returns the node font size of a package with the given complexity,
+// scaled between the minimum and maximum size relative to the most complex package.
+func complexityFontSize(complexity, maxComplexity int) int {
+	if maxComplexity == 0 {
+		return minComplexityFontSize
+	}
+	growth := (maxComplexityFontSize - minComplexityFontSize) * complexity / maxComplexity
+	return minComplexityFontSize + growth
+}
+
+// isChurnRisk reports whether WithChurnBadge flags a package as both frequently changed and
+// widely imported.
+func (v *Visualizer) isChurnRisk(pkg *analyzer.PackageInfo, pkgPath string, graph *analyzer.DependencyGraph) bool {
+	if v.churnBadgeChurn == 0 || pkg.Churn < v.churnBadgeChurn {
+		return false
+	}
+	return v.countImporters(pkgPath, graph) >= v.churnBadgeFanIn
+}
+
+// countImporters counts the packages in the graph that depend on the given package.
+func (v *Visualizer) countImporters(pkgPath string, graph *analyzer.DependencyGraph) int {
+	count := 0
+	for _, pkg := range graph.Packages {
+		for _, dep := range pkg.Dependencies {
+			if dep == pkgPath {
+				count++
+				break
+			}
+		}
+	}
+	return count
 }
 
-// writeNodes writes all node definitions to the DOT output.
-func (v *Visualizer) writeNodes(dot *strings.Builder, nodeLines []string) {
-	for _, line := range nodeLines {
-		dot.WriteString(line + "\n")
+// edgeLabelAttributes returns the extra DOT attributes labelling an edge with its import count.
+// The label is a head label so it is placed by the labeldistance set in the header.
+func (v *Visualizer) edgeLabelAttributes(pkg *analyzer.PackageInfo, dep string) string {
+	if !v.edgeImportCounts || pkg.ImportCounts[dep] == 0 {
+		return ""
 	}
-	dot.WriteString("  \n")
+	return fmt.Sprintf(", headlabel=\"%d\"", pkg.ImportCounts[dep])
+}
+
+// isCrossModuleEdge reports whether an import joins packages attributed to different modules,
+// as analyzer.CrossModuleEdges does.
+func isCrossModuleEdge(from, to *analyzer.PackageInfo) bool {
+	return from.Module != "" && to.Module != "" && from.Module != to.Module
 }
 
-// writeEdges writes all edge definitions to the DOT output.
-func (v *Visualizer) writeEdges(dot *strings.Builder, normalEdges, circularEdges []string) {
-	// Output normal edges first
-	for _, line := range normalEdges {
-		dot.WriteString(line + "\n")
+// reverseEdgeLabelAttributes labels the tail of a double-headed edge with the number of files
+// of the target package importing the source, when import counts are shown.
+func (v *Visualizer) reverseEdgeLabelAttributes(dep *analyzer.PackageInfo, pkgPath string) string {
+	if !v.edgeImportCounts || dep.ImportCounts[pkgPath] == 0 {
+		return ""
 	}
+	return fmt.Sprintf(", taillabel=\"%d\"", dep.ImportCounts[pkgPath])
+}
 
-	// Output circular edges last (so they appear "on top")
-	for _, line := range circularEdges {
-		dot.WriteString(line + "\n")
+// createCircularEdge creates a red edge for a circular dependency, double-headed when the
+// packages import each other.
+func (v *Visualizer) createCircularEdge(fromID, toID string, bidirectional bool, labelAttrs string) string {
+	edgeDirection := ""
+	if bidirectional {
+		edgeDirection = ", dir=both"
 	}
+	return fmt.Sprintf("  %s -> %s [color=\"red\", penwidth=1.5%s%s];", fromID, toID, edgeDirection, labelAttrs)
+}
+
+// createNormalEdge creates a normal dependency edge.
+func (v *Visualizer) createNormalEdge(fromID, toID, sourceBorderColor, labelAttrs string) string {
+	if v.edgePorts && v.rankDir == RankDirTopToBottom {
+		fromID += ":s"
+		toID += ":n"
+	}
+	return fmt.Sprintf("  %s -> %s [color=\"%s\", penwidth=1.5%s];", fromID, toID, sourceBorderColor, labelAttrs)
 }
 
 // writeLayerConstraints writes layer constraints and entry point ranking to the DOT output.
 func (v *Visualizer) writeLayerConstraints(dot *strings.Builder, graph *analyzer.DependencyGraph) {
-	dot.WriteString("  \n")
+	if v.rankMode == RankByPathDepth {
+		v.generatePathDepthConstraints(dot, graph)
+		return
+	}
 
-	// First, set the entry package to be at the top with highest rank
+	// First, set the entry package to be at the top with highest rank. An imported entry may
+	// share the top rank, as rank=source would force its incoming edges to point upward.
 	if graph.EntryPackage != "" {
 		entryNodeID := v.sanitizeNodeID(graph.EntryPackage)
-		fmt.Fprintf(dot, "  { rank=source; %s; }\n", entryNodeID)
+		rank := "source"
+		if entry, exists := graph.Packages[graph.EntryPackage]; exists && entry.IsImportedEntry {
+			rank = "min"
+		}
+		fmt.Fprintf(dot, "  { rank=%s; %s; }\n", rank, entryNodeID)
 	}
 
 	// Generate rank constraints for each layer
@@ -247,6 +776,42 @@ func (v *Visualizer) generateLayerConstraints(dot *strings.Builder, graph *analy
 	}
 }
 
+// generatePathDepthConstraints groups module packages into rank=same constraints by path depth.
+// External packages are left unconstrained.
+func (v *Visualizer) generatePathDepthConstraints(dot *strings.Builder, graph *analyzer.DependencyGraph) {
+	depths := make(map[int][]string)
+	for _, pkgPath := range v.getSortedPackagePaths(graph) {
+		depth, ok := pathDepth(pkgPath, graph.ModuleName)
+		if !ok {
+			continue
+		}
+		depths[depth] = append(depths[depth], v.sanitizeNodeID(pkgPath))
+	}
+
+	sortedDepths := make([]int, 0, len(depths))
+	for depth := range depths {
+		sortedDepths = append(sortedDepths, depth)
+	}
+	sort.Ints(sortedDepths)
+
+	for _, depth := range sortedDepths {
+		fmt.Fprintf(dot, "  { rank=same; %s; }\n", strings.Join(depths[depth], "; "))
+	}
+}
+
+// pathDepth returns the number of path segments of a package below the module root.
+// It reports false for packages outside the module.
+func pathDepth(pkgPath, moduleName string) (int, bool) {
+	if pkgPath == moduleName {
+		return 0, true
+	}
+	relPath, found := strings.CutPrefix(pkgPath, moduleName+"/")
+	if moduleName == "" || !found {
+		return 0, false
+	}
+	return strings.Count(relPath, "/") + 1, true
+}
+
 // processMultiPackageLayer handles layers with multiple packages.
 func (v *Visualizer) processMultiPackageLayer(dot *strings.Builder, layer []string, entryPackage string) {
 	// Sort packages within the layer for deterministic output
@@ -294,100 +859,35 @@ func (v *Visualizer) isLeafPackage(pkgPath string, graph *analyzer.DependencyGra
 	return true
 }
 
-// detectCircularDependencies identifies packages that have circular dependencies.
+// detectCircularDependencies identifies the edges that are part of an import cycle: those
+// between packages of the same strongly connected component. Every such edge lies on some cycle,
+// so the result does not depend on the order a search happens to visit packages in.
 func (v *Visualizer) detectCircularDependencies(graph *analyzer.DependencyGraph) map[string]map[string]bool {
 	circularEdges := make(map[string]map[string]bool)
 
-	// Find all cycles using DFS
-	cycles := v.findAllCycles(graph)
-
-	// Mark all edges that are part of any cycle as circular
-	for _, cycle := range cycles {
-		for i := range cycle {
-			from := cycle[i]
-			to := cycle[(i+1)%len(cycle)]
-
-			if circularEdges[from] == nil {
-				circularEdges[from] = make(map[string]bool)
-			}
-			circularEdges[from][to] = true
-		}
-	}
-
-	return circularEdges
-}
-
-// findAllCycles finds all cycles in the dependency graph using DFS.
-func (v *Visualizer) findAllCycles(graph *analyzer.DependencyGraph) [][]string {
-	var cycles [][]string
-	visited := make(map[string]bool)
-	recStack := make(map[string]bool)
-
-	// Try to find cycles starting from each unvisited node
-	for pkgPath := range graph.Packages {
-		if !visited[pkgPath] {
-			path := []string{}
-			v.dfsForCycles(graph, pkgPath, visited, recStack, path, &cycles)
+	component := make(map[string]int)
+	for i, members := range analyzer.New().StronglyConnectedComponents(graph) {
+		for _, pkgPath := range members {
+			component[pkgPath] = i
 		}
 	}
 
-	return cycles
-}
-
-// dfsForCycles performs DFS to find cycles.
-func (v *Visualizer) dfsForCycles(
-	graph *analyzer.DependencyGraph,
-	node string,
-	visited, recStack map[string]bool,
-	path []string,
-	cycles *[][]string,
-) {
-	visited[node] = true
-	recStack[node] = true
-	path = append(path, node)
-
-	if pkg, exists := graph.Packages[node]; exists {
-		v.processDependenciesForCycles(pkg, graph, visited, recStack, path, cycles)
-	}
-
-	recStack[node] = false
-}
-
-// processDependenciesForCycles processes package dependencies for cycle detection.
-func (v *Visualizer) processDependenciesForCycles(
-	pkg *analyzer.PackageInfo,
-	graph *analyzer.DependencyGraph,
-	visited, recStack map[string]bool,
-	path []string,
-	cycles *[][]string,
-) {
-	for _, dep := range pkg.Dependencies {
-		if _, depExists := graph.Packages[dep]; !depExists {
+	for pkgPath, pkg := range graph.Packages {
+		from, inCycle := component[pkgPath]
+		if !inCycle {
 			continue
 		}
-
-		if !visited[dep] {
-			v.dfsForCycles(graph, dep, visited, recStack, path, cycles)
-		} else if recStack[dep] {
-			v.extractCycleFromPath(dep, path, cycles)
+		for _, dep := range pkg.Dependencies {
+			if to, depInCycle := component[dep]; depInCycle && to == from {
+				if circularEdges[pkgPath] == nil {
+					circularEdges[pkgPath] = make(map[string]bool)
+				}
+				circularEdges[pkgPath][dep] = true
+			}
 		}
 	}
-}
 
-// extractCycleFromPath extracts a cycle from the current path.
-func (v *Visualizer) extractCycleFromPath(dep string, path []string, cycles *[][]string) {
-	cycleStart := -1
-	for i, pathNode := range path {
-		if pathNode == dep {
-			cycleStart = i
-			break
-		}
-	}
-	if cycleStart != -1 {
-		cycle := make([]string, len(path)-cycleStart)
-		copy(cycle, path[cycleStart:])
-		*cycles = append(*cycles, cycle)
-	}
+	return circularEdges
 }
 
 // sanitizeNodeID creates a valid DOT node identifier.
@@ -407,15 +907,24 @@ func (v *Visualizer) sanitizeNodeID(pkgPath string) string {
 	return nodeID
 }
 
+// getLabelPath returns the path shown in a package's node label, as selected by WithLabelPath.
+func (v *Visualizer) getLabelPath(pkg *analyzer.PackageInfo, pkgPath, moduleName string) string {
+	switch v.labelPath {
+	case LabelPathImport:
+		return pkgPath
+	case LabelPathDir:
+		if pkg.Dir != "" {
+			return pkg.Dir
+		}
+		return pkgPath
+	default:
+		return v.getRelativePath(pkgPath, moduleName)
+	}
+}
+
 // getRelativePath returns the path relative to the module (without the module namespace).
 func (v *Visualizer) getRelativePath(pkgPath, moduleName string) string {
-	// Remove module name prefix to get relative path
-	relPath := strings.TrimPrefix(pkgPath, moduleName)
-	relPath = strings.TrimPrefix(relPath, "/")
-	relPath = strings.TrimPrefix(relPath, "\\") // Handle Windows backslashes
-
-	// Normalize path separators to forward slashes for display
-	relPath = strings.ReplaceAll(relPath, "\\", "/")
+	relPath := analyzer.RelativePath(pkgPath, moduleName)
 
 	// If it's the root package, show a meaningful name
 	if relPath == "" {
@@ -444,11 +953,13 @@ func (v *Visualizer) hexToRGBA(hexColor string, opacity float64) string {
 	return fmt.Sprintf("rgba(%d,%d,%d,%.2f)", r, g, b, opacity)
 }
 
-// getPackageColors returns fill and border colors for a package using dependency path coloring.
-func (v *Visualizer) getPackageColors(
-	pkgPath, moduleName string,
-	dependencyPaths map[string]int,
-) string {
+// groupColors assigns a border color to every group of packages in the graph using dependency
+// path coloring. The entry package's group gets the first color and every other group the color
+// its key hashes to, so a group keeps its color when other groups are filtered out. When keys
+// hash to the same color, the later key in sorted order takes the next free color. Colors are
+// assigned over the whole graph, before layer cutoffs or component splitting, so every view of
+// a graph agrees on them, and are shared once groups outnumber them.
+func (v *Visualizer) groupColors(graph *analyzer.DependencyGraph) map[string]string {
 	// Color series: border colors for dependency paths
 	colorSeries := []string{
 		"#6fdc8c", // Bright Pastel Mint
@@ -466,34 +977,60 @@ func (v *Visualizer) getPackageColors(
 		"#ff80bf", // Light Magenta Pink
 	}
 
-	// Get dependency path for this package
-	depPath := v.getDependencyPath(pkgPath, moduleName)
-
-	// Get color index for this dependency path
-	colorIndex, exists := dependencyPaths[depPath]
-	if !exists {
-		// Assign next color in series
-		colorIndex = len(dependencyPaths)
-		dependencyPaths[depPath] = colorIndex
+	entryGroup := v.groupKey(graph.EntryPackage, graph.ModuleName)
+	colors := map[string]string{entryGroup: colorSeries[0]}
+	var groups []string
+	for _, pkgPath := range v.getSortedPackagePaths(graph) {
+		group := v.groupKey(pkgPath, graph.ModuleName)
+		if _, assigned := colors[group]; !assigned {
+			colors[group] = ""
+			groups = append(groups, group)
+		}
 	}
+	sort.Strings(groups)
+
+	palette := colorSeries[1:]
+	taken := make([]bool, len(palette))
+	for _, group := range groups {
+		hash := fnv.New32a()
+		hash.Write([]byte(group))
+		hashed := int(hash.Sum32() % uint32(len(palette)))
+		// Probe for a free color, keeping the hashed one once every color is taken
+		slot := hashed
+		for probe := range len(palette) {
+			if candidate := (hashed + probe) % len(palette); !taken[candidate] {
+				slot = candidate
+				break
+			}
+		}
+		taken[slot] = true
+		colors[group] = palette[slot]
+	}
+	return colors
+}
 
-	// Wrap around if we exceed the color series
-	colorIndex %= len(colorSeries)
-
-	borderColor := colorSeries[colorIndex]
+// getPackageColors returns the border color of a package from the colors of its group.
+func (v *Visualizer) getPackageColors(pkgPath, moduleName string, groupColors map[string]string) string {
+	return groupColors[v.groupKey(pkgPath, moduleName)]
+}
 
-	return borderColor
+// groupKey returns the key packages are colored by: their WithGrouper group, else their
+// community when communities are configured, otherwise their dependency path.
+func (v *Visualizer) groupKey(pkgPath, moduleName string) string {
+	if v.grouper != nil {
+		if group := v.grouper(pkgPath); group != "" {
+			return "group:" + group
+		}
+	}
+	if community, exists := v.communities[pkgPath]; exists {
+		return "community:" + strconv.Itoa(community)
+	}
+	return v.getDependencyPath(pkgPath, moduleName)
 }
 
 // getDependencyPath extracts the dependency path from a package path.
 func (v *Visualizer) getDependencyPath(pkgPath, moduleName string) string {
-	// Get the relative path from module
-	relPath := strings.TrimPrefix(pkgPath, moduleName)
-	relPath = strings.TrimPrefix(relPath, "/")
-	relPath = strings.TrimPrefix(relPath, "\\") // Handle Windows backslashes
-
-	// Normalize path separators to forward slashes
-	relPath = strings.ReplaceAll(relPath, "\\", "/")
+	relPath := analyzer.RelativePath(pkgPath, moduleName)
 
 	// If it's the root package
 	if relPath == "" {
@@ -0,0 +1,22 @@
+package visualizer_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/cvsouth/go-package-analyzer/internal/analyzer"
+	"github.com/cvsouth/go-package-analyzer/internal/visualizer"
+)
+
+func BenchmarkGenerateDOTContent(b *testing.B) {
+	for _, n := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprintf("packages=%d", n), func(b *testing.B) {
+			graph := analyzer.BuildSyntheticGraph(n)
+			viz := visualizer.New()
+
+			for b.Loop() {
+				viz.GenerateDOTContent(graph)
+			}
+		})
+	}
+}
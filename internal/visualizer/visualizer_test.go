@@ -1,10 +1,22 @@
 package visualizer_test
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
 	"strings"
 	"testing"
+	"text/template"
+	"time"
 
 	"github.com/cvsouth/go-package-analyzer/internal/analyzer"
+	"github.com/cvsouth/go-package-analyzer/internal/scanner"
 	"github.com/cvsouth/go-package-analyzer/internal/visualizer"
 )
 
@@ -106,6 +118,39 @@ func TestGenerateDOTContent_CircularDependencies(t *testing.T) {
 	}
 }
 
+func TestGenerateDOTContent_MutualDependencySingleEdge(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "test/main",
+		ModuleName:   "test",
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/main": {Name: "main", Path: "test/main", Dependencies: []string{"test/a"}},
+			"test/a": {
+				Name: "a", Path: "test/a", Dependencies: []string{"test/b"},
+				ImportCounts: map[string]int{"test/b": 2},
+			},
+			"test/b": {
+				Name: "b", Path: "test/b", Dependencies: []string{"test/a"},
+				ImportCounts: map[string]int{"test/a": 3},
+			},
+		},
+	}
+
+	dotContent := visualizer.New().GenerateDOTContent(graph)
+
+	count := strings.Count(dotContent, "test_a -> test_b") + strings.Count(dotContent, "test_b -> test_a")
+	if count != 1 {
+		t.Errorf("Expected exactly one edge line for the mutual dependency, got %d:\n%s", count, dotContent)
+	}
+	if !strings.Contains(dotContent, `  test_a -> test_b [color="red", penwidth=1.5, dir=both];`) {
+		t.Errorf("Expected a double-headed edge from the smaller package, got:\n%s", dotContent)
+	}
+
+	dotContent = visualizer.New(visualizer.WithEdgeImportCounts(true)).GenerateDOTContent(graph)
+	if !strings.Contains(dotContent, `dir=both, headlabel="2", taillabel="3"];`) {
+		t.Errorf("Expected both import counts on the merged edge, got:\n%s", dotContent)
+	}
+}
+
 func TestGenerateDOTContent_LayerConstraints(t *testing.T) {
 	// Create a graph with multiple layers
 	graph := &analyzer.DependencyGraph{
@@ -630,3 +675,1495 @@ func validateNoProblematicChars(t *testing.T, dotContent string, problematicChar
 		}
 	}
 }
+
+func TestGenerateDOTContent_HideExternalEdges(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "test/main",
+		ModuleName:   "test",
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/main": {
+				Name:         "main",
+				Path:         "test/main",
+				Dependencies: []string{"fmt", "test/util"},
+				FileCount:    1,
+			},
+			"test/util": {
+				Name:         "util",
+				Path:         "test/util",
+				Dependencies: []string{"fmt"},
+				FileCount:    1,
+			},
+			"fmt": {
+				Name:         "fmt",
+				Path:         "fmt",
+				Dependencies: []string{},
+			},
+		},
+	}
+
+	dotContent := visualizer.New(visualizer.WithHideExternalEdges(true)).GenerateDOTContent(graph)
+
+	if strings.Contains(dotContent, "-> fmt") {
+		t.Error("Edges to external packages should be suppressed")
+	}
+	if !strings.Contains(dotContent, "test_main -> test_util") {
+		t.Error("Internal edges should still be rendered")
+	}
+	if !strings.Contains(dotContent, "  fmt [label=") {
+		t.Error("External node should still be rendered")
+	}
+	if !strings.Contains(dotContent, "imported by 2") {
+		t.Error("External node label should show its importer count")
+	}
+}
+
+func TestGenerateDOTContent_EdgeImportCounts(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "test/main",
+		ModuleName:   "test",
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/main": {
+				Name:         "main",
+				Path:         "test/main",
+				Dependencies: []string{"test/util", "test/store"},
+				FileCount:    3,
+				ImportCounts: map[string]int{"test/util": 3},
+			},
+			"test/util":  {Name: "util", Path: "test/util", Dependencies: []string{}, FileCount: 1},
+			"test/store": {Name: "store", Path: "test/store", Dependencies: []string{}, FileCount: 1},
+		},
+	}
+
+	defaultContent := visualizer.New().GenerateDOTContent(graph)
+	if strings.Contains(defaultContent, "headlabel") {
+		t.Error("Edges should not be labelled by default")
+	}
+
+	dotContent := visualizer.New(visualizer.WithEdgeImportCounts(true)).GenerateDOTContent(graph)
+	if !strings.Contains(dotContent, "test_main -> test_util [color=\"#6fdc8c\", penwidth=1.5, headlabel=\"3\"];") {
+		t.Errorf("Expected edge labelled with import count, got:\n%s", dotContent)
+	}
+	if !strings.Contains(dotContent, "test_main -> test_store [color=\"#6fdc8c\", penwidth=1.5];") {
+		t.Error("Edges without import counts should be unlabelled")
+	}
+}
+
+func TestGenerateJSON(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "test/main",
+		EntryName:    "main",
+		ModuleName:   "test",
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/main": {Name: "main", Path: "test/main", Dependencies: []string{"test/b", "test/a"}, FileCount: 1},
+			"test/a": {
+				Name: "a", Path: "test/a", Module: "test", Dependencies: []string{"test/b"}, FileCount: 2, Layer: 1,
+			},
+			"test/b": {Name: "b", Path: "test/b", Dependencies: []string{"test/a"}, FileCount: 1, Layer: 1},
+		},
+		Layers: [][]string{{"test/main"}, {"test/a", "test/b"}},
+		Cycles: []analyzer.Cycle{{
+			Packages: []string{"test/a", "test/b"},
+			Edges:    [][2]string{{"test/a", "test/b"}, {"test/b", "test/a"}},
+		}},
+	}
+
+	data, err := visualizer.New().GenerateJSON(graph)
+	if err != nil {
+		t.Fatalf("GenerateJSON failed: %v", err)
+	}
+
+	var export visualizer.GraphJSON
+	if unmarshalErr := json.Unmarshal(data, &export); unmarshalErr != nil {
+		t.Fatalf("Failed to unmarshal export: %v", unmarshalErr)
+	}
+
+	if export.EntryPackage != "test/main" || export.EntryName != "main" {
+		t.Errorf("Expected entry package 'test/main' named 'main', got '%s' named '%s'",
+			export.EntryPackage, export.EntryName)
+	}
+	if len(export.Packages) != 3 || export.Packages[0].Path != "test/a" {
+		t.Fatalf("Expected 3 packages sorted by path, got %+v", export.Packages)
+	}
+	if !reflect.DeepEqual(export.Packages[2].Dependencies, []string{"test/a", "test/b"}) {
+		t.Errorf("Expected sorted dependencies, got %v", export.Packages[2].Dependencies)
+	}
+	if export.Packages[0].Module != "test" || export.Packages[1].Module != "" {
+		t.Errorf("Expected package modules to be exported, got %+v", export.Packages)
+	}
+	if !reflect.DeepEqual(export.Cycles, graph.Cycles) {
+		t.Errorf("Expected cycles %v, got %v", graph.Cycles, export.Cycles)
+	}
+
+	again, err := visualizer.New().GenerateJSON(graph)
+	if err != nil {
+		t.Fatalf("GenerateJSON failed: %v", err)
+	}
+	if string(data) != string(again) {
+		t.Error("JSON export should be deterministic")
+	}
+}
+
+func TestGenerateJSON_PackageFilter(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "test/main",
+		ModuleName:   "test",
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/main":      {Name: "main", Path: "test/main", Dependencies: []string{"test/api"}},
+			"test/api":       {Name: "api", Path: "test/api", Dependencies: []string{"test/store", "fmt"}, Layer: 1},
+			"test/api/mock":  {Name: "mock", Path: "test/api/mock", Dependencies: []string{"test/api"}, Layer: 0},
+			"test/store":     {Name: "store", Path: "test/store", Dependencies: []string{"test/store/sql"}, Layer: 2},
+			"test/store/sql": {Name: "sql", Path: "test/store/sql", Layer: 3},
+			"fmt":            {Name: "fmt", Path: "fmt", Layer: 3},
+			"test/unrelated": {Name: "unrelated", Path: "test/unrelated", Layer: 1},
+		},
+		Layers: [][]string{
+			{"test/api/mock", "test/main"}, {"test/api", "test/unrelated"}, {"test/store"}, {"fmt", "test/store/sql"},
+		},
+	}
+
+	data, err := visualizer.New().GenerateJSON(graph,
+		visualizer.JSONWithInclude("api*"),
+		visualizer.JSONWithExclude("*/mock"))
+	if err != nil {
+		t.Fatalf("GenerateJSON failed: %v", err)
+	}
+	var export visualizer.GraphJSON
+	if unmarshalErr := json.Unmarshal(data, &export); unmarshalErr != nil {
+		t.Fatalf("Failed to unmarshal export: %v", unmarshalErr)
+	}
+
+	packages := make(map[string]visualizer.PackageJSON)
+	for _, pkg := range export.Packages {
+		packages[pkg.Path] = pkg
+	}
+	expected := map[string]bool{
+		"test/api": false, "test/main": true, "test/api/mock": true, "test/store": true, "fmt": true,
+	}
+	if len(packages) != len(expected) {
+		t.Fatalf("Expected matching packages and their neighbors %v, got %+v", expected, export.Packages)
+	}
+	for pkgPath, context := range expected {
+		if pkg, exists := packages[pkgPath]; !exists || pkg.Context != context {
+			t.Errorf("Expected %s exported with context=%v, got %+v", pkgPath, context, pkg)
+		}
+	}
+	if deps := packages["test/store"].Dependencies; len(deps) != 0 {
+		t.Errorf("Expected neighbors not to be expanded, got dependencies %v", deps)
+	}
+	expectedLayers := [][]string{{"test/api/mock", "test/main"}, {"test/api"}, {"test/store"}, {"fmt"}}
+	if !reflect.DeepEqual(export.Layers, expectedLayers) {
+		t.Errorf("Expected layers %v, got %v", expectedLayers, export.Layers)
+	}
+
+	data, err = visualizer.New().GenerateJSON(graph, visualizer.JSONWithInclude("nothing/*"))
+	if err != nil {
+		t.Fatalf("GenerateJSON failed: %v", err)
+	}
+	if !strings.Contains(string(data), `"packages": []`) {
+		t.Errorf("Expected no packages when nothing matches, got:\n%s", data)
+	}
+}
+
+func TestGenerateCytoscapeJSON(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "test/main",
+		ModuleName:   "test",
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/main": {
+				Name: "main", Path: "test/main", Dependencies: []string{"test/a", "fmt"}, FileCount: 1, Layer: 2,
+			},
+			"test/a": {Name: "a", Path: "test/a", Dependencies: []string{"test/b"}, FileCount: 2, Layer: 1},
+			"test/b": {Name: "b", Path: "test/b", Dependencies: []string{"test/a"}, FileCount: 1, Layer: 1},
+			"fmt":    {Name: "fmt", Path: "fmt"},
+		},
+	}
+
+	data, err := visualizer.New().GenerateCytoscapeJSON(graph)
+	if err != nil {
+		t.Fatalf("GenerateCytoscapeJSON failed: %v", err)
+	}
+
+	var export struct {
+		Elements struct {
+			Nodes []struct {
+				Data map[string]any `json:"data"`
+			} `json:"nodes"`
+			Edges []struct {
+				Data map[string]any `json:"data"`
+			} `json:"edges"`
+		} `json:"elements"`
+	}
+	if unmarshalErr := json.Unmarshal(data, &export); unmarshalErr != nil {
+		t.Fatalf("Failed to unmarshal export: %v", unmarshalErr)
+	}
+
+	if len(export.Elements.Nodes) != 4 {
+		t.Fatalf("Expected 4 nodes, got %+v", export.Elements.Nodes)
+	}
+	expectedNode := map[string]any{"id": "test/a", "path": "test/a", "name": "a", "layer": 1.0, "fileCount": 2.0}
+	if !reflect.DeepEqual(export.Elements.Nodes[1].Data, expectedNode) {
+		t.Errorf("Expected node data %v, got %v", expectedNode, export.Elements.Nodes[1].Data)
+	}
+
+	circular := make(map[string]bool)
+	for _, edge := range export.Elements.Edges {
+		circular[edge.Data["source"].(string)+" "+edge.Data["target"].(string)] = edge.Data["circular"].(bool)
+	}
+	expectedEdges := map[string]bool{
+		"test/a test/b": true, "test/b test/a": true, "test/main fmt": false, "test/main test/a": false,
+	}
+	if !reflect.DeepEqual(circular, expectedEdges) {
+		t.Errorf("Expected edges %v, got %v", expectedEdges, circular)
+	}
+	if id := export.Elements.Edges[0].Data["id"]; id != "test/a->test/b" {
+		t.Errorf("Expected edges sorted by source with source->target ids, got %v", id)
+	}
+
+	data, err = visualizer.New(visualizer.WithHideExternalEdges(true)).GenerateCytoscapeJSON(graph)
+	if err != nil {
+		t.Fatalf("GenerateCytoscapeJSON failed: %v", err)
+	}
+	if strings.Contains(string(data), `"target": "fmt"`) {
+		t.Errorf("Expected edges into external packages to be hidden, got:\n%s", data)
+	}
+}
+
+func TestGenerateCytoscapeJSON_DeterministicCycles(t *testing.T) {
+	// a→c and c→b are not on the cycle a search from a finds first, but lie on a→c→b→a
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "test/a",
+		ModuleName:   "test",
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/a": {Name: "a", Path: "test/a", Dependencies: []string{"test/b", "test/c"}},
+			"test/b": {Name: "b", Path: "test/b", Dependencies: []string{"test/a"}},
+			"test/c": {Name: "c", Path: "test/c", Dependencies: []string{"test/b"}},
+		},
+	}
+
+	first, err := visualizer.New().GenerateCytoscapeJSON(graph)
+	if err != nil {
+		t.Fatalf("GenerateCytoscapeJSON failed: %v", err)
+	}
+	for range 20 {
+		data, generateErr := visualizer.New().GenerateCytoscapeJSON(graph)
+		if generateErr != nil {
+			t.Fatalf("GenerateCytoscapeJSON failed: %v", generateErr)
+		}
+		if string(data) != string(first) {
+			t.Fatalf("Expected identical exports, got:\n%s\nthen:\n%s", first, data)
+		}
+	}
+
+	var export visualizer.CytoscapeJSON
+	if unmarshalErr := json.Unmarshal(first, &export); unmarshalErr != nil {
+		t.Fatalf("Failed to unmarshal export: %v", unmarshalErr)
+	}
+	for _, edge := range export.Elements.Edges {
+		if !edge.Data.Circular {
+			t.Errorf("Expected %s to be marked circular", edge.Data.ID)
+		}
+	}
+}
+
+func TestGenerateJSON_EmptyCycles(t *testing.T) {
+	data, err := visualizer.New().GenerateJSON(analyzer.BuildSyntheticGraph(3))
+	if err != nil {
+		t.Fatalf("GenerateJSON failed: %v", err)
+	}
+
+	if !strings.Contains(string(data), `"cycles": []`) {
+		t.Errorf("Expected an empty cycles array, got:\n%s", data)
+	}
+}
+
+func TestGenerateJSON_AnalysisOptions(t *testing.T) {
+	graph := analyzer.BuildSyntheticGraph(3)
+	data, err := visualizer.New().GenerateJSON(graph)
+	if err != nil {
+		t.Fatalf("GenerateJSON failed: %v", err)
+	}
+	if !strings.Contains(string(data), `"includedExternal": false`) ||
+		!strings.Contains(string(data), `"excludedDirs": []`) {
+		t.Errorf("Expected the analysis options to always be exported, got:\n%s", data)
+	}
+
+	graph.IncludedExternal = true
+	graph.ExcludedDirs = []string{"testdata", "gen/*"}
+	data, err = visualizer.New().GenerateJSON(graph)
+	if err != nil {
+		t.Fatalf("GenerateJSON failed: %v", err)
+	}
+	var export visualizer.GraphJSON
+	if unmarshalErr := json.Unmarshal(data, &export); unmarshalErr != nil {
+		t.Fatalf("Failed to unmarshal export: %v", unmarshalErr)
+	}
+	if !export.IncludedExternal || !reflect.DeepEqual(export.ExcludedDirs, graph.ExcludedDirs) {
+		t.Errorf("Expected the graph's analysis options, got %v and %v", export.IncludedExternal, export.ExcludedDirs)
+	}
+}
+
+func TestGenerateJSON_Files(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "test/main",
+		ModuleName:   "test",
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/main": {
+				Name: "main", Path: "test/main", Dependencies: []string{"fmt"}, Dir: "/src/main",
+				Files: []analyzer.SourceFile{
+					{Path: "/src/main/doc.go"},
+					{Path: "/src/main/main.go"},
+				},
+			},
+			"fmt": {Name: "fmt", Path: "fmt", Dependencies: []string{}},
+		},
+	}
+
+	data, err := visualizer.New().GenerateJSON(graph)
+	if err != nil {
+		t.Fatalf("GenerateJSON failed: %v", err)
+	}
+	if strings.Contains(string(data), `"files"`) || strings.Contains(string(data), `"dir"`) {
+		t.Errorf("Expected no files without JSONWithFiles, got:\n%s", data)
+	}
+
+	data, err = visualizer.New().GenerateJSON(graph, visualizer.JSONWithFiles())
+	if err != nil {
+		t.Fatalf("GenerateJSON failed: %v", err)
+	}
+	var export visualizer.GraphJSON
+	if unmarshalErr := json.Unmarshal(data, &export); unmarshalErr != nil {
+		t.Fatalf("Invalid JSON: %v", unmarshalErr)
+	}
+	main := export.Packages[1]
+	expected := []visualizer.SourceJSON{
+		{Path: "/src/main/doc.go"},
+		{Path: "/src/main/main.go"},
+	}
+	if main.Dir != "/src/main" || !reflect.DeepEqual(main.Files, expected) {
+		t.Errorf("Expected the directory and files of test/main, got %+v", main)
+	}
+	if export.Packages[0].Files != nil {
+		t.Errorf("Expected no files for the unanalyzed external package, got %+v", export.Packages[0].Files)
+	}
+}
+
+func TestGenerateDOTContent_PartialPackages(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "test/main",
+		ModuleName:   "test",
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/main": {
+				Name: "main", Path: "test/main", Dependencies: []string{"test/util"},
+				FileCount: 1, Dir: "/src/main", Complete: true,
+			},
+			"test/util": {
+				Name: "util", Path: "test/util", Dependencies: []string{},
+				FileCount: 2, Dir: "/src/util", Complete: false,
+			},
+		},
+	}
+
+	dotContent := visualizer.New().GenerateDOTContent(graph)
+
+	if !strings.Contains(dotContent, `test_util [label="util\n2 files (partial)\nutil", style="filled,dashed"`) {
+		t.Errorf("Expected partial package to be flagged, got:\n%s", dotContent)
+	}
+	if strings.Contains(dotContent, `1 files (partial)`) {
+		t.Error("Complete packages should not be flagged")
+	}
+}
+
+func TestGenerateDOTContent_CommunityColors(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "test/main",
+		ModuleName:   "test",
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/main":     {Name: "main", Path: "test/main", Dependencies: []string{"test/api/http", "test/store"}},
+			"test/api/http": {Name: "http", Path: "test/api/http", Dependencies: []string{}},
+			"test/store":    {Name: "store", Path: "test/store", Dependencies: []string{}},
+		},
+	}
+	communities := map[string]int{"test/main": 0, "test/api/http": 1, "test/store": 1}
+
+	dotContent := visualizer.New(visualizer.WithCommunities(communities)).GenerateDOTContent(graph)
+
+	expected := `test_main [label="main\n0 files\nmain", fillcolor="rgba(111,220,140,0.05)", color="#6fdc8c"`
+	if !strings.Contains(dotContent, expected) {
+		t.Errorf("Expected entry community to use the first color, got:\n%s", dotContent)
+	}
+	if strings.Count(dotContent, `color="#ff944d", fontcolor`) != 2 {
+		t.Errorf("Expected both packages in community 1 to share a color, got:\n%s", dotContent)
+	}
+}
+
+func TestGenerateDOTContent_ColorsIndependentOfFilters(t *testing.T) {
+	packages := map[string]*analyzer.PackageInfo{
+		"test/main": {
+			Name: "main", Path: "test/main", Dependencies: []string{"test/api", "test/cache", "test/store"},
+		},
+		"test/api":      {Name: "api", Path: "test/api", Dependencies: []string{}},
+		"test/cache":    {Name: "cache", Path: "test/cache", Dependencies: []string{}},
+		"test/store":    {Name: "store", Path: "test/store", Dependencies: []string{}},
+		"test/store/db": {Name: "db", Path: "test/store/db", Dependencies: []string{}},
+	}
+	nodeColor := regexp.MustCompile(`(?m)^\s*(\w+) \[label=.*, color="(#[0-9a-f]{6})"`)
+
+	colors := make(map[string]string)
+	exclusions := [][]string{nil, {"test/api"}, {"test/api", "test/cache"}, {"test/cache", "test/store/db"}}
+	for _, excluded := range exclusions {
+		graph := &analyzer.DependencyGraph{
+			EntryPackage: "test/main",
+			ModuleName:   "test",
+			Packages:     map[string]*analyzer.PackageInfo{},
+		}
+		for pkgPath, pkg := range packages {
+			if !slices.Contains(excluded, pkgPath) {
+				graph.Packages[pkgPath] = pkg
+			}
+		}
+
+		dotContent := visualizer.New().GenerateDOTContent(graph)
+		for _, match := range nodeColor.FindAllStringSubmatch(dotContent, -1) {
+			if previous, seen := colors[match[1]]; seen && previous != match[2] {
+				t.Errorf("Expected %s to keep color %s when excluding %v, got %s",
+					match[1], previous, excluded, match[2])
+			}
+			colors[match[1]] = match[2]
+		}
+	}
+
+	if colors["test_main"] != "#6fdc8c" {
+		t.Errorf("Expected the entry package to use the first color, got %s", colors["test_main"])
+	}
+	if colors["test_store"] != colors["test_store_db"] {
+		t.Errorf("Expected packages of one group to share a color, got %s and %s",
+			colors["test_store"], colors["test_store_db"])
+	}
+}
+
+func TestGenerateDOTContent_DistinctGroupColors(t *testing.T) {
+	groups := []string{
+		"app", "cmd", "config", "internal", "pkg", "web", "api", "store",
+		"services/a", "services/b", "tools", "util",
+	}
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "test/main",
+		ModuleName:   "test",
+		Packages:     map[string]*analyzer.PackageInfo{"test/main": {Name: "main", Path: "test/main"}},
+	}
+	for _, group := range groups {
+		pkgPath := "test/" + group + "/x"
+		graph.Packages[pkgPath] = &analyzer.PackageInfo{Name: "x", Path: pkgPath}
+		graph.Packages["test/main"].Dependencies = append(graph.Packages["test/main"].Dependencies, pkgPath)
+	}
+	nodeColor := regexp.MustCompile(`(?m)^\s*(\w+) \[label=.*, color="(#[0-9a-f]{6})"`)
+
+	dotContent := visualizer.New().GenerateDOTContent(graph)
+	owners := make(map[string]string)
+	for _, match := range nodeColor.FindAllStringSubmatch(dotContent, -1) {
+		if owner, taken := owners[match[2]]; taken {
+			t.Errorf("Expected distinct groups to get distinct colors, %s and %s share %s", owner, match[1], match[2])
+		}
+		owners[match[2]] = match[1]
+	}
+	if len(owners) != len(groups)+1 {
+		t.Errorf("Expected %d colored nodes, got %d:\n%s", len(groups)+1, len(owners), dotContent)
+	}
+}
+
+func TestGenerateDOTContent_RankByPathDepth(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "test/cmd/app",
+		ModuleName:   "test",
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/cmd/app": {
+				Name: "main", Path: "test/cmd/app", Dependencies: []string{"test/internal/api", "fmt"},
+			},
+			"test/internal/api": {
+				Name: "api", Path: "test/internal/api", Dependencies: []string{"test/internal/db"},
+			},
+			"test/internal/db": {
+				Name: "db", Path: "test/internal/db", Dependencies: []string{"test/internal/db/sql"},
+			},
+			"test/internal/db/sql": {Name: "sql", Path: "test/internal/db/sql", Dependencies: []string{}},
+			"fmt":                  {Name: "fmt", Path: "fmt", Dependencies: []string{}},
+		},
+		Layers: [][]string{
+			{"test/cmd/app"}, {"test/internal/api"}, {"test/internal/db"}, {"test/internal/db/sql", "fmt"},
+		},
+	}
+
+	dotContent := visualizer.New(visualizer.WithRankMode(visualizer.RankByPathDepth)).GenerateDOTContent(graph)
+
+	expected := []string{
+		"{ rank=same; test_cmd_app; test_internal_api; test_internal_db; }",
+		"{ rank=same; test_internal_db_sql; }",
+	}
+	for _, constraint := range expected {
+		if !strings.Contains(dotContent, constraint) {
+			t.Errorf("Expected constraint %q, got:\n%s", constraint, dotContent)
+		}
+	}
+	if strings.Contains(dotContent, "rank=source") || strings.Contains(dotContent, "rank=sink") {
+		t.Error("Path depth ranks should replace layer constraints")
+	}
+	if strings.Contains(dotContent, "rank=same; fmt") {
+		t.Error("External packages should not be ranked by path depth")
+	}
+}
+
+func TestGenerateDOTContent_EdgePorts(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "test/main",
+		ModuleName:   "test",
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/main": {Name: "main", Path: "test/main", Dependencies: []string{"test/a"}},
+			"test/a":    {Name: "a", Path: "test/a", Dependencies: []string{"test/b"}},
+			"test/b":    {Name: "b", Path: "test/b", Dependencies: []string{"test/a"}},
+		},
+	}
+
+	dotContent := visualizer.New(visualizer.WithEdgePorts(true)).GenerateDOTContent(graph)
+
+	if !strings.Contains(dotContent, "  test_main:s -> test_a:n [") {
+		t.Errorf("Expected normal edge to use ports, got:\n%s", dotContent)
+	}
+	if !strings.Contains(dotContent, "  test_a -> test_b [color=\"red\"") {
+		t.Errorf("Expected circular edge without ports, got:\n%s", dotContent)
+	}
+
+	dotContent = visualizer.New(
+		visualizer.WithEdgePorts(true),
+		visualizer.WithRankDir(visualizer.RankDirLeftToRight),
+	).GenerateDOTContent(graph)
+
+	if !strings.Contains(dotContent, "rankdir=LR;") {
+		t.Errorf("Expected left-to-right rank direction, got:\n%s", dotContent)
+	}
+	if strings.Contains(dotContent, ":s ->") {
+		t.Error("Edge ports should only apply to top-to-bottom layouts")
+	}
+}
+
+func TestGenerateDOTContent_UnknownRankDir(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "test/main",
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/main": {Name: "main", Path: "test/main", Dependencies: []string{}},
+		},
+	}
+
+	dotContent := visualizer.New(visualizer.WithRankDir("sideways")).GenerateDOTContent(graph)
+
+	if !strings.Contains(dotContent, "rankdir=TB;") {
+		t.Errorf("Expected unknown rank direction to be ignored, got:\n%s", dotContent)
+	}
+}
+
+func TestGenerateDOTContent_Spacing(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "test/main",
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/main": {Name: "main", Path: "test/main", Dependencies: []string{}},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		opts     []visualizer.Option
+		expected []string
+	}{
+		{
+			name:     "defaults",
+			expected: []string{"nodesep=1.0;", "ranksep=1.5;", `margin="0.4,0.3"`},
+		},
+		{
+			name: "overrides",
+			opts: []visualizer.Option{
+				visualizer.WithNodeSep(0.25),
+				visualizer.WithRankSep(2),
+				visualizer.WithNodeMargin(0.1, 0.05),
+			},
+			expected: []string{"nodesep=0.25;", "ranksep=2.0;", `margin="0.1,0.05"`},
+		},
+		{
+			name: "non-positive values are ignored",
+			opts: []visualizer.Option{
+				visualizer.WithNodeSep(0),
+				visualizer.WithRankSep(-1),
+				visualizer.WithNodeMargin(0.2, 0),
+			},
+			expected: []string{"nodesep=1.0;", "ranksep=1.5;", `margin="0.4,0.3"`},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dotContent := visualizer.New(tc.opts...).GenerateDOTContent(graph)
+			for _, attr := range tc.expected {
+				if !strings.Contains(dotContent, attr) {
+					t.Errorf("Expected %s in DOT output, got:\n%s", attr, dotContent)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateDOTContent_ModuleClusters(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "example.com/app",
+		ModuleName:   "example.com/app",
+		Packages: map[string]*analyzer.PackageInfo{
+			"example.com/app": {
+				Name: "app", Path: "example.com/app", Module: "example.com/app",
+				Dependencies: []string{"example.com/app/api", "example.com/lib", "fmt"},
+			},
+			"example.com/app/api": {Name: "api", Path: "example.com/app/api", Module: "example.com/app"},
+			"example.com/lib":     {Name: "lib", Path: "example.com/lib", Module: "example.com/lib"},
+			"fmt":                 {Name: "fmt", Path: "fmt"},
+		},
+	}
+
+	dotContent := visualizer.New(visualizer.WithModuleClusters(true)).GenerateDOTContent(graph)
+
+	appCluster := strings.Index(dotContent, "subgraph cluster_module_0 {\n    label=\"example.com/app\";")
+	libCluster := strings.Index(dotContent, "subgraph cluster_module_1 {\n    label=\"example.com/lib\";")
+	if appCluster < 0 || libCluster < appCluster {
+		t.Fatalf("Expected one cluster per module in module order, got:\n%s", dotContent)
+	}
+	if api := strings.Index(dotContent, "    example_com_app_api [label="); api < appCluster || api > libCluster {
+		t.Errorf("Expected api node inside the app cluster, got:\n%s", dotContent)
+	}
+	if !strings.Contains(dotContent, "  }\n  fmt [label=") {
+		t.Errorf("Expected packages without a module outside the clusters, got:\n%s", dotContent)
+	}
+
+	if strings.Contains(visualizer.New().GenerateDOTContent(graph), "subgraph") {
+		t.Error("Clusters should be opt-in")
+	}
+}
+
+func TestGenerateDOTContent_Grouper(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "test/cmd/api",
+		ModuleName:   "test",
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/cmd/api": {
+				Name: "api", Path: "test/cmd/api", Module: "test",
+				Dependencies: []string{"test/billing/invoice", "test/billing/tax", "test/catalog"},
+			},
+			"test/billing/invoice": {Name: "invoice", Path: "test/billing/invoice", Module: "test"},
+			"test/billing/tax":     {Name: "tax", Path: "test/billing/tax", Module: "test"},
+			"test/catalog":         {Name: "catalog", Path: "test/catalog", Module: "test"},
+		},
+	}
+	owners := map[string]string{
+		"test/billing/invoice": "team payments",
+		"test/billing/tax":     "team payments",
+		"test/catalog":         "team shop & co",
+	}
+	grouper := func(pkgPath string) string { return owners[pkgPath] }
+
+	dotContent := visualizer.New(visualizer.WithGrouper(grouper), visualizer.WithModuleClusters(true)).
+		GenerateDOTContent(graph)
+
+	payments := strings.Index(dotContent, "subgraph cluster_group_0 {\n    label=\"team payments\";")
+	shop := strings.Index(dotContent, "subgraph cluster_group_1 {\n    label=\"team shop &amp; co\";")
+	if payments < 0 || shop < payments {
+		t.Fatalf("Expected one cluster per group in name order, got:\n%s", dotContent)
+	}
+	for _, node := range []string{"    test_billing_invoice [label=", "    test_billing_tax [label="} {
+		if index := strings.Index(dotContent, node); index < payments || index > shop {
+			t.Errorf("Expected %q inside the payments cluster, got:\n%s", node, dotContent)
+		}
+	}
+	if !strings.Contains(dotContent, "  }\n  test_cmd_api [label=") {
+		t.Errorf("Expected ungrouped packages outside the clusters, got:\n%s", dotContent)
+	}
+	if strings.Contains(dotContent, "cluster_module") {
+		t.Errorf("Expected groups to take precedence over module clusters, got:\n%s", dotContent)
+	}
+
+	invoiceColor := regexp.MustCompile(`test_billing_invoice \[label="[^"]*", fillcolor="[^"]*", color="([^"]*)"`)
+	taxColor := regexp.MustCompile(`test_billing_tax \[label="[^"]*", fillcolor="[^"]*", color="([^"]*)"`)
+	invoice, tax := invoiceColor.FindStringSubmatch(dotContent), taxColor.FindStringSubmatch(dotContent)
+	if invoice == nil || tax == nil || invoice[1] != tax[1] {
+		t.Errorf("Expected packages of a group to share a color, got:\n%s", dotContent)
+	}
+}
+
+func TestGenerateDOTPerComponent(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "example.com/app/cmd/api",
+		ModuleName:   "example.com/app",
+		Packages: map[string]*analyzer.PackageInfo{
+			"example.com/app/cmd/api": {
+				Name: "api", Path: "example.com/app/cmd/api", Dependencies: []string{"example.com/app/store"},
+			},
+			"example.com/app/store": {Name: "store", Path: "example.com/app/store", Layer: 1},
+			"example.com/app/tools/gen": {
+				Name: "gen", Path: "example.com/app/tools/gen", Dependencies: []string{"example.com/app/tools/tmpl"},
+			},
+			"example.com/app/tools/tmpl": {Name: "tmpl", Path: "example.com/app/tools/tmpl", Layer: 1},
+		},
+		Layers: [][]string{
+			{"example.com/app/cmd/api", "example.com/app/tools/gen"},
+			{"example.com/app/store", "example.com/app/tools/tmpl"},
+		},
+	}
+
+	documents := visualizer.New().GenerateDOTPerComponent(graph)
+	if len(documents) != 2 {
+		t.Fatalf("Expected 2 components, got %d", len(documents))
+	}
+
+	testCases := []struct {
+		key        string
+		expected   []string
+		unexpected []string
+	}{
+		{
+			key: "example.com/app/cmd/api",
+			expected: []string{
+				"example_com_app_cmd_api -> example_com_app_store",
+				"{ rank=source; example_com_app_cmd_api; }",
+			},
+			unexpected: []string{"example_com_app_tools_gen"},
+		},
+		{
+			key:        "example.com/app/tools/gen",
+			expected:   []string{"example_com_app_tools_gen -> example_com_app_tools_tmpl"},
+			unexpected: []string{"example_com_app_store", "rank=source"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.key, func(t *testing.T) {
+			dotContent, exists := documents[tc.key]
+			if !exists {
+				t.Fatalf("Expected a document keyed by %s", tc.key)
+			}
+			for _, want := range tc.expected {
+				if !strings.Contains(dotContent, want) {
+					t.Errorf("Expected %q in DOT output, got:\n%s", want, dotContent)
+				}
+			}
+			for _, notWant := range tc.unexpected {
+				if strings.Contains(dotContent, notWant) {
+					t.Errorf("Did not expect %q in DOT output, got:\n%s", notWant, dotContent)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateDOTContent_EntryHighlight(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "example.com/app",
+		ModuleName:   "example.com/app",
+		Packages: map[string]*analyzer.PackageInfo{
+			"example.com/app": {
+				Name: "app", Path: "example.com/app", Dependencies: []string{"example.com/app/api"},
+			},
+			"example.com/app/api": {Name: "api", Path: "example.com/app/api"},
+		},
+	}
+
+	dotContent := visualizer.New(visualizer.WithEntryHighlight(true)).GenerateDOTContent(graph)
+	if !strings.Contains(dotContent, "example_com_app [label=\"▶ entry\\napp\\n0 files\\n/\", peripheries=2, ") {
+		t.Errorf("Expected a badge and double border on the entry node, got:\n%s", dotContent)
+	}
+	if strings.Count(dotContent, "peripheries=2") != 1 || strings.Count(dotContent, "▶ entry") != 1 {
+		t.Errorf("Expected only the entry node to be highlighted, got:\n%s", dotContent)
+	}
+
+	if plain := visualizer.New().GenerateDOTContent(graph); strings.Contains(plain, "peripheries") {
+		t.Error("Entry highlighting should be opt-in")
+	}
+}
+
+func TestGenerateDOTContent_Template(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "example.com/app",
+		ModuleName:   "example.com/app",
+		Packages: map[string]*analyzer.PackageInfo{
+			"example.com/app": {
+				Name: "app", Path: "example.com/app", Dependencies: []string{"example.com/app/api"},
+			},
+			"example.com/app/api": {Name: "api", Path: "example.com/app/api", Layer: 1},
+		},
+		Layers: [][]string{{"example.com/app"}, {"example.com/app/api"}},
+	}
+
+	tmpl := template.Must(template.New("custom").Parse(
+		"digraph custom {\n  rankdir={{.RankDir}};\n{{range .Nodes}}{{.}}\n{{end}}{{range .Edges}}{{.}}\n{{end}}}\n"))
+	dotContent := visualizer.New(visualizer.WithTemplate(tmpl)).GenerateDOTContent(graph)
+
+	if !strings.HasPrefix(dotContent, "digraph custom {\n  rankdir=TB;\n") {
+		t.Errorf("Expected the custom header, got:\n%s", dotContent)
+	}
+	if strings.Contains(dotContent, "splines=ortho") || strings.Contains(dotContent, "rank=") {
+		t.Errorf("Expected only what the custom template writes, got:\n%s", dotContent)
+	}
+	if !strings.Contains(dotContent, " -> ") {
+		t.Errorf("Expected the template to receive edges, got:\n%s", dotContent)
+	}
+
+	defaultContent := visualizer.New().GenerateDOTContent(graph)
+	failing := template.Must(template.New("failing").Parse("{{.Missing}}"))
+	if got := visualizer.New(visualizer.WithTemplate(failing)).GenerateDOTContent(graph); got != defaultContent {
+		t.Errorf("Expected a failing template to fall back to the default, got:\n%s", got)
+	}
+	if got := visualizer.New(visualizer.WithTemplate(nil)).GenerateDOTContent(graph); got != defaultContent {
+		t.Errorf("Expected a nil template to keep the default, got:\n%s", got)
+	}
+}
+
+func TestGenerateDOTContent_LabelPath(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "example.com/app",
+		ModuleName:   "example.com/app",
+		Packages: map[string]*analyzer.PackageInfo{
+			"example.com/app": {
+				Name: "app", Path: "example.com/app", Dir: "/src/app", Complete: true,
+				Dependencies: []string{"example.com/app/api", "fmt"},
+			},
+			"example.com/app/api": {Name: "api", Path: "example.com/app/api", Dir: "/src/app/api", Complete: true},
+			"fmt":                 {Name: "fmt", Path: "fmt"},
+		},
+	}
+
+	testCases := []struct {
+		name     string
+		mode     visualizer.LabelPathMode
+		expected []string
+	}{
+		{
+			name:     "relative by default",
+			mode:     visualizer.LabelPathRelative,
+			expected: []string{"app\\n0 files\\n/\"", "api\\n0 files\\napi\"", "fmt\\n0 files\\nfmt\""},
+		},
+		{
+			name:     "import path",
+			mode:     visualizer.LabelPathImport,
+			expected: []string{"app\\n0 files\\nexample.com/app\"", "api\\n0 files\\nexample.com/app/api\""},
+		},
+		{
+			name:     "directory",
+			mode:     visualizer.LabelPathDir,
+			expected: []string{"app\\n0 files\\n/src/app\"", "api\\n0 files\\n/src/app/api\"", "fmt\\n0 files\\nfmt\""},
+		},
+		{
+			name:     "unknown mode keeps relative paths",
+			mode:     visualizer.LabelPathMode(42),
+			expected: []string{"app\\n0 files\\n/\"", "api\\n0 files\\napi\""},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dotContent := visualizer.New(visualizer.WithLabelPath(tc.mode)).GenerateDOTContent(graph)
+			for _, label := range tc.expected {
+				if !strings.Contains(dotContent, label) {
+					t.Errorf("Expected label ending %q, got:\n%s", label, dotContent)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateDirectoryDOT(t *testing.T) {
+	directories := []*scanner.DirectoryNode{
+		{Name: "tools", Path: "/src/tools", Children: []*scanner.DirectoryNode{
+			{Name: "gen", Path: "/src/tools/gen", IsGoProject: true},
+		}},
+		{Name: "app \"beta\"", Path: "/src/app \"beta\"", IsGoProject: true},
+	}
+
+	dotContent := visualizer.New().GenerateDirectoryDOT("/src", directories)
+
+	expected := []string{
+		"digraph directories {",
+		`dir_0 [label="/src", fillcolor="rgba(138,138,138,0.05)", color="#8a8a8a"`,
+		`dir_1 [label="tools", fillcolor="rgba(138,138,138,0.05)"`,
+		`dir_2 [label="gen", fillcolor="rgba(111,220,140,0.05)", color="#6fdc8c"`,
+		`dir_3 [label="app &quot;beta&quot;", fillcolor="rgba(111,220,140,0.05)"`,
+		"dir_0 -> dir_1;",
+		"dir_1 -> dir_2;",
+		"dir_0 -> dir_3;",
+	}
+	for _, line := range expected {
+		if !strings.Contains(dotContent, line) {
+			t.Errorf("Expected %q in DOT output, got:\n%s", line, dotContent)
+		}
+	}
+	if strings.Contains(dotContent, "dir_4") {
+		t.Errorf("Expected one node per directory plus the root, got:\n%s", dotContent)
+	}
+}
+
+func TestGenerateDOTContent_EmptyForTarget(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "test/main",
+		ModuleName:   "test",
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/main":   {Name: "main", Path: "test/main", Dependencies: []string{"test/winsvc"}, Complete: true},
+			"test/winsvc": {Name: "winsvc", Path: "test/winsvc", Complete: true, EmptyForTarget: true},
+		},
+	}
+
+	dotContent := visualizer.New().GenerateDOTContent(graph)
+
+	expected := `test_winsvc [label="winsvc\nno files for target\nwinsvc", style="filled,dotted", `
+	if !strings.Contains(dotContent, expected) {
+		t.Errorf("Expected the package to be drawn dotted with an explanation, got:\n%s", dotContent)
+	}
+	if strings.Contains(dotContent, `test_main [label="main\nno files`) || strings.Count(dotContent, "dotted") != 1 {
+		t.Errorf("Expected only the empty package to be drawn dotted, got:\n%s", dotContent)
+	}
+}
+
+func TestGenerateDOTContent_CrossModuleEdges(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "example.com/api",
+		ModuleName:   "example.com/api",
+		Packages: map[string]*analyzer.PackageInfo{
+			"example.com/api": {
+				Name: "api", Path: "example.com/api", Module: "example.com/api",
+				Dependencies: []string{"example.com/api/handlers", "example.com/shared/auth", "fmt"},
+			},
+			"example.com/api/handlers": {Name: "handlers", Path: "example.com/api/handlers", Module: "example.com/api"},
+			"example.com/shared/auth":  {Name: "auth", Path: "example.com/shared/auth", Module: "example.com/shared"},
+			"fmt":                      {Name: "fmt", Path: "fmt"},
+		},
+	}
+
+	dotContent := visualizer.New().GenerateDOTContent(graph)
+	if strings.Contains(dotContent, "bold,dashed") {
+		t.Errorf("Expected cross-module edges to be plain by default, got:\n%s", dotContent)
+	}
+
+	dotContent = visualizer.New(visualizer.WithCrossModuleEdges(true)).GenerateDOTContent(graph)
+	expected := `example_com_api -> example_com_shared_auth [color="#6fdc8c", penwidth=1.5, style="bold,dashed"];`
+	if !strings.Contains(dotContent, expected) {
+		t.Errorf("Expected the cross-module edge to be bold and dashed, got:\n%s", dotContent)
+	}
+	if strings.Count(dotContent, "bold,dashed") != 1 {
+		t.Errorf("Expected only edges between attributed modules to be styled, got:\n%s", dotContent)
+	}
+}
+
+func TestGenerateDOTContent_CollapsedExternals(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "test/main",
+		ModuleName:   "test",
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/main": {Name: "main", Path: "test/main", Dependencies: []string{analyzer.CollapsedExternalPackage}},
+			analyzer.CollapsedExternalPackage: {
+				Name: "… and 7 more external", Path: analyzer.CollapsedExternalPackage,
+			},
+		},
+	}
+
+	dotContent := visualizer.New().GenerateDOTContent(graph)
+
+	if !strings.Contains(dotContent, `[label="… and 7 more external", fillcolor=`) {
+		t.Errorf("Expected the collapsed externals to be labeled by name only, got:\n%s", dotContent)
+	}
+}
+
+func TestGenerateDOTContent_MetricAttributes(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "test/main",
+		ModuleName:   "test",
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/main": {
+				Name: "main", Path: "test/main", FileCount: 2, Dependencies: []string{"test/api", "test/util"},
+			},
+			"test/api":   {Name: "api", Path: "test/api", FileCount: 3, Layer: 1, Dependencies: []string{"test/util"}},
+			"test/util":  {Name: "util", Path: "test/util", FileCount: 1, Layer: 2, Dependencies: []string{}},
+			"test/extra": {Name: "extra", Path: "test/extra", Dependencies: []string{}},
+		},
+	}
+
+	dotContent := visualizer.New().GenerateDOTContent(graph)
+	if strings.Contains(dotContent, "gpa_") {
+		t.Errorf("Expected no metric attributes by default, got:\n%s", dotContent)
+	}
+
+	dotContent = visualizer.New(visualizer.WithMetricAttributes(true)).GenerateDOTContent(graph)
+	for _, expected := range []string{
+		`test_main [label="main\n2 files\nmain", gpa_files=2, gpa_layer=0, gpa_fanin=0, ` +
+			`class="gpa-files-2 gpa-layer-0 gpa-fanin-0", fillcolor=`,
+		`test_api [label="api\n3 files\napi", gpa_files=3, gpa_layer=1, gpa_fanin=1, ` +
+			`class="gpa-files-3 gpa-layer-1 gpa-fanin-1", fillcolor=`,
+		`test_util [label="util\n1 files\nutil", gpa_files=1, gpa_layer=2, gpa_fanin=2, ` +
+			`class="gpa-files-1 gpa-layer-2 gpa-fanin-2", fillcolor=`,
+	} {
+		if !strings.Contains(dotContent, expected) {
+			t.Errorf("Expected %q, got:\n%s", expected, dotContent)
+		}
+	}
+}
+
+func TestGenerateDOTContent_DeterministicLayout(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "test/main",
+		ModuleName:   "test",
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/main": {Name: "main", Path: "test/main", Dependencies: []string{"test/util"}},
+			"test/util": {Name: "util", Path: "test/util", Layer: 1, Dependencies: []string{}},
+		},
+	}
+	variableAttributes := []string{"overlap=false;", `sep="+30,30";`, `esep="+15,15";`, `packmode="graph";`}
+
+	dotContent := visualizer.New().GenerateDOTContent(graph)
+	for _, attr := range variableAttributes {
+		if !strings.Contains(dotContent, "\n  "+attr+"\n") {
+			t.Errorf("Expected %s in the default header, got:\n%s", attr, dotContent)
+		}
+	}
+
+	deterministic := visualizer.New(visualizer.WithDeterministicLayout(true)).GenerateDOTContent(graph)
+	for _, attr := range variableAttributes {
+		if strings.Contains(deterministic, attr) {
+			t.Errorf("Expected %s to be left out of the deterministic header, got:\n%s", attr, deterministic)
+		}
+	}
+	if !strings.Contains(deterministic, "\n  start=42;\n  ordering=out;\n  dpi=96;\n") {
+		t.Errorf("Expected the deterministic header to keep the seed, got:\n%s", deterministic)
+	}
+
+	var stripped []string
+	for _, line := range strings.Split(dotContent, "\n") {
+		if !slices.Contains(variableAttributes, strings.TrimSpace(line)) {
+			stripped = append(stripped, line)
+		}
+	}
+	if deterministic != strings.Join(stripped, "\n") {
+		t.Errorf("Expected only the variable attributes to differ, got:\n%s", deterministic)
+	}
+}
+
+func TestGenerateDOTContent_DeterministicLayoutIsReproducible(t *testing.T) {
+	// Edges inside the cycle a→b→a, a→c→b must be styled the same on every run
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "test/a",
+		ModuleName:   "test",
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/a": {Name: "a", Path: "test/a", Dependencies: []string{"test/b", "test/c"}},
+			"test/b": {Name: "b", Path: "test/b", Dependencies: []string{"test/a"}},
+			"test/c": {Name: "c", Path: "test/c", Dependencies: []string{"test/b"}},
+		},
+	}
+	viz := visualizer.New(visualizer.WithDeterministicLayout(true))
+
+	first := viz.GenerateDOTContent(graph)
+	for range 50 {
+		if dotContent := viz.GenerateDOTContent(graph); dotContent != first {
+			t.Fatalf("Expected identical documents, got:\n%s\nthen:\n%s", first, dotContent)
+		}
+	}
+}
+
+func TestGenerateDOTContent_ChurnBadge(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "test/main",
+		ModuleName:   "test",
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/main": {Name: "main", Path: "test/main", Churn: 9, Dependencies: []string{"test/api", "test/util"}},
+			"test/api":  {Name: "api", Path: "test/api", Churn: 2, Dependencies: []string{"test/util"}},
+			"test/util": {Name: "util", Path: "test/util", Churn: 5, Dependencies: []string{}},
+		},
+	}
+
+	dotContent := visualizer.New().GenerateDOTContent(graph)
+	if strings.Contains(dotContent, "churn") {
+		t.Errorf("Expected no churn badges by default, got:\n%s", dotContent)
+	}
+
+	dotContent = visualizer.New(visualizer.WithChurnBadge(3, 2)).GenerateDOTContent(graph)
+	if !strings.Contains(dotContent, `test_util [label="util\n0 files\n⚠ churn 5\nutil"`) {
+		t.Errorf("Expected the churned, widely imported package to be badged, got:\n%s", dotContent)
+	}
+	if strings.Count(dotContent, "⚠ churn") != 1 {
+		t.Errorf("Expected packages below either threshold to be left unbadged, got:\n%s", dotContent)
+	}
+}
+
+func TestGenerateDOTContent_ComplexitySizing(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "test/main",
+		ModuleName:   "test",
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/main": {Name: "main", Path: "test/main", Complexity: 10, Dependencies: []string{"test/api"}},
+			"test/api":  {Name: "api", Path: "test/api", Complexity: 40, Dependencies: []string{}},
+		},
+	}
+
+	dotContent := visualizer.New().GenerateDOTContent(graph)
+	if strings.Contains(dotContent, "complexity") {
+		t.Errorf("Expected no complexity by default, got:\n%s", dotContent)
+	}
+
+	dotContent = visualizer.New(visualizer.WithComplexitySizing(true)).GenerateDOTContent(graph)
+	for _, expected := range []string{
+		`test_api [label="api\n0 files\ncomplexity 40\napi", fontsize=22, `,
+		`test_main [label="main\n0 files\ncomplexity 10\nmain", fontsize=13, `,
+	} {
+		if !strings.Contains(dotContent, expected) {
+			t.Errorf("Expected %q, got:\n%s", expected, dotContent)
+		}
+	}
+}
+
+func TestGenerateDOTContent_ReplacedPackages(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "test/main",
+		ModuleName:   "test",
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/main": {Name: "main", Path: "test/main", Dependencies: []string{"github.com/orig/x"}},
+			"github.com/orig/x": {
+				Name: "x", Path: "github.com/orig/x", Dependencies: []string{}, ReplacedBy: "github.com/fork/x@v1.2.0",
+			},
+		},
+	}
+
+	dotContent := visualizer.New().GenerateDOTContent(graph)
+	expected := `github_com_orig_x [label="x\n0 files\nreplaced by\ngithub.com/fork/x@v1.2.0\ngithub.com/orig/x"`
+	if !strings.Contains(dotContent, expected) {
+		t.Errorf("Expected the replacement next to the original path, got:\n%s", dotContent)
+	}
+	if strings.Count(dotContent, "replaced by") != 1 {
+		t.Errorf("Expected only the replaced package to be labeled, got:\n%s", dotContent)
+	}
+}
+
+func TestGenerateDOTContent_MaxLabelLen(t *testing.T) {
+	const migrations = "test/internal/storage/postgres/migrations"
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "test/main",
+		ModuleName:   "test",
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/main": {Name: "main", Path: "test/main", Dependencies: []string{migrations}},
+			migrations:  {Name: "migrations", Path: migrations, Dependencies: []string{}},
+		},
+	}
+
+	dotContent := visualizer.New().GenerateDOTContent(graph)
+	if strings.Contains(dotContent, "…") || strings.Contains(dotContent, "tooltip") {
+		t.Errorf("Expected no truncation by default, got:\n%s", dotContent)
+	}
+
+	dotContent = visualizer.New(visualizer.WithMaxLabelLen(30)).GenerateDOTContent(graph)
+	// The path wraps after "internal/storage/", which leaves 13 characters for the second line
+	expected := `test_internal_storage_postgres_migrations ` +
+		`[label="migrations\n0 files\ninternal/storage/\npostgres/migr…", ` +
+		`tooltip="migrations\ninternal/storage/postgres/migrations", `
+	if !strings.Contains(dotContent, expected) {
+		t.Errorf("Expected %q, got:\n%s", expected, dotContent)
+	}
+	if !strings.Contains(dotContent, `test_main [label="main\n0 files\nmain", fillcolor=`) {
+		t.Errorf("Expected short labels to be left alone, got:\n%s", dotContent)
+	}
+}
+
+func TestGenerateDOTContent_ImportedEntry(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "test/tool",
+		ModuleName:   "test",
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/tool":   {Name: "tool", Path: "test/tool", Dependencies: []string{"test/shared"}, Layer: 0},
+			"test/shared": {Name: "shared", Path: "test/shared", Dependencies: []string{"test/util"}, Layer: 1},
+			"test/util":   {Name: "util", Path: "test/util", Dependencies: []string{"test/tool"}, Layer: 2},
+		},
+		Layers: [][]string{{"test/tool"}, {"test/shared"}, {"test/util"}},
+	}
+
+	dotContent := visualizer.New().GenerateDOTContent(graph)
+	if !strings.Contains(dotContent, "{ rank=source; test_tool; }") {
+		t.Errorf("Expected an entry without the flag to keep rank=source, got:\n%s", dotContent)
+	}
+
+	graph.Packages["test/tool"].IsImportedEntry = true
+	dotContent = visualizer.New().GenerateDOTContent(graph)
+	if strings.Contains(dotContent, "rank=source") {
+		t.Errorf("Expected no rank=source for an imported entry, got:\n%s", dotContent)
+	}
+	if !strings.Contains(dotContent, "{ rank=min; test_tool; }") {
+		t.Errorf("Expected the imported entry at rank=min, got:\n%s", dotContent)
+	}
+	if !strings.Contains(dotContent, "test_util -> test_tool [color=\"red\", penwidth=1.5, constraint=false];") {
+		t.Errorf("Expected the import of the entry to leave ranking alone, got:\n%s", dotContent)
+	}
+	if !strings.Contains(dotContent, "test_tool -> test_shared [color=\"red\", penwidth=1.5];") {
+		t.Errorf("Expected the entry's own imports to keep constraining, got:\n%s", dotContent)
+	}
+}
+
+func TestGenerateDOTContent_DependencyBadge(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "test/main",
+		ModuleName:   "test",
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/main": {
+				Name: "main", Path: "test/main", FileCount: 2,
+				Dependencies: []string{"test/api", "test/store", "fmt"},
+			},
+			"test/api":   {Name: "api", Path: "test/api", FileCount: 1, Dependencies: []string{"test/store"}},
+			"test/store": {Name: "store", Path: "test/store", FileCount: 1, Dependencies: []string{"database/sql"}},
+		},
+	}
+
+	dotContent := visualizer.New().GenerateDOTContent(graph)
+	if strings.Contains(dotContent, "d)") {
+		t.Errorf("Expected no dependency badge by default, got:\n%s", dotContent)
+	}
+
+	dotContent = visualizer.New(visualizer.WithDependencyBadge(true)).GenerateDOTContent(graph)
+	for _, expected := range []string{
+		`test_main [label="main\n2 files (2d)\nmain"`,
+		`test_api [label="api\n1 files (1d)\napi"`,
+		`test_store [label="store\n1 files (0d)\nstore"`,
+	} {
+		if !strings.Contains(dotContent, expected) {
+			t.Errorf("Expected %q, got:\n%s", expected, dotContent)
+		}
+	}
+}
+
+func TestGenerateDOTContent_LayerCutoff(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "test/main",
+		ModuleName:   "test",
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/main":    {Name: "main", Path: "test/main", Dependencies: []string{"test/handler"}, Layer: 0},
+			"test/handler": {Name: "handler", Path: "test/handler", Dependencies: []string{"test/service"}, Layer: 1},
+			"test/service": {Name: "service", Path: "test/service", Dependencies: []string{"test/util"}, Layer: 2},
+			"test/util":    {Name: "util", Path: "test/util", Dependencies: []string{}, Layer: 3},
+		},
+		Layers: [][]string{{"test/main"}, {"test/handler"}, {"test/service"}, {"test/util"}},
+	}
+
+	dotContent := visualizer.New(visualizer.WithLayerCutoff(2)).GenerateDOTContent(graph)
+	for _, expected := range []string{"test_main [label=", "test_handler [label=", "test_main -> test_handler"} {
+		if !strings.Contains(dotContent, expected) {
+			t.Errorf("Expected %q above the cutoff, got:\n%s", expected, dotContent)
+		}
+	}
+	for _, hidden := range []string{"test_service", "test_util"} {
+		if strings.Contains(dotContent, hidden) {
+			t.Errorf("Expected %s at or below the cutoff to be hidden, got:\n%s", hidden, dotContent)
+		}
+	}
+	if len(graph.Packages) != 4 || len(graph.Layers) != 4 {
+		t.Error("The cutoff should not change the graph")
+	}
+
+	full := visualizer.New().GenerateDOTContent(graph)
+	for _, cutoff := range []int{0, -1, 4, 10} {
+		dotContent = visualizer.New(visualizer.WithLayerCutoff(cutoff)).GenerateDOTContent(graph)
+		if dotContent != full {
+			t.Errorf("Expected cutoff %d to show every layer, got:\n%s", cutoff, dotContent)
+		}
+	}
+}
+
+// fakeGraphviz puts a dot command running the shell script body on an otherwise empty PATH.
+func fakeGraphviz(t *testing.T, body string) {
+	t.Helper()
+	binDir := t.TempDir()
+	script := "#!/bin/sh\n" + body
+	if err := os.WriteFile(filepath.Join(binDir, "dot"), []byte(script), 0o755); err != nil {
+		t.Fatalf("Failed to write fake dot: %v", err)
+	}
+	t.Setenv("PATH", binDir)
+}
+
+func TestGenerateHTML(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "test/main",
+		ModuleName:   "test",
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/main":   {Name: "main", Path: "test/main", Dependencies: []string{"test/api-v2"}},
+			"test/api-v2": {Name: "api", Path: "test/api-v2", Dependencies: []string{}},
+		},
+	}
+	// Write an SVG document holding the format flag and the DOT read, behind an XML declaration
+	fakeGraphviz(t, "echo '<?xml version=\"1.0\"?>'\necho \"<svg><!-- $1\"\n/bin/cat\necho '--></svg>'\n")
+
+	page, err := visualizer.New().GenerateHTML(context.Background(), graph)
+	if err != nil {
+		t.Fatalf("GenerateHTML failed: %v", err)
+	}
+	content := string(page)
+
+	for _, expected := range []string{
+		"<title>test/main</title>",
+		`<button type="button" data-node="test_api_v2" title="test/api-v2">api-v2</button>`,
+		`<button type="button" data-node="test_main" title="test/main">main</button>`,
+		`<main id="graph"><svg><!-- -Tsvg`,
+		"digraph dependencies {\n",
+		"test_main -> test_api_v2",
+	} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("Expected %q in the page, got:\n%s", expected, content)
+		}
+	}
+	if strings.Contains(content, "<?xml") {
+		t.Error("Expected the XML declaration to be dropped from the inlined SVG")
+	}
+	if strings.Contains(content, "<script src=") || strings.Contains(content, "https://") {
+		t.Error("Expected the page to load nothing from the network")
+	}
+	if strings.Index(content, `data-node="test_api_v2"`) > strings.Index(content, `data-node="test_main"`) {
+		t.Error("Expected the package list to be sorted by path")
+	}
+}
+
+func TestGenerateHTML_MetricAttributes(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "test/main",
+		ModuleName:   "test",
+		Packages:     map[string]*analyzer.PackageInfo{"test/main": {Name: "main", Path: "test/main"}},
+	}
+	// Write node groups the way Graphviz does, with and without the metric classes
+	fakeGraphviz(t, "/bin/cat >/dev/null\n"+
+		"echo '<svg><g id=\"node1\" class=\"node gpa-files-3 gpa-layer-1 gpa-fanin-12\"></g>'\n"+
+		"echo '<g id=\"node2\" class=\"node\"></g><g id=\"edge1\" class=\"edge\"></g></svg>'\n")
+
+	page, err := visualizer.New(visualizer.WithMetricAttributes(true)).GenerateHTML(context.Background(), graph)
+	if err != nil {
+		t.Fatalf("GenerateHTML failed: %v", err)
+	}
+	for _, expected := range []string{
+		`class="node gpa-files-3 gpa-layer-1 gpa-fanin-12" data-gpa-files="3" data-gpa-layer="1" data-gpa-fanin="12">`,
+		`<g id="node2" class="node">`,
+		`<g id="edge1" class="edge">`,
+	} {
+		if !strings.Contains(string(page), expected) {
+			t.Errorf("Expected %q in the page, got:\n%s", expected, page)
+		}
+	}
+
+	page, err = visualizer.New().GenerateHTML(context.Background(), graph)
+	if err != nil {
+		t.Fatalf("GenerateHTML failed: %v", err)
+	}
+	if strings.Contains(string(page), "data-gpa-") {
+		t.Errorf("Expected no data attributes without metric attributes, got:\n%s", page)
+	}
+}
+
+func TestGenerateHTML_MetricAttributesGraphviz(t *testing.T) {
+	if _, err := exec.LookPath("dot"); err != nil {
+		t.Skip("Graphviz dot is not installed")
+	}
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "test/main",
+		ModuleName:   "test",
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/main": {Name: "main", Path: "test/main", FileCount: 2, Dependencies: []string{"test/api"}},
+			"test/api":  {Name: "api", Path: "test/api", FileCount: 3, Layer: 1, Dependencies: []string{}},
+		},
+	}
+
+	page, err := visualizer.New(visualizer.WithMetricAttributes(true)).GenerateHTML(context.Background(), graph)
+	if err != nil {
+		t.Fatalf("GenerateHTML failed: %v", err)
+	}
+	for _, expected := range []string{
+		`data-gpa-files="2" data-gpa-layer="0" data-gpa-fanin="0"`,
+		`data-gpa-files="3" data-gpa-layer="1" data-gpa-fanin="1"`,
+	} {
+		if !strings.Contains(string(page), expected) {
+			t.Errorf("Expected the rendered SVG to carry %q, got:\n%s", expected, page)
+		}
+	}
+}
+
+func TestGenerateHTML_WithoutGraphviz(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "test/main",
+		ModuleName:   "test",
+		Packages:     map[string]*analyzer.PackageInfo{"test/main": {Name: "main", Path: "test/main"}},
+	}
+	t.Setenv("PATH", t.TempDir())
+
+	_, err := visualizer.New().GenerateHTML(context.Background(), graph)
+	if !errors.Is(err, visualizer.ErrGraphvizNotFound) {
+		t.Errorf("Expected ErrGraphvizNotFound, got %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	graph := &analyzer.DependencyGraph{
+		EntryPackage: "test/main",
+		ModuleName:   "test",
+		Packages: map[string]*analyzer.PackageInfo{
+			"test/main":   {Name: "main", Path: "test/main", Dependencies: []string{"test/api-v2"}},
+			"test/api-v2": {Name: "api", Path: "test/api-v2", Dependencies: []string{"test/main"}},
+		},
+	}
+	viz := visualizer.New(visualizer.WithModuleClusters(true))
+	if err := viz.Validate(context.Background(), viz.GenerateDOTContent(graph)); err != nil {
+		t.Errorf("Expected generated DOT to be valid, got: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		dot  string
+	}{
+		{"empty", ""},
+		{"unclosed graph", "digraph g {\n  a -> b;\n"},
+		{"unmatched brace", "digraph g {\n  a -> b;\n}}"},
+		{"unclosed attribute list", "digraph g {\n  a [label=\"a\";\n}"},
+		{"mismatched bracket", "digraph g {\n  a [label=\"a\"};\n"},
+		{"unterminated string", "digraph g {\n  a [label=\"a\\\"];\n}"},
+		{"invalid node ID", "digraph g {\n  test/main -> b;\n}"},
+		{"unterminated comment", "digraph g {\n  /* a -> b;\n}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := viz.Validate(context.Background(), tt.dot)
+			if !errors.Is(err, visualizer.ErrInvalidDOT) {
+				t.Errorf("Expected ErrInvalidDOT, got: %v", err)
+			}
+			if structureErr := viz.ValidateStructure(tt.dot); !errors.Is(structureErr, visualizer.ErrInvalidDOT) {
+				t.Errorf("Expected ErrInvalidDOT from the structural check, got: %v", structureErr)
+			}
+		})
+	}
+}
+
+func TestValidate_Graphviz(t *testing.T) {
+	viz := visualizer.New()
+	dot := "digraph g {\n  a -> b;\n}\n"
+
+	fakeGraphviz(t, "/bin/cat >/dev/null\necho 'Error: syntax error in line 2' >&2\nexit 1\n")
+	err := viz.Validate(context.Background(), dot)
+	if !errors.Is(err, visualizer.ErrInvalidDOT) || !strings.Contains(err.Error(), "syntax error in line 2") {
+		t.Errorf("Expected ErrInvalidDOT with Graphviz's message, got: %v", err)
+	}
+	if structureErr := viz.ValidateStructure(dot); structureErr != nil {
+		t.Errorf("Expected the structural check not to run Graphviz, got: %v", structureErr)
+	}
+
+	// A hung Graphviz is stopped when the context ends
+	fakeGraphviz(t, "exec /bin/sleep 10\n")
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = viz.Validate(ctx, dot)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the deadline to stop Graphviz, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected Validate to return soon after the deadline, took %v", elapsed)
+	}
+
+	t.Setenv("PATH", t.TempDir())
+	if err := viz.Validate(context.Background(), dot); err != nil {
+		t.Errorf("Expected only the structural check without Graphviz, got: %v", err)
+	}
+}
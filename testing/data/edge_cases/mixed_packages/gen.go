@@ -0,0 +1,6 @@
+//go:build ignore
+
+// A generator script excluded from the build, which may declare its own package.
+package gen
+
+func main() {}
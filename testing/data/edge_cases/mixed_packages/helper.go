@@ -0,0 +1,9 @@
+// Package helper conflicts with the main package declared alongside it.
+package helper
+
+import "strings"
+
+// Shout upper-cases a message.
+func Shout(message string) string {
+	return strings.ToUpper(message)
+}
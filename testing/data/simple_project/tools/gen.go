@@ -0,0 +1,8 @@
+//go:build ignore
+
+// gen is a generator script run with "go run tools/gen.go"; it is not part of the build.
+package main
+
+func main() {
+	println("generating")
+}
@@ -693,7 +693,8 @@ function populateEntryPointSelector() {
     entryPointsData.forEach((entryPoint, index) => {
         const option = document.createElement('option');
         option.value = index;
-        option.textContent = entryPoint.relativePath;
+        option.textContent = entryPoint.name || entryPoint.relativePath;
+        option.title = entryPoint.relativePath;
         if (index === currentEntryPointIndex) {
             option.selected = true;
         }